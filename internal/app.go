@@ -6,6 +6,7 @@ import (
 	catv1 "github.com/kurneo/go-template/internal/category/transport/http/v1"
 	"github.com/kurneo/go-template/pkg/cache"
 	"github.com/kurneo/go-template/pkg/database"
+	"github.com/kurneo/go-template/pkg/filesystem"
 	"github.com/kurneo/go-template/pkg/hashing"
 	logPkg "github.com/kurneo/go-template/pkg/log"
 	"github.com/labstack/echo/v4"
@@ -13,11 +14,15 @@ import (
 	"net/http"
 	"os/signal"
 	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 )
 
+// adminApiPrefix is the group of every admin api route
+const adminApiPrefix = "/api/admin/v1"
+
 var (
 	appInstance App
 	appOnce     sync.Once
@@ -29,6 +34,7 @@ type App interface {
 	GetCache() cache.Contact
 	GetDB() database.Contract
 	GetHashing() hashing.Contact
+	GetFilesystem() *filesystem.Manager
 	GetHttpHandler() *echo.Echo
 }
 
@@ -38,12 +44,14 @@ type application struct {
 	db database.Contract
 	c  cache.Contact
 	s  hashing.Contact
+	fs *filesystem.Manager
 }
 
 // Start server with gracefully shutdown.
 func (app *application) Start(p int) {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
+	logPkg.WatchSignals(ctx)
 	go func() {
 		if err := app.e.Start(":" + strconv.Itoa(p)); err != nil && err != http.ErrServerClosed {
 			log.Fatal("Shutting down the server")
@@ -62,6 +70,10 @@ func (app *application) Start(p int) {
 	if err != nil {
 		log.Fatal(err)
 	}
+	log.Println("Flush logs")
+	if err = logPkg.Shutdown(ctx); err != nil {
+		log.Println(err)
+	}
 }
 
 // GetLogger used by application
@@ -84,6 +96,11 @@ func (app *application) GetHashing() hashing.Contact {
 	return app.s
 }
 
+// GetFilesystem used by application
+func (app *application) GetFilesystem() *filesystem.Manager {
+	return app.fs
+}
+
 // GetHttpHandler that create server
 func (app *application) GetHttpHandler() *echo.Echo {
 	return app.e
@@ -96,6 +113,7 @@ func NewApplication(
 	db database.Contract,
 	c cache.Contact,
 	s hashing.Contact,
+	fs *filesystem.Manager,
 	authV1 *authv1.Controller,
 	catV1 *catv1.Controller,
 ) App {
@@ -106,10 +124,21 @@ func NewApplication(
 			db: db,
 			c:  c,
 			s:  s,
+			fs: fs,
 		}
-		g := e.Group("/api/admin/v1")
+		e.GET("/metrics", metricsHandler(c), adminMiddleware())
+		if s := fs.Signer(); s != nil {
+			if routesOverlap(s.RoutePath(), adminApiPrefix) {
+				log.Fatalf("url signing path \"%s\" overlaps the api routes \"%s\"", s.RoutePath(), adminApiPrefix)
+			}
+			e.GET(s.Route(), temporaryFileHandler(fs))
+		}
+		g := e.Group(adminApiPrefix)
 		authV1.RegisterRoute(g)
 		catV1.RegisterRoute(g)
+		g.GET("/system/log-level", getLogLevelHandler(), adminMiddleware())
+		g.PUT("/system/log-level", setLogLevelHandler(lg), adminMiddleware())
+		g.DELETE("/system/log-level", resetLogLevelHandler(), adminMiddleware())
 	})
 
 	return appInstance
@@ -118,3 +147,8 @@ func NewApplication(
 func GetApplication() App {
 	return appInstance
 }
+
+// routesOverlap reports whether a and b are the same path or one contains the other
+func routesOverlap(a, b string) bool {
+	return a == b || strings.HasPrefix(a, b+"/") || strings.HasPrefix(b, a+"/")
+}
@@ -19,7 +19,7 @@ type Controller struct {
 func (ctl Controller) Login(context echo.Context) error {
 	body, err := http.ParseFormData[LoginFormData](context)
 	if err != nil {
-		ctl.l.Error(err)
+		ctl.l.WithContext(context.Request().Context()).Error(err)
 		return http.ResponseBadRequest(context, err.Error())
 	}
 
@@ -29,7 +29,7 @@ func (ctl Controller) Login(context echo.Context) error {
 
 	errTrans := ctl.db.Begin()
 	if errTrans != nil {
-		ctl.l.Error(errTrans)
+		ctl.l.WithContext(context.Request().Context()).Error(errTrans)
 		return http.ResponseError(context, errTrans.Error())
 	}
 
@@ -38,7 +38,7 @@ func (ctl Controller) Login(context echo.Context) error {
 	if errLogin != nil {
 		errTrans = ctl.db.Rollback()
 		if errTrans != nil {
-			ctl.l.Error(errTrans)
+			ctl.l.WithContext(context.Request().Context()).Error(errTrans)
 			return http.ResponseError(context, errTrans.Error())
 		}
 		if errLogin.IsDomainError() {
@@ -50,7 +50,7 @@ func (ctl Controller) Login(context echo.Context) error {
 
 	errTrans = ctl.db.Commit()
 	if errTrans != nil {
-		ctl.l.Error(errTrans)
+		ctl.l.WithContext(context.Request().Context()).Error(errTrans)
 		return http.ResponseError(context, errTrans.Error())
 	}
 
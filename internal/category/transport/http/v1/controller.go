@@ -73,7 +73,7 @@ func (c Controller) Get(context echo.Context) error {
 func (c Controller) Store(context echo.Context) error {
 	body, err := http.ParseFormData[CategoryFormData](context)
 	if err != nil {
-		c.l.Error(err)
+		c.l.WithContext(context.Request().Context()).Error(err)
 		return http.ResponseBadRequest(context, err.Error())
 	}
 
@@ -84,7 +84,7 @@ func (c Controller) Store(context echo.Context) error {
 	errTrans := c.db.Begin()
 
 	if errTrans != nil {
-		c.l.Error(errTrans)
+		c.l.WithContext(context.Request().Context()).Error(errTrans)
 		return http.ResponseError(context, errTrans.Error())
 	}
 	cat, errCrt := c.u.Store(context.Request().Context(), body)
@@ -92,7 +92,7 @@ func (c Controller) Store(context echo.Context) error {
 	if errCrt != nil {
 		errTrans = c.db.Rollback()
 		if errTrans != nil {
-			c.l.Error(errTrans)
+			c.l.WithContext(context.Request().Context()).Error(errTrans)
 			return http.ResponseError(context, errTrans.Error())
 		}
 		if errCrt.IsDomainError() {
@@ -104,7 +104,7 @@ func (c Controller) Store(context echo.Context) error {
 
 	errTrans = c.db.Commit()
 	if errTrans != nil {
-		c.l.Error(errTrans)
+		c.l.WithContext(context.Request().Context()).Error(errTrans)
 		return http.ResponseError(context, errTrans.Error())
 	}
 
@@ -120,7 +120,7 @@ func (c Controller) Update(context echo.Context) error {
 
 	body, errParse := http.ParseFormData[CategoryFormData](context)
 	if errParse != nil {
-		c.l.Error(errParse)
+		c.l.WithContext(context.Request().Context()).WithField("category_id", id).Error(errParse)
 		return http.ResponseBadRequest(context)
 	}
 
@@ -143,7 +143,7 @@ func (c Controller) Update(context echo.Context) error {
 
 	errTrans := c.db.Begin()
 	if errTrans != nil {
-		c.l.Error(errTrans)
+		c.l.WithContext(context.Request().Context()).WithField("category_id", id).Error(errTrans)
 		return http.ResponseError(context, errTrans.Error())
 	}
 	errUpdate := c.u.Update(context.Request().Context(), category, body)
@@ -151,7 +151,7 @@ func (c Controller) Update(context echo.Context) error {
 	if errUpdate != nil {
 		errTrans = c.db.Rollback()
 		if errTrans != nil {
-			c.l.Error(errTrans)
+			c.l.WithContext(context.Request().Context()).WithField("category_id", id).Error(errTrans)
 			return http.ResponseError(context, errTrans.Error())
 		}
 		if errUpdate.IsDomainError() {
@@ -162,7 +162,7 @@ func (c Controller) Update(context echo.Context) error {
 	}
 	errTrans = c.db.Commit()
 	if errTrans != nil {
-		c.l.Error(errTrans)
+		c.l.WithContext(context.Request().Context()).WithField("category_id", id).Error(errTrans)
 		return http.ResponseError(context, errTrans.Error())
 	}
 
@@ -192,7 +192,7 @@ func (c Controller) Delete(context echo.Context) error {
 
 	errTrans := c.db.Begin()
 	if errTrans != nil {
-		c.l.Error(errTrans)
+		c.l.WithContext(context.Request().Context()).WithField("category_id", id).Error(errTrans)
 		return http.ResponseError(context, errTrans.Error())
 	}
 
@@ -201,7 +201,7 @@ func (c Controller) Delete(context echo.Context) error {
 	if errDel != nil {
 		errTrans = c.db.Rollback()
 		if errTrans != nil {
-			c.l.Error(errTrans)
+			c.l.WithContext(context.Request().Context()).WithField("category_id", id).Error(errTrans)
 			return http.ResponseError(context, errTrans.Error())
 		}
 		if errDel.IsDomainError() {
@@ -212,7 +212,7 @@ func (c Controller) Delete(context echo.Context) error {
 	}
 	errTrans = c.db.Commit()
 	if errTrans != nil {
-		c.l.Error(errTrans)
+		c.l.WithContext(context.Request().Context()).WithField("category_id", id).Error(errTrans)
 		return http.ResponseError(context, errTrans.Error())
 	}
 
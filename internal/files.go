@@ -0,0 +1,46 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/kurneo/go-template/pkg/filesystem"
+	"github.com/kurneo/go-template/pkg/support/http"
+	"github.com/labstack/echo/v4"
+	"strconv"
+	"strings"
+)
+
+// temporaryFileHandler streams files of local disks requested through signed temporary urls
+func temporaryFileHandler(m *filesystem.Manager) echo.HandlerFunc {
+	return func(context echo.Context) error {
+		disk := context.Param("disk")
+		path := strings.TrimPrefix(context.Request().URL.Path, m.Signer().RoutePath()+"/"+disk+"/")
+
+		if err := m.Signer().Verify(disk, path, context.QueryParams()); err != nil {
+			if errors.Is(err, filesystem.ErrUrlExpired) {
+				return http.ResponseForbidden(context, "url is expired")
+			}
+			return http.ResponseForbidden(context, "signature is invalid")
+		}
+
+		d, err := m.Disk(disk)
+		if err != nil {
+			return http.ResponseNotFound(context)
+		}
+
+		r, err := d.ReadStream(path)
+		if err != nil {
+			return http.ResponseNotFound(context)
+		}
+		defer func() {
+			if err := r.Close(); err != nil {
+				fmt.Println(err)
+			}
+		}()
+
+		if size, err := d.Size(path); err == nil {
+			context.Response().Header().Set(echo.HeaderContentLength, strconv.FormatInt(size, 10))
+		}
+		return http.ResponseReader(context, d.Mime(path), r)
+	}
+}
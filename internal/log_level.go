@@ -0,0 +1,84 @@
+package internal
+
+import (
+	jwtPkg "github.com/kurneo/go-template/pkg/jwt"
+	logPkg "github.com/kurneo/go-template/pkg/log"
+	"github.com/kurneo/go-template/pkg/support/http"
+	"github.com/kurneo/go-template/pkg/support/validator"
+	"github.com/labstack/echo/v4"
+	"time"
+)
+
+type logLevelFormData struct {
+	Level       string `json:"level" form:"level" validate:"required,oneof=debug info warn error"`
+	RevertAfter string `json:"revert_after" form:"revert_after" validate:"omitempty"`
+}
+
+// adminMiddleware only lets the users listed in LOG_LEVEL_ADMIN_IDS through, it guards
+// the log level and metrics endpoints
+func adminMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(context echo.Context) error {
+			auth, ok := context.Get("auth").(*jwtPkg.AccessToken[int64])
+			if !ok || auth == nil || !logPkg.IsLevelAdmin(auth.Sub) {
+				return http.ResponseForbidden(context)
+			}
+			return next(context)
+		}
+	}
+}
+
+// getLogLevelHandler returns the current runtime log level
+func getLogLevelHandler() echo.HandlerFunc {
+	return func(context echo.Context) error {
+		return http.ResponseOk(context, logPkg.GetLevel())
+	}
+}
+
+// setLogLevelHandler changes the runtime log level, the change is reverted after
+// revert_after (e.g. "10m") or the configured default when omitted
+func setLogLevelHandler(l logPkg.Contract) echo.HandlerFunc {
+	return func(context echo.Context) error {
+		body, err := http.ParseFormData[logLevelFormData](context)
+		if err != nil {
+			return http.ResponseBadRequest(context, err.Error())
+		}
+
+		if errVald := validator.ValidateStruct(body); len(errVald) > 0 {
+			return http.ResponseUnprocessableEntity(context, errVald)
+		}
+
+		revertAfter := logPkg.RevertAfter()
+		if body.RevertAfter != "" {
+			revertAfter, err = time.ParseDuration(body.RevertAfter)
+			if err != nil || revertAfter < 0 {
+				return http.ResponseUnprocessableEntity(context, map[string][]string{
+					"revert_after": {"duration"},
+				})
+			}
+		}
+
+		state, err := logPkg.SetLevel(body.Level, revertAfter)
+		if err != nil {
+			return http.ResponseError(context, err.Error())
+		}
+
+		l.WithContext(context.Request().Context()).WithFields(logPkg.Fields{
+			"level":        state.Level,
+			"revert_after": revertAfter.String(),
+		}).Warn("log level changed")
+
+		return http.ResponseOk(context, state)
+	}
+}
+
+// resetLogLevelHandler restores the configured log level
+func resetLogLevelHandler() echo.HandlerFunc {
+	return func(context echo.Context) error {
+		state, err := logPkg.ResetLevel()
+		if err != nil {
+			return http.ResponseError(context, err.Error())
+		}
+		return http.ResponseOk(context, state)
+	}
+}
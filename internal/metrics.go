@@ -0,0 +1,32 @@
+package internal
+
+import (
+	"github.com/kurneo/go-template/pkg/cache"
+	"github.com/kurneo/go-template/pkg/support/http"
+	"github.com/labstack/echo/v4"
+)
+
+type cacheMetric struct {
+	cache.Stats
+	HitRatio     float64 `json:"hit_ratio"`
+	AvgLatencyNs int64   `json:"avg_latency_ns"`
+}
+
+// metricsHandler exposes runtime counters of the application components
+func metricsHandler(c cache.Contact) echo.HandlerFunc {
+	return func(context echo.Context) error {
+		cacheMetrics := make(map[string]cacheMetric)
+		if p, ok := c.(cache.StatsProvider); ok {
+			for prefix, s := range p.Stats() {
+				cacheMetrics[prefix] = cacheMetric{
+					Stats:        s,
+					HitRatio:     s.HitRatio(),
+					AvgLatencyNs: int64(s.AvgLatency()),
+				}
+			}
+		}
+		return http.ResponseOk(context, map[string]interface{}{
+			"cache": cacheMetrics,
+		})
+	}
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS public.cache
\ No newline at end of file
@@ -0,0 +1,9 @@
+CREATE TABLE public.cache
+(
+    "key"      varchar(255) NOT NULL,
+    value      text         NOT NULL,
+    expiration bigint       NOT NULL DEFAULT 0,
+    CONSTRAINT cache_pkey PRIMARY KEY ("key")
+);
+
+CREATE INDEX cache_expiration_index ON public.cache (expiration);
\ No newline at end of file
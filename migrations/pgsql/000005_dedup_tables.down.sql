@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.dedup_files;
+DROP TABLE IF EXISTS public.dedup_blobs;
\ No newline at end of file
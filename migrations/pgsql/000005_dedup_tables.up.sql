@@ -0,0 +1,22 @@
+CREATE TABLE public.dedup_blobs
+(
+    hash       varchar(64)  NOT NULL,
+    "size"     bigint       NOT NULL DEFAULT 0,
+    refcount   bigint       NOT NULL DEFAULT 0,
+    updated_at timestamp(0) NOT NULL,
+    CONSTRAINT dedup_blobs_pkey PRIMARY KEY (hash)
+);
+
+CREATE INDEX dedup_blobs_unreferenced_index ON public.dedup_blobs (updated_at) WHERE refcount <= 0;
+
+CREATE TABLE public.dedup_files
+(
+    "path"     varchar(1024) NOT NULL,
+    hash       varchar(64)   NOT NULL,
+    "size"     bigint        NOT NULL DEFAULT 0,
+    mime       varchar(255)  NOT NULL DEFAULT '',
+    updated_at timestamp(0)  NOT NULL,
+    CONSTRAINT dedup_files_pkey PRIMARY KEY ("path")
+);
+
+CREATE INDEX dedup_files_hash_index ON public.dedup_files (hash);
@@ -3,6 +3,7 @@ package cache
 import (
 	"context"
 	"errors"
+	"github.com/kurneo/go-template/pkg/database"
 	"sync"
 	"time"
 )
@@ -11,19 +12,31 @@ type Contact interface {
 	Get(ctx context.Context, key string) (interface{}, error)
 	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
 	Forget(ctx context.Context, key string) error
+	Ping(ctx context.Context) error
 }
 
 type Config struct {
-	Driver string
-	Redis  struct {
-		Host string
-		Port int
-		DB   int
-	}
+	Driver   string
+	Prefix   string
+	Metrics  bool
+	Redis    RedisConfig
 	InMemory struct {
 		DefaultExpiration      time.Duration
 		DefaultCleanUpInterval time.Duration
 	}
+	Tiered struct {
+		LocalExpiration      time.Duration
+		LocalCleanUpInterval time.Duration
+		InvalidationChannel  string
+	}
+	File struct {
+		Path                   string
+		DefaultCleanUpInterval time.Duration
+	}
+	Database struct {
+		Table                  string
+		DefaultCleanUpInterval time.Duration
+	}
 }
 
 var (
@@ -34,20 +47,60 @@ var (
 const (
 	DriverRedis    = "redis"
 	DriverInMemory = "in-memory"
+	DriverTiered   = "tiered"
+	DriverFile     = "file"
+	DriverDatabase = "database"
+	DriverNull     = "null"
 )
 
-func New(c Config) (Contact, error) {
+const defaultInvalidationChannel = "cache:invalidate"
+
+func New(c Config, db database.Contract) (Contact, error) {
 	var err error
 
 	cacheOnce.Do(func() {
 		switch c.Driver {
 		case DriverRedis:
-			cacheInstance = newRedisDriver(c.Redis.Host, c.Redis.Port, c.Redis.DB)
+			var r *redisDriver
+			if r, err = newRedisDriver(c.Redis); err == nil {
+				cacheInstance = r
+			}
 		case DriverInMemory:
 			cacheInstance = newInMemoryDriver(c.InMemory.DefaultExpiration, c.InMemory.DefaultCleanUpInterval)
+		case DriverTiered:
+			var remote *redisDriver
+			remote, err = newRedisDriver(c.Redis)
+			if err != nil {
+				return
+			}
+			cacheInstance = newTieredDriver(
+				newInMemoryDriver(c.Tiered.LocalExpiration, c.Tiered.LocalCleanUpInterval),
+				remote,
+				c.Tiered.LocalExpiration,
+				invalidationChannel(c.Prefix, c.Tiered.InvalidationChannel),
+			)
+		case DriverFile:
+			cacheInstance = newFileDriver(c.File.Path, c.File.DefaultCleanUpInterval)
+		case DriverDatabase:
+			if db == nil {
+				err = errors.New("cache database driver requires a database connection")
+				return
+			}
+			cacheInstance = newDatabaseDriver(db, c.Database.Table, c.Database.DefaultCleanUpInterval)
+		case DriverNull:
+			cacheInstance = newNullDriver()
 		default:
 			err = errors.New("cache driver is invalid")
 		}
+
+		if err != nil {
+			return
+		}
+
+		cacheInstance = withPrefix(cacheInstance, c.Prefix)
+		if c.Metrics {
+			cacheInstance = withMetrics(cacheInstance)
+		}
 	})
 
 	if err != nil {
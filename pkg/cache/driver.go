@@ -2,14 +2,19 @@ package cache
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"github.com/google/uuid"
 	"github.com/patrickmn/go-cache"
 	"github.com/redis/go-redis/v9"
+	"strings"
+	"sync"
 	"time"
 )
 
 type redisDriver struct {
-	client *redis.Client
+	client redis.UniversalClient
 }
 
 func (c redisDriver) Get(ctx context.Context, key string) (interface{}, error) {
@@ -32,13 +37,101 @@ func (c redisDriver) Forget(ctx context.Context, key string) error {
 	return c.client.Del(ctx, key).Err()
 }
 
-func newRedisDriver(host string, port, db int) *redisDriver {
-	return &redisDriver{
-		client: redis.NewClient(&redis.Options{
-			Addr: fmt.Sprintf("%s:%d", host, port),
-			DB:   db,
-		}),
+func (c redisDriver) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
+
+type RedisConfig struct {
+	Mode             string
+	Host             string
+	Port             int
+	Addrs            []string
+	MasterName       string
+	Username         string
+	Password         string
+	SentinelPassword string
+	DB               int
+	TLS              bool
+	TLSSkipVerify    bool
+	PoolSize         int
+	MinIdleConns     int
+	DialTimeout      time.Duration
+	ReadTimeout      time.Duration
+	WriteTimeout     time.Duration
+}
+
+const (
+	RedisModeStandalone = "standalone"
+	RedisModeSentinel   = "sentinel"
+	RedisModeCluster    = "cluster"
+)
+
+func newRedisDriver(c RedisConfig) (*redisDriver, error) {
+	addrs := c.Addrs
+	if len(addrs) == 0 {
+		addrs = []string{fmt.Sprintf("%s:%d", c.Host, c.Port)}
+	}
+
+	var tlsConfig *tls.Config
+	if c.TLS {
+		tlsConfig = &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: c.TLSSkipVerify,
+		}
+	}
+
+	var client redis.UniversalClient
+	switch c.Mode {
+	case "", RedisModeStandalone:
+		client = redis.NewClient(&redis.Options{
+			Addr:         addrs[0],
+			Username:     c.Username,
+			Password:     c.Password,
+			DB:           c.DB,
+			TLSConfig:    tlsConfig,
+			PoolSize:     c.PoolSize,
+			MinIdleConns: c.MinIdleConns,
+			DialTimeout:  c.DialTimeout,
+			ReadTimeout:  c.ReadTimeout,
+			WriteTimeout: c.WriteTimeout,
+		})
+	case RedisModeSentinel:
+		if c.MasterName == "" {
+			return nil, errors.New("redis sentinel mode requires a master name")
+		}
+		client = redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       c.MasterName,
+			SentinelAddrs:    addrs,
+			SentinelPassword: c.SentinelPassword,
+			Username:         c.Username,
+			Password:         c.Password,
+			DB:               c.DB,
+			TLSConfig:        tlsConfig,
+			PoolSize:         c.PoolSize,
+			MinIdleConns:     c.MinIdleConns,
+			DialTimeout:      c.DialTimeout,
+			ReadTimeout:      c.ReadTimeout,
+			WriteTimeout:     c.WriteTimeout,
+		})
+	case RedisModeCluster:
+		client = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        addrs,
+			Username:     c.Username,
+			Password:     c.Password,
+			TLSConfig:    tlsConfig,
+			PoolSize:     c.PoolSize,
+			MinIdleConns: c.MinIdleConns,
+			DialTimeout:  c.DialTimeout,
+			ReadTimeout:  c.ReadTimeout,
+			WriteTimeout: c.WriteTimeout,
+		})
+	default:
+		return nil, errors.New("redis mode is invalid")
 	}
+
+	return &redisDriver{
+		client: client,
+	}, nil
 }
 
 type inMemoryDriver struct {
@@ -63,9 +156,135 @@ func (i inMemoryDriver) Forget(ctx context.Context, key string) error {
 	return nil
 }
 
+func (i inMemoryDriver) Ping(ctx context.Context) error {
+	return nil
+}
+
 func newInMemoryDriver(defaultExpiration, cleanupInterval time.Duration) *inMemoryDriver {
 	c := cache.New(defaultExpiration, cleanupInterval)
 	return &inMemoryDriver{
 		c: c,
 	}
 }
+
+const tieredMissValue = "\x00tiered:miss"
+
+type tieredDriver struct {
+	local   *inMemoryDriver
+	remote  *redisDriver
+	ttl     time.Duration
+	channel string
+	id      string
+	gen     *tieredGeneration
+}
+
+// tieredGeneration is bumped by every invalidation, a Get only fills the local cache
+// when no invalidation happened while it was reading redis
+type tieredGeneration struct {
+	mu sync.Mutex
+	n  uint64
+}
+
+func (t tieredDriver) Get(ctx context.Context, key string) (interface{}, error) {
+	value, _ := t.local.Get(ctx, key)
+	if value != nil {
+		if value == tieredMissValue {
+			return nil, nil
+		}
+		return value, nil
+	}
+
+	t.gen.mu.Lock()
+	gen := t.gen.n
+	t.gen.mu.Unlock()
+
+	value, err := t.remote.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// misses are cached as well, so hot keys that are usually absent
+	// (e.g. revoked token ids) don't hit redis on every lookup
+	fill := value
+	if fill == nil {
+		fill = tieredMissValue
+	}
+	t.gen.mu.Lock()
+	if t.gen.n == gen {
+		_ = t.local.Set(ctx, key, fill, t.ttl)
+	}
+	t.gen.mu.Unlock()
+	return value, nil
+}
+
+func (t tieredDriver) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	if err := t.remote.Set(ctx, key, value, expiration); err != nil {
+		return err
+	}
+	return t.invalidate(ctx, key)
+}
+
+func (t tieredDriver) Forget(ctx context.Context, key string) error {
+	if err := t.remote.Forget(ctx, key); err != nil {
+		return err
+	}
+	return t.invalidate(ctx, key)
+}
+
+func (t tieredDriver) Ping(ctx context.Context) error {
+	return t.remote.Ping(ctx)
+}
+
+func (t tieredDriver) invalidate(ctx context.Context, key string) error {
+	t.forgetLocal(ctx, key)
+	return t.remote.client.Publish(ctx, t.channel, t.id+"|"+key).Err()
+}
+
+func (t tieredDriver) listen() {
+	sub := t.remote.client.Subscribe(context.Background(), t.channel)
+	for msg := range sub.Channel() {
+		origin, key, found := strings.Cut(msg.Payload, "|")
+		if !found || origin == t.id {
+			continue
+		}
+		t.forgetLocal(context.Background(), key)
+	}
+}
+
+func (t tieredDriver) forgetLocal(ctx context.Context, key string) {
+	t.gen.mu.Lock()
+	defer t.gen.mu.Unlock()
+	t.gen.n++
+	_ = t.local.Forget(ctx, key)
+}
+
+// invalidationChannel returns channel or the default one namespaced by prefix, so apps
+// sharing a redis never evict the local tier of each other
+func invalidationChannel(prefix, channel string) string {
+	if channel != "" {
+		return channel
+	}
+	if prefix = strings.TrimRight(prefix, namespaceSeparator); prefix != "" {
+		return prefix + namespaceSeparator + defaultInvalidationChannel
+	}
+	return defaultInvalidationChannel
+}
+
+func newTieredDriver(local *inMemoryDriver, remote *redisDriver, ttl time.Duration, channel string) *tieredDriver {
+	if ttl <= 0 {
+		ttl = 5 * time.Second
+	}
+	if channel == "" {
+		channel = defaultInvalidationChannel
+	}
+	t := &tieredDriver{
+		local:   local,
+		remote:  remote,
+		ttl:     ttl,
+		channel: channel,
+		id:      uuid.New().String(),
+		gen:     &tieredGeneration{},
+	}
+	go t.listen()
+	return t
+}
@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/kurneo/go-template/pkg/database"
+	"gorm.io/gorm/clause"
+	"log"
+	"time"
+)
+
+type databaseEntry struct {
+	Key        string `gorm:"column:key;primaryKey"`
+	Value      string `gorm:"column:value"`
+	Expiration int64  `gorm:"column:expiration"`
+}
+
+type databaseDriver struct {
+	db    database.Contract
+	table string
+}
+
+func (d databaseDriver) Get(ctx context.Context, key string) (interface{}, error) {
+	var entries []databaseEntry
+	err := d.db.GetConnection(ctx).Table(d.table).
+		Where(d.keyEq(key)).
+		Limit(1).
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	entry := entries[0]
+	if entry.Expiration > 0 && entry.Expiration < time.Now().Unix() {
+		return nil, d.Forget(ctx, key)
+	}
+
+	var value interface{}
+	if err = json.Unmarshal([]byte(entry.Value), &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (d databaseDriver) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	entry := databaseEntry{
+		Key:   key,
+		Value: string(b),
+	}
+	if expiration > 0 {
+		entry.Expiration = time.Now().Add(expiration).Unix()
+	}
+
+	return d.db.GetConnection(ctx).Table(d.table).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"value", "expiration"}),
+		}).
+		Create(&entry).Error
+}
+
+func (d databaseDriver) Forget(ctx context.Context, key string) error {
+	return d.db.GetConnection(ctx).Table(d.table).
+		Where(d.keyEq(key)).
+		Delete(&databaseEntry{}).Error
+}
+
+func (d databaseDriver) Ping(ctx context.Context) error {
+	db, err := d.db.GetConnection(ctx).DB()
+	if err != nil {
+		return err
+	}
+	return db.PingContext(ctx)
+}
+
+// keyEq builds a quoted condition on the key column, "key" is reserved in mysql
+func (d databaseDriver) keyEq(key string) clause.Eq {
+	return clause.Eq{Column: clause.Column{Name: "key"}, Value: key}
+}
+
+func (d databaseDriver) gc() {
+	err := d.db.GetConnection(context.Background()).Table(d.table).
+		Where("expiration > 0 AND expiration < ?", time.Now().Unix()).
+		Delete(&databaseEntry{}).Error
+	if err != nil {
+		log.Println("Cache error: database garbage collection failed", err)
+	}
+}
+
+func (d databaseDriver) background(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		d.gc()
+	}
+}
+
+func newDatabaseDriver(db database.Contract, table string, cleanupInterval time.Duration) *databaseDriver {
+	if table == "" {
+		table = "cache"
+	}
+	if cleanupInterval <= 0 {
+		cleanupInterval = time.Hour
+	}
+	d := &databaseDriver{
+		db:    db,
+		table: table,
+	}
+	go d.background(cleanupInterval)
+	return d
+}
@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// sqliteDB is a database.Contract over a sqlite file, transactions behave like the
+// postgres and mysql drivers, GetDB returns the open one
+type sqliteDB struct {
+	db *gorm.DB
+	tx *gorm.DB
+}
+
+func (s *sqliteDB) Close() error {
+	db, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return db.Close()
+}
+
+func (s *sqliteDB) Connect() error {
+	return nil
+}
+
+func (s *sqliteDB) Begin() error {
+	s.tx = s.db.Begin()
+	return s.tx.Error
+}
+
+func (s *sqliteDB) Commit() error {
+	err := s.tx.Commit().Error
+	s.tx = nil
+	return err
+}
+
+func (s *sqliteDB) Rollback() error {
+	err := s.tx.Rollback().Error
+	s.tx = nil
+	return err
+}
+
+func (s *sqliteDB) IsTransaction() bool {
+	return s.tx != nil
+}
+
+func (s *sqliteDB) IsNotFound(err error) bool {
+	return errors.Is(err, gorm.ErrRecordNotFound)
+}
+
+func (s *sqliteDB) GetDB(ctx context.Context) *gorm.DB {
+	if s.tx != nil {
+		return s.tx.WithContext(ctx)
+	}
+	return s.db.WithContext(ctx)
+}
+
+func (s *sqliteDB) GetConnection(ctx context.Context) *gorm.DB {
+	return s.db.WithContext(ctx)
+}
+
+func setupDatabaseDriver(t *testing.T) (*databaseDriver, *sqliteDB) {
+	gdb, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "cache.db")+"?_pragma=busy_timeout(5000)"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("gorm.Open() FAILED. Unexpected error \"%s\"", err)
+	}
+	err = gdb.Exec(`CREATE TABLE cache ("key" VARCHAR(255) PRIMARY KEY, value TEXT, expiration BIGINT NOT NULL DEFAULT 0)`).Error
+	if err != nil {
+		t.Fatalf("CREATE TABLE FAILED. Unexpected error \"%s\"", err)
+	}
+	db := &sqliteDB{db: gdb}
+	t.Cleanup(func() { _ = db.Close() })
+	return &databaseDriver{db: db, table: "cache"}, db
+}
+
+func TestDatabaseDriverSetGet(t *testing.T) {
+	d, _ := setupDatabaseDriver(t)
+	ctx := context.Background()
+
+	key := "user:1"
+	if err := d.Set(ctx, key, "name", time.Minute); err != nil {
+		t.Errorf("Set(\"%s\") FAILED. Expected error nil, got error \"%s\"\n", key, err.Error())
+	}
+	_ = d.Set(ctx, key, "other", time.Minute)
+
+	result, err := d.Get(ctx, key)
+	if err == nil && result == "other" {
+		t.Logf("Get(\"%s\") PASS. Expected \"other\", got \"%v\"\n", key, result)
+	} else {
+		t.Errorf("Get(\"%s\") FAILED. Expected \"other\", got \"%v\", error %v\n", key, result, err)
+	}
+
+	_ = d.Forget(ctx, key)
+	if result, _ = d.Get(ctx, key); result == nil {
+		t.Logf("Get(\"%s\") PASS. Expected nil, got %v\n", key, result)
+	} else {
+		t.Errorf("Get(\"%s\") FAILED. Expected nil, got %v\n", key, result)
+	}
+
+	if err = d.Ping(ctx); err != nil {
+		t.Errorf("Ping() FAILED. Expected error nil, got error \"%s\"\n", err.Error())
+	}
+}
+
+func TestDatabaseDriverExpiration(t *testing.T) {
+	d, db := setupDatabaseDriver(t)
+	ctx := context.Background()
+
+	_ = db.db.Table("cache").Create(&databaseEntry{Key: "expired", Value: "true", Expiration: time.Now().Add(-time.Minute).Unix()}).Error
+	_ = db.db.Table("cache").Create(&databaseEntry{Key: "gc", Value: "true", Expiration: time.Now().Add(-time.Minute).Unix()}).Error
+
+	if result, err := d.Get(ctx, "expired"); err == nil && result == nil {
+		t.Logf("Get() PASS. Expected nil for an expired entry, got %v\n", result)
+	} else {
+		t.Errorf("Get() FAILED. Expected nil for an expired entry, got %v, error %v\n", result, err)
+	}
+
+	d.gc()
+	var count int64
+	db.db.Table("cache").Count(&count)
+	if count == 0 {
+		t.Logf("gc() PASS. Expected expired entries removed\n")
+	} else {
+		t.Errorf("gc() FAILED. Expected expired entries removed, got %d rows\n", count)
+	}
+}
+
+func TestDatabaseDriverOutsideTransaction(t *testing.T) {
+	d, db := setupDatabaseDriver(t)
+	ctx := context.Background()
+
+	// a request transaction rolled back must not take cache writes with it
+	if err := db.Begin(); err != nil {
+		t.Fatalf("Begin() FAILED. Unexpected error \"%s\"", err)
+	}
+	_ = d.Set(ctx, "user:1", "name", time.Minute)
+	_ = db.Rollback()
+
+	result, err := d.Get(ctx, "user:1")
+	if err == nil && result == "name" {
+		t.Logf("Get() PASS. Expected \"name\" after rollback, got \"%v\"\n", result)
+	} else {
+		t.Errorf("Get() FAILED. Expected \"name\" after rollback, got \"%v\", error %v\n", result, err)
+	}
+}
@@ -0,0 +1,171 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	fileTmpSuffix = ".tmp"
+	// fileTmpMaxAge after which the gc removes temporary files left by a crashed writer
+	fileTmpMaxAge = time.Hour
+)
+
+type fileEntry struct {
+	ExpiresAt int64       `json:"expires_at"`
+	Value     interface{} `json:"value"`
+}
+
+func (e fileEntry) isExpired() bool {
+	return e.ExpiresAt > 0 && e.ExpiresAt < time.Now().UnixNano()
+}
+
+type fileDriver struct {
+	dir string
+}
+
+func (f fileDriver) Get(ctx context.Context, key string) (interface{}, error) {
+	path := f.path(key)
+	entry, err := f.read(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if entry.isExpired() {
+		_ = os.Remove(path)
+		return nil, nil
+	}
+
+	return entry.Value, nil
+}
+
+func (f fileDriver) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	entry := fileEntry{Value: value}
+	if expiration > 0 {
+		entry.ExpiresAt = time.Now().Add(expiration).UnixNano()
+	}
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	path := f.path(key)
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// write to a temporary file first so readers never see a partial entry, every
+	// writer gets its own so concurrent sets of one key don't interleave
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*"+fileTmpSuffix)
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(b)
+	if errClose := tmp.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
+
+func (f fileDriver) Forget(ctx context.Context, key string) error {
+	err := os.Remove(f.path(key))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (f fileDriver) Ping(ctx context.Context) error {
+	if err := os.MkdirAll(f.dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(f.dir, "ping-*")
+	if err != nil {
+		return err
+	}
+	_ = tmp.Close()
+	return os.Remove(tmp.Name())
+}
+
+func (f fileDriver) path(key string) string {
+	sum := sha1.Sum([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(f.dir, name[:2], name[2:4], name)
+}
+
+func (f fileDriver) read(path string) (*fileEntry, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entry fileEntry
+	if err = json.Unmarshal(b, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (f fileDriver) gc() {
+	err := filepath.WalkDir(f.dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if strings.HasSuffix(path, fileTmpSuffix) {
+			if info, errInfo := d.Info(); errInfo == nil && time.Since(info.ModTime()) > fileTmpMaxAge {
+				_ = os.Remove(path)
+			}
+			return nil
+		}
+		entry, errRead := f.read(path)
+		if errRead != nil || entry.isExpired() {
+			_ = os.Remove(path)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Println("Cache error: file garbage collection failed", err)
+	}
+}
+
+func (f fileDriver) background(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		f.gc()
+	}
+}
+
+func newFileDriver(dir string, cleanupInterval time.Duration) *fileDriver {
+	if dir == "" {
+		dir = "storage/framework/cache"
+	}
+	if cleanupInterval <= 0 {
+		cleanupInterval = time.Hour
+	}
+	f := &fileDriver{
+		dir: dir,
+	}
+	go f.background(cleanupInterval)
+	return f
+}
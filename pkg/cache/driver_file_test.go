@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func setupFileDriver() *fileDriver {
+	return &fileDriver{dir: "./storage/testing/cache"}
+}
+
+func teardownFileDriver() {
+	_ = os.RemoveAll("./storage")
+}
+
+func TestFileDriverSetGet(t *testing.T) {
+	d := setupFileDriver()
+	defer teardownFileDriver()
+	ctx := context.Background()
+
+	key := "user:1"
+	value := "name"
+	err := d.Set(ctx, key, value, time.Minute)
+	if err != nil {
+		t.Errorf("Set(\"%s\") FAILED. Expected error nil, got error \"%s\"\n", key, err.Error())
+	}
+
+	result, err := d.Get(ctx, key)
+	if err == nil && result == value {
+		t.Logf("Get(\"%s\") PASS. Expected \"%s\", got \"%v\"\n", key, value, result)
+	} else {
+		t.Errorf("Get(\"%s\") FAILED. Expected \"%s\", got \"%v\", error %v\n", key, value, result, err)
+	}
+
+	err = d.Forget(ctx, key)
+	if err != nil {
+		t.Errorf("Forget(\"%s\") FAILED. Expected error nil, got error \"%s\"\n", key, err.Error())
+	}
+
+	result, _ = d.Get(ctx, key)
+	if result == nil {
+		t.Logf("Get(\"%s\") PASS. Expected nil, got %v\n", key, result)
+	} else {
+		t.Errorf("Get(\"%s\") FAILED. Expected nil, got %v\n", key, result)
+	}
+}
+
+func TestFileDriverExpiration(t *testing.T) {
+	d := setupFileDriver()
+	defer teardownFileDriver()
+	ctx := context.Background()
+
+	key := "expired"
+	_ = d.Set(ctx, key, true, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	result, _ := d.Get(ctx, key)
+	if result == nil {
+		t.Logf("Get(\"%s\") PASS. Expected nil, got %v\n", key, result)
+	} else {
+		t.Errorf("Get(\"%s\") FAILED. Expected nil, got %v\n", key, result)
+	}
+
+	_ = d.Set(ctx, key, true, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	d.gc()
+
+	if _, err := os.Stat(d.path(key)); os.IsNotExist(err) {
+		t.Logf("gc() PASS. Expected expired entry removed\n")
+	} else {
+		t.Errorf("gc() FAILED. Expected expired entry removed, got error %v\n", err)
+	}
+}
+
+func TestFileDriverConcurrentSet(t *testing.T) {
+	d := setupFileDriver()
+	defer teardownFileDriver()
+	ctx := context.Background()
+
+	key := "concurrent"
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for n := 0; n < 20; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			errs <- d.Set(ctx, key, strings.Repeat("v", n*1000), time.Minute)
+		}(n)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Set(\"%s\") FAILED. Expected error nil, got error \"%s\"\n", key, err.Error())
+		}
+	}
+
+	result, err := d.Get(ctx, key)
+	if err == nil && result != nil {
+		t.Logf("Get(\"%s\") PASS. Expected a complete entry, got %d bytes\n", key, len(result.(string)))
+	} else {
+		t.Errorf("Get(\"%s\") FAILED. Expected a complete entry, got %v, error %v\n", key, result, err)
+	}
+
+	// a temporary file of a writer in progress survives the gc
+	tmp := d.path(key) + ".123" + fileTmpSuffix
+	_ = os.WriteFile(tmp, []byte("{"), 0644)
+	d.gc()
+	if _, err = os.Stat(tmp); err == nil {
+		t.Logf("gc() PASS. Expected in-progress temporary file kept\n")
+	} else {
+		t.Errorf("gc() FAILED. Expected in-progress temporary file kept, got error %v\n", err)
+	}
+}
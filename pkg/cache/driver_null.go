@@ -0,0 +1,29 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+type nullDriver struct {
+}
+
+func (n nullDriver) Get(ctx context.Context, key string) (interface{}, error) {
+	return nil, nil
+}
+
+func (n nullDriver) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	return nil
+}
+
+func (n nullDriver) Forget(ctx context.Context, key string) error {
+	return nil
+}
+
+func (n nullDriver) Ping(ctx context.Context) error {
+	return nil
+}
+
+func newNullDriver() *nullDriver {
+	return &nullDriver{}
+}
@@ -0,0 +1,162 @@
+package cache
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"testing"
+	"time"
+)
+
+func setupTieredDrivers(t *testing.T) (*miniredis.Miniredis, *tieredDriver, *tieredDriver) {
+	s := miniredis.RunT(t)
+	newDriver := func() *tieredDriver {
+		client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+		t.Cleanup(func() { _ = client.Close() })
+		return newTieredDriver(newInMemoryDriver(time.Minute, time.Minute), &redisDriver{client: client}, time.Minute, "")
+	}
+	a, b := newDriver(), newDriver()
+
+	// wait for both listeners, a publish before the subscription is lost
+	deadline := time.Now().Add(time.Second)
+	for s.PubSubNumSub("cache:invalidate")["cache:invalidate"] < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("setupTieredDrivers() FAILED. Listeners did not subscribe")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return s, a, b
+}
+
+// eventually polls get until it returns expect or a second passed
+func eventually(get func() interface{}, expect interface{}) interface{} {
+	deadline := time.Now().Add(time.Second)
+	for {
+		v := get()
+		if v == expect || time.Now().After(deadline) {
+			return v
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestTieredDriverGet(t *testing.T) {
+	s, a, _ := setupTieredDrivers(t)
+	ctx := context.Background()
+
+	_ = a.Set(ctx, "user:1", "name", time.Minute)
+	result, err := a.Get(ctx, "user:1")
+	if err == nil && result == "name" {
+		t.Logf("Get() PASS. Expected \"name\", got \"%v\"\n", result)
+	} else {
+		t.Errorf("Get() FAILED. Expected \"name\", got \"%v\", error %v\n", result, err)
+	}
+
+	// the local copy answers without redis
+	s.Del("user:1")
+	if result, _ = a.Get(ctx, "user:1"); result == "name" {
+		t.Logf("Get() PASS. Expected local copy \"name\", got \"%v\"\n", result)
+	} else {
+		t.Errorf("Get() FAILED. Expected local copy \"name\", got \"%v\"\n", result)
+	}
+
+	// misses are cached locally as well
+	if result, _ = a.Get(ctx, "missing"); result != nil {
+		t.Errorf("Get() FAILED. Expected nil, got \"%v\"\n", result)
+	}
+	_ = s.Set("missing", "found")
+	if result, _ = a.Get(ctx, "missing"); result == nil {
+		t.Logf("Get() PASS. Expected cached miss, got nil\n")
+	} else {
+		t.Errorf("Get() FAILED. Expected cached miss, got \"%v\"\n", result)
+	}
+}
+
+func TestTieredDriverInvalidation(t *testing.T) {
+	_, a, b := setupTieredDrivers(t)
+	ctx := context.Background()
+
+	_ = a.Set(ctx, "user:1", "old", time.Minute)
+	if result, _ := b.Get(ctx, "user:1"); result != "old" {
+		t.Fatalf("Get() FAILED. Expected \"old\", got \"%v\"", result)
+	}
+
+	_ = a.Set(ctx, "user:1", "new", time.Minute)
+	result := eventually(func() interface{} {
+		v, _ := b.Get(ctx, "user:1")
+		return v
+	}, "new")
+	if result == "new" {
+		t.Logf("Set() PASS. Expected peer to see \"new\", got \"%v\"\n", result)
+	} else {
+		t.Errorf("Set() FAILED. Expected peer to see \"new\", got \"%v\"\n", result)
+	}
+
+	_ = a.Forget(ctx, "user:1")
+	result = eventually(func() interface{} {
+		v, _ := b.Get(ctx, "user:1")
+		return v
+	}, nil)
+	if result == nil {
+		t.Logf("Forget() PASS. Expected peer to see nil, got %v\n", result)
+	} else {
+		t.Errorf("Forget() FAILED. Expected peer to see nil, got \"%v\"\n", result)
+	}
+}
+
+func TestTieredDriverStaleFill(t *testing.T) {
+	_, a, _ := setupTieredDrivers(t)
+	ctx := context.Background()
+	_ = a.Set(ctx, "user:1", "old", time.Minute)
+
+	// an invalidation while redis is read must keep the read value out of the local cache
+	a.remote.client.AddHook(staleHook{invalidate: func() { a.forgetLocal(ctx, "user:1") }})
+	if result, _ := a.Get(ctx, "user:1"); result != "old" {
+		t.Fatalf("Get() FAILED. Expected \"old\", got \"%v\"", result)
+	}
+	if value, _ := a.local.Get(ctx, "user:1"); value == nil {
+		t.Logf("Get() PASS. Expected no local fill after invalidation, got nil\n")
+	} else {
+		t.Errorf("Get() FAILED. Expected no local fill after invalidation, got \"%v\"\n", value)
+	}
+}
+
+// staleHook runs invalidate after every GET command reached redis
+type staleHook struct {
+	invalidate func()
+}
+
+func (h staleHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h staleHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := next(ctx, cmd)
+		if cmd.Name() == "get" {
+			h.invalidate()
+		}
+		return err
+	}
+}
+
+func (h staleHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestInvalidationChannel(t *testing.T) {
+	for _, tc := range []struct {
+		prefix, channel, expect string
+	}{
+		{prefix: "", channel: "", expect: "cache:invalidate"},
+		{prefix: "app1", channel: "", expect: "app1:cache:invalidate"},
+		{prefix: "app2:", channel: "", expect: "app2:cache:invalidate"},
+		{prefix: "app1", channel: "custom", expect: "custom"},
+	} {
+		if actual := invalidationChannel(tc.prefix, tc.channel); actual == tc.expect {
+			t.Logf("invalidationChannel(\"%s\", \"%s\") PASS. Expected \"%s\"\n", tc.prefix, tc.channel, tc.expect)
+		} else {
+			t.Errorf("invalidationChannel(\"%s\", \"%s\") FAILED. Expected \"%s\", got \"%s\"\n", tc.prefix, tc.channel, tc.expect, actual)
+		}
+	}
+}
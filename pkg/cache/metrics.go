@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const metricsDefaultGroup = "*"
+
+// Stats is a snapshot of the counters recorded for one key prefix
+type Stats struct {
+	Hits         uint64        `json:"hits"`
+	Misses       uint64        `json:"misses"`
+	Sets         uint64        `json:"sets"`
+	Forgets      uint64        `json:"forgets"`
+	Errors       uint64        `json:"errors"`
+	Calls        uint64        `json:"calls"`
+	TotalLatency time.Duration `json:"total_latency_ns"`
+	MaxLatency   time.Duration `json:"max_latency_ns"`
+}
+
+func (s Stats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+func (s Stats) AvgLatency() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.TotalLatency / time.Duration(s.Calls)
+}
+
+// StatsProvider is implemented by caches created with metrics enabled
+type StatsProvider interface {
+	Stats() map[string]Stats
+}
+
+type counters struct {
+	hits, misses, sets, forgets, errors, calls atomic.Uint64
+	totalLatency, maxLatency                   atomic.Int64
+}
+
+func (c *counters) observe(d time.Duration, err error) {
+	c.calls.Add(1)
+	c.totalLatency.Add(int64(d))
+	for {
+		max := c.maxLatency.Load()
+		if int64(d) <= max || c.maxLatency.CompareAndSwap(max, int64(d)) {
+			break
+		}
+	}
+	if err != nil {
+		c.errors.Add(1)
+	}
+}
+
+func (c *counters) snapshot() Stats {
+	return Stats{
+		Hits:         c.hits.Load(),
+		Misses:       c.misses.Load(),
+		Sets:         c.sets.Load(),
+		Forgets:      c.forgets.Load(),
+		Errors:       c.errors.Load(),
+		Calls:        c.calls.Load(),
+		TotalLatency: time.Duration(c.totalLatency.Load()),
+		MaxLatency:   time.Duration(c.maxLatency.Load()),
+	}
+}
+
+type instrumentedDriver struct {
+	c      Contact
+	groups sync.Map
+}
+
+func (i *instrumentedDriver) Get(ctx context.Context, key string) (interface{}, error) {
+	start := time.Now()
+	value, err := i.c.Get(ctx, key)
+	g := i.group(key)
+	g.observe(time.Since(start), err)
+	if err == nil {
+		if value != nil {
+			g.hits.Add(1)
+		} else {
+			g.misses.Add(1)
+		}
+	}
+	return value, err
+}
+
+func (i *instrumentedDriver) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	start := time.Now()
+	err := i.c.Set(ctx, key, value, expiration)
+	g := i.group(key)
+	g.observe(time.Since(start), err)
+	g.sets.Add(1)
+	return err
+}
+
+func (i *instrumentedDriver) Forget(ctx context.Context, key string) error {
+	start := time.Now()
+	err := i.c.Forget(ctx, key)
+	g := i.group(key)
+	g.observe(time.Since(start), err)
+	g.forgets.Add(1)
+	return err
+}
+
+func (i *instrumentedDriver) Ping(ctx context.Context) error {
+	return i.c.Ping(ctx)
+}
+
+func (i *instrumentedDriver) Stats() map[string]Stats {
+	result := make(map[string]Stats)
+	i.groups.Range(func(k, v any) bool {
+		result[k.(string)] = v.(*counters).snapshot()
+		return true
+	})
+	return result
+}
+
+// group returns the counters for the first segment of the key, keys without
+// a namespace separator (e.g. token uuids) share one group so cardinality stays bounded
+func (i *instrumentedDriver) group(key string) *counters {
+	name := metricsDefaultGroup
+	if prefix, _, found := strings.Cut(key, namespaceSeparator); found && prefix != "" {
+		name = prefix
+	}
+	if g, ok := i.groups.Load(name); ok {
+		return g.(*counters)
+	}
+	g, _ := i.groups.LoadOrStore(name, &counters{})
+	return g.(*counters)
+}
+
+func withMetrics(c Contact) Contact {
+	return &instrumentedDriver{
+		c: c,
+	}
+}
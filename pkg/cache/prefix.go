@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+const namespaceSeparator = ":"
+
+type prefixedDriver struct {
+	c      Contact
+	prefix string
+}
+
+func (p prefixedDriver) Get(ctx context.Context, key string) (interface{}, error) {
+	return p.c.Get(ctx, p.prefix+key)
+}
+
+func (p prefixedDriver) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	return p.c.Set(ctx, p.prefix+key, value, expiration)
+}
+
+func (p prefixedDriver) Forget(ctx context.Context, key string) error {
+	return p.c.Forget(ctx, p.prefix+key)
+}
+
+func (p prefixedDriver) Ping(ctx context.Context) error {
+	return p.c.Ping(ctx)
+}
+
+func withPrefix(c Contact, prefix string) Contact {
+	prefix = strings.TrimRight(prefix, namespaceSeparator)
+	if prefix == "" {
+		return c
+	}
+	return &prefixedDriver{
+		c:      c,
+		prefix: prefix + namespaceSeparator,
+	}
+}
+
+// Namespace returns a view of the cache where every key is prefixed with ns,
+// so modules sharing the same cache cannot overwrite each other's keys
+func Namespace(c Contact, ns string) Contact {
+	return withPrefix(c, ns)
+}
@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"errors"
+	"github.com/kurneo/go-template/pkg/log"
 	"gorm.io/gorm"
 	"sync"
 )
@@ -16,6 +17,9 @@ type Contract interface {
 	IsTransaction() bool
 	IsNotFound(err error) bool
 	GetDB(ctx context.Context) *gorm.DB
+	// GetConnection returns the connection outside of any open transaction, for work
+	// that must not be committed or rolled back with the current request
+	GetConnection(ctx context.Context) *gorm.DB
 }
 
 type Config struct {
@@ -34,7 +38,7 @@ var (
 	dbOnce     sync.Once
 )
 
-func New(c Config) (Contract, error) {
+func New(c Config, l log.Contract) (Contract, error) {
 	var err error = nil
 
 	if c.Driver == "" || (c.Driver != DriverMysql && c.Driver != DriverPostgres) {
@@ -44,10 +48,10 @@ func New(c Config) (Contract, error) {
 	dbOnce.Do(func() {
 		switch c.Driver {
 		case DriverPostgres:
-			dbInstance = newPostgres(c.PgSql)
+			dbInstance = newPostgres(c.PgSql, l)
 			break
 		case DriverMysql:
-			dbInstance = newMySql(c.MySql)
+			dbInstance = newMySql(c.MySql, l)
 		}
 
 		if errConnect := dbInstance.Connect(); errConnect != nil {
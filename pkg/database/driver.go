@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	logPkg "github.com/kurneo/go-template/pkg/log"
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -29,6 +30,7 @@ type PgConfig struct {
 
 type Postgres struct {
 	c            PgConfig
+	l            logger.Interface
 	connAttempts int
 	connTimeout  time.Duration
 
@@ -71,7 +73,7 @@ func (p *Postgres) Connect() error {
 
 		p.db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
 			SkipDefaultTransaction: true,
-			Logger:                 logger.Default.LogMode(logger.Info),
+			Logger:                 p.l,
 		})
 
 		if err == nil {
@@ -149,9 +151,14 @@ func (p *Postgres) GetDB(ctx context.Context) *gorm.DB {
 	return p.db.WithContext(ctx)
 }
 
-func newPostgres(c PgConfig) *Postgres {
+func (p *Postgres) GetConnection(ctx context.Context) *gorm.DB {
+	return p.db.WithContext(ctx)
+}
+
+func newPostgres(c PgConfig, l logPkg.Contract) *Postgres {
 	return &Postgres{
 		c:            c,
+		l:            newGormLogger(l),
 		connAttempts: postgresDefaultConnAttempts,
 		connTimeout:  postgresDefaultConnTimeout,
 	}
@@ -173,6 +180,7 @@ type MySqlConfig struct {
 
 type MySQL struct {
 	c            MySqlConfig
+	l            logger.Interface
 	connAttempts int
 	connTimeout  time.Duration
 
@@ -209,7 +217,7 @@ func (m *MySQL) Connect() error {
 
 		m.db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{
 			SkipDefaultTransaction: true,
-			Logger:                 logger.Default.LogMode(logger.Info),
+			Logger:                 m.l,
 		})
 
 		if err == nil {
@@ -274,9 +282,14 @@ func (m *MySQL) GetDB(ctx context.Context) *gorm.DB {
 	return m.db.WithContext(ctx)
 }
 
-func newMySql(c MySqlConfig) *MySQL {
+func (m *MySQL) GetConnection(ctx context.Context) *gorm.DB {
+	return m.db.WithContext(ctx)
+}
+
+func newMySql(c MySqlConfig, l logPkg.Contract) *MySQL {
 	return &MySQL{
 		c:            c,
+		l:            newGormLogger(l),
 		connAttempts: mySqlDefaultConnAttempts,
 		connTimeout:  mySqlDefaultConnTimeout,
 	}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"github.com/kurneo/go-template/pkg/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"time"
+)
+
+const gormDefaultSlowThreshold = 200 * time.Millisecond
+
+// gormLogger writes gorm output through the application logger so sql entries
+// carry the same fields (request id etc.) as the rest of the request logs
+type gormLogger struct {
+	l             log.Contract
+	level         logger.LogLevel
+	slowThreshold time.Duration
+}
+
+func (g *gormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	child := *g
+	child.level = level
+	return &child
+}
+
+func (g *gormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if g.level >= logger.Info {
+		g.l.WithContext(ctx).Infof(msg, data...)
+	}
+}
+
+func (g *gormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if g.level >= logger.Warn {
+		g.l.WithContext(ctx).Warnf(msg, data...)
+	}
+}
+
+func (g *gormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if g.level >= logger.Error {
+		g.l.WithContext(ctx).Errorf(msg, data...)
+	}
+}
+
+func (g *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if g.level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	sql, rows := fc()
+	l := g.l.WithContext(ctx).WithFields(log.Fields{
+		"sql":     sql,
+		"rows":    rows,
+		"elapsed": elapsed.String(),
+	})
+
+	switch {
+	case err != nil && g.level >= logger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		l.WithError(err).Error("sql error")
+	case g.slowThreshold != 0 && elapsed > g.slowThreshold && g.level >= logger.Warn:
+		l.Warn("slow sql")
+	case g.level >= logger.Info:
+		l.Debug("sql")
+	}
+}
+
+func newGormLogger(l log.Contract) logger.Interface {
+	if l == nil {
+		return logger.Default.LogMode(logger.Info)
+	}
+	return &gormLogger{
+		l:             l,
+		level:         logger.Info,
+		slowThreshold: gormDefaultSlowThreshold,
+	}
+}
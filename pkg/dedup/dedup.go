@@ -0,0 +1,281 @@
+package dedup
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/kurneo/go-template/pkg/database"
+	"github.com/kurneo/go-template/pkg/filesystem"
+	"io"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+)
+
+var (
+	storeInstance *Store
+	storeOnce     sync.Once
+)
+
+const (
+	defaultDir             = "blobs"
+	defaultFilesTable      = "dedup_files"
+	defaultBlobsTable      = "dedup_blobs"
+	defaultGracePeriod     = time.Hour
+	defaultCleanupInterval = time.Hour
+	collectBatchSize       = 500
+)
+
+type Config struct {
+	// Disk the blobs are stored on, empty means the default disk
+	Disk string
+	// Dir of the blobs, a blob is stored at "<Dir>/<hash[0:2]>/<hash[2:4]>/<hash>"
+	// and uploads are staged in "<Dir>/tmp", defaults to "blobs"
+	Dir string
+	// FilesTable and BlobsTable default to "dedup_files" and "dedup_blobs"
+	FilesTable string
+	BlobsTable string
+	// GracePeriod a blob stays stored once unreferenced, defaults to an hour
+	GracePeriod time.Duration
+	// CleanupInterval of the background garbage collection, defaults to an hour
+	CleanupInterval time.Duration
+	// Visibility of the blobs, they are shared by every path so it cannot be set per file
+	Visibility filesystem.Visibility
+}
+
+// Store keeps every distinct content once on a disk while callers address files by
+// logical path, identical uploads under other paths only add a reference to the blob
+type Store struct {
+	c     Config
+	disk  filesystem.DriverContract
+	index Index
+}
+
+// Put streams r into the blob of its SHA-256 and points path at it, the blob is only
+// written when its content is not stored yet. When the blob cannot be moved into place
+// path stays recorded and reads fail until the content is put again. opts.Visibility is
+// ignored, see Config
+func (s *Store) Put(ctx context.Context, p string, r io.Reader, opts filesystem.PutOptions) (*Entry, error) {
+	name, err := randomName()
+	if err != nil {
+		return nil, err
+	}
+	tmp := path.Join(s.c.Dir, "tmp", name)
+
+	h := sha256.New()
+	counter := &countingWriter{}
+	opts.ContentType, r = contentType(p, r, opts.ContentType)
+	opts.Visibility = s.c.Visibility
+	if err = s.disk.PutStream(tmp, io.TeeReader(r, io.MultiWriter(h, counter)), opts); err != nil {
+		_ = s.disk.Delete(tmp)
+		return nil, err
+	}
+	defer func() {
+		_ = s.disk.Delete(tmp)
+	}()
+
+	// the blob is referenced first so the garbage collection leaves it alone, it is
+	// moved into place once recorded, a failed commit never leaves an orphan blob
+	e := Entry{Path: p, Hash: hex.EncodeToString(h.Sum(nil)), Size: counter.n, Mime: opts.ContentType}
+	if err = s.index.Link(ctx, e, nil); err != nil {
+		return nil, err
+	}
+	if err = s.place(tmp, s.blobPath(e.Hash)); err != nil {
+		return nil, err
+	}
+	return s.index.Get(ctx, p)
+}
+
+// place moves tmp to blob unless the content is stored already
+func (s *Store) place(tmp, blob string) error {
+	exists, err := s.disk.FileExists(blob)
+	if err != nil || exists {
+		return err
+	}
+	// local disks rename the file, the directory of the blob must exist
+	if err = s.disk.MakeDir(path.Dir(blob), 0755); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	return s.disk.Move(tmp, blob)
+}
+
+// PutBytes stores content at path, see Put
+func (s *Store) PutBytes(ctx context.Context, p string, content []byte) (*Entry, error) {
+	return s.Put(ctx, p, bytes.NewReader(content), filesystem.PutOptions{Size: int64(len(content))})
+}
+
+func (s *Store) Get(ctx context.Context, p string) ([]byte, error) {
+	r, err := s.ReadStream(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := r.Close(); err != nil {
+			fmt.Println(err)
+		}
+	}()
+	return io.ReadAll(r)
+}
+
+func (s *Store) ReadStream(ctx context.Context, p string) (io.ReadCloser, error) {
+	e, err := s.index.Get(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return s.disk.ReadStream(s.blobPath(e.Hash))
+}
+
+// Stat returns the entry of path, ErrNotFound when path is not stored
+func (s *Store) Stat(ctx context.Context, p string) (*Entry, error) {
+	return s.index.Get(ctx, p)
+}
+
+func (s *Store) Exists(ctx context.Context, p string) (bool, error) {
+	_, err := s.index.Get(ctx, p)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Delete removes path, its blob is deleted by the garbage collection once no path
+// references it anymore
+func (s *Store) Delete(ctx context.Context, p string) error {
+	return s.index.Unlink(ctx, p)
+}
+
+// Move points to at the content of from and removes from, no blob is copied
+func (s *Store) Move(ctx context.Context, from, to string) error {
+	return s.index.Rename(ctx, from, to)
+}
+
+// Copy points to at the content of from, no blob is copied
+func (s *Store) Copy(ctx context.Context, from, to string) error {
+	e, err := s.index.Get(ctx, from)
+	if err != nil {
+		return err
+	}
+	e.Path = to
+	return s.index.Link(ctx, *e, func() error {
+		exists, err := s.disk.FileExists(s.blobPath(e.Hash))
+		if err == nil && !exists {
+			err = fmt.Errorf("dedup blob \"%s\" of \"%s\" is missing", e.Hash, from)
+		}
+		return err
+	})
+}
+
+// Url returns the public url of the blob of path, empty when the disk has no public urls
+func (s *Store) Url(ctx context.Context, p string) (string, error) {
+	e, err := s.index.Get(ctx, p)
+	if err != nil {
+		return "", err
+	}
+	if d, ok := s.disk.(interface{ Url(path string) string }); ok {
+		return d.Url(s.blobPath(e.Hash)), nil
+	}
+	return "", nil
+}
+
+// GC deletes the blobs unreferenced for longer than the grace period and returns their count
+func (s *Store) GC(ctx context.Context) (int, error) {
+	return s.collect(ctx, time.Now().Add(-s.c.GracePeriod))
+}
+
+func (s *Store) collect(ctx context.Context, before time.Time) (int, error) {
+	total := 0
+	for {
+		n, err := s.index.Collect(ctx, before, collectBatchSize, func(b Blob) error {
+			if err := s.disk.Delete(s.blobPath(b.Hash)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			return nil
+		})
+		total += n
+		if err != nil || n < collectBatchSize {
+			return total, err
+		}
+	}
+}
+
+func (s *Store) blobPath(hash string) string {
+	return path.Join(s.c.Dir, hash[0:2], hash[2:4], hash)
+}
+
+func (s *Store) background() {
+	ticker := time.NewTicker(s.c.CleanupInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := s.GC(context.Background()); err != nil {
+			log.Println("Dedup error: garbage collection failed", err)
+		}
+	}
+}
+
+// contentType returns t, the type of the extension of p or the sniffed type of the first
+// bytes of r, the returned reader must be used instead of r
+func contentType(p string, r io.Reader, t string) (string, io.Reader) {
+	if t != "" {
+		return t, r
+	}
+	if t = mime.TypeByExtension(path.Ext(p)); t != "" {
+		return t, r
+	}
+	br := bufio.NewReaderSize(r, 512)
+	head, _ := br.Peek(512)
+	return http.DetectContentType(head), br
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+func randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func New(c Config, m *filesystem.Manager, db database.Contract) (*Store, error) {
+	var err error
+	storeOnce.Do(func() {
+		storeInstance, err = newStore(c, m, newDatabaseIndex(db, c.FilesTable, c.BlobsTable))
+		if err == nil {
+			go storeInstance.background()
+		}
+	})
+	return storeInstance, err
+}
+
+func newStore(c Config, m *filesystem.Manager, index Index) (*Store, error) {
+	if c.Dir == "" {
+		c.Dir = defaultDir
+	}
+	if c.GracePeriod <= 0 {
+		c.GracePeriod = defaultGracePeriod
+	}
+	if c.CleanupInterval <= 0 {
+		c.CleanupInterval = defaultCleanupInterval
+	}
+	d, err := m.Disk(c.Disk)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{c: c, disk: d, index: index}, nil
+}
@@ -0,0 +1,156 @@
+package dedup
+
+import (
+	"context"
+	"errors"
+	"github.com/kurneo/go-template/pkg/filesystem"
+	"testing"
+	"time"
+)
+
+func setupStore(t *testing.T) (*Store, filesystem.DriverContract) {
+	m, err := filesystem.New(filesystem.Config{}, nil)
+	if err != nil {
+		t.Fatalf("filesystem.New() FAILED. Unexpected error \"%s\"", err)
+	}
+	d := filesystem.NewDriverMemory()
+	m.Extend("dedup", d)
+
+	s, err := newStore(Config{Disk: "dedup"}, m, newMemoryIndex())
+	if err != nil {
+		t.Fatalf("newStore() FAILED. Unexpected error \"%s\"", err)
+	}
+	return s, d
+}
+
+func countBlobs(d filesystem.DriverContract) int {
+	files, _, _ := d.ListContentsRecursive("blobs")
+	return len(files)
+}
+
+func TestStorePutGet(t *testing.T) {
+	s, d := setupStore(t)
+	ctx := context.Background()
+
+	a, err := s.PutBytes(ctx, "users/1/avatar.png", []byte("same image"))
+	if err != nil {
+		t.Fatalf("PutBytes() FAILED. Unexpected error \"%s\"", err)
+	}
+	b, _ := s.PutBytes(ctx, "users/2/avatar.png", []byte("same image"))
+	_, _ = s.PutBytes(ctx, "users/3/avatar.png", []byte("other image"))
+
+	if a.Hash == b.Hash && a.Size == 10 && a.Mime == "image/png" && countBlobs(d) == 2 {
+		t.Logf("PutBytes() PASS. Expected identical content stored once, got %d blobs\n", countBlobs(d))
+	} else {
+		t.Errorf("PutBytes() FAILED. Expected identical content stored once, got %+v, %+v with %d blobs\n", a, b, countBlobs(d))
+	}
+
+	if content, err := s.Get(ctx, "users/2/avatar.png"); err == nil && string(content) == "same image" {
+		t.Logf("Get() PASS. Got \"%s\"\n", content)
+	} else {
+		t.Errorf("Get() FAILED. Expected \"same image\", got \"%s\", %v\n", content, err)
+	}
+
+	if _, err = s.Get(ctx, "missing.png"); errors.Is(err, ErrNotFound) {
+		t.Logf("Get() PASS. Expected ErrNotFound, got \"%s\"\n", err)
+	} else {
+		t.Errorf("Get() FAILED. Expected ErrNotFound, got %v\n", err)
+	}
+}
+
+func TestStoreGC(t *testing.T) {
+	s, d := setupStore(t)
+	ctx := context.Background()
+	later := time.Now().Add(time.Second)
+
+	_, _ = s.PutBytes(ctx, "a.txt", []byte("shared"))
+	_, _ = s.PutBytes(ctx, "b.txt", []byte("shared"))
+	_ = s.Delete(ctx, "a.txt")
+
+	if n, err := s.collect(ctx, later); err == nil && n == 0 && countBlobs(d) == 1 {
+		t.Logf("GC() PASS. Expected referenced blob to be kept\n")
+	} else {
+		t.Errorf("GC() FAILED. Expected referenced blob to be kept, got %d removed, %v\n", n, err)
+	}
+
+	// overwriting b.txt releases the shared blob
+	_, _ = s.PutBytes(ctx, "b.txt", []byte("changed"))
+	if n, err := s.GC(ctx); err == nil && n == 0 {
+		t.Logf("GC() PASS. Expected blob to be kept during the grace period\n")
+	} else {
+		t.Errorf("GC() FAILED. Expected blob to be kept during the grace period, got %d removed, %v\n", n, err)
+	}
+
+	n, err := s.collect(ctx, later)
+	content, _ := s.Get(ctx, "b.txt")
+	if err == nil && n == 1 && countBlobs(d) == 1 && string(content) == "changed" {
+		t.Logf("GC() PASS. Expected unreferenced blob to be removed\n")
+	} else {
+		t.Errorf("GC() FAILED. Expected 1 blob removed, got %d, %v with %d blobs\n", n, err, countBlobs(d))
+	}
+
+	// the content is stored again once its blob is collected
+	_, _ = s.PutBytes(ctx, "c.txt", []byte("shared"))
+	if content, err := s.Get(ctx, "c.txt"); err == nil && string(content) == "shared" {
+		t.Logf("PutBytes() PASS. Expected collected content to be stored again\n")
+	} else {
+		t.Errorf("PutBytes() FAILED. Expected \"shared\", got \"%s\", %v\n", content, err)
+	}
+}
+
+func TestStoreMoveCopy(t *testing.T) {
+	s, d := setupStore(t)
+	ctx := context.Background()
+	_, _ = s.PutBytes(ctx, "a.txt", []byte("content"))
+	_, _ = s.PutBytes(ctx, "b.txt", []byte("replaced"))
+
+	if err := s.Copy(ctx, "a.txt", "copy.txt"); err != nil {
+		t.Errorf("Copy() FAILED. Unexpected error \"%s\"\n", err)
+	}
+	if err := s.Move(ctx, "a.txt", "b.txt"); err != nil {
+		t.Errorf("Move() FAILED. Unexpected error \"%s\"\n", err)
+	}
+
+	exists, _ := s.Exists(ctx, "a.txt")
+	moved, _ := s.Get(ctx, "b.txt")
+	copied, _ := s.Get(ctx, "copy.txt")
+	n, _ := s.collect(ctx, time.Now().Add(time.Second))
+	if !exists && string(moved) == "content" && string(copied) == "content" && n == 1 && countBlobs(d) == 1 {
+		t.Logf("Move() and Copy() PASS. Expected paths to share one blob\n")
+	} else {
+		t.Errorf("Move() and Copy() FAILED. Got \"%s\", \"%s\", %d collected, %d blobs\n", moved, copied, n, countBlobs(d))
+	}
+
+	if err := s.Move(ctx, "missing.txt", "x.txt"); errors.Is(err, ErrNotFound) {
+		t.Logf("Move() PASS. Expected ErrNotFound, got \"%s\"\n", err)
+	} else {
+		t.Errorf("Move() FAILED. Expected ErrNotFound, got %v\n", err)
+	}
+}
+
+func TestStoreLocalDisk(t *testing.T) {
+	m, err := filesystem.New(filesystem.Config{}, nil)
+	if err != nil {
+		t.Fatalf("filesystem.New() FAILED. Unexpected error \"%s\"", err)
+	}
+	d := filesystem.NewDriverLocal(t.TempDir(), "/", filesystem.VisibilityPrivate)
+	m.Extend("dedup-local", d)
+	s, err := newStore(Config{Disk: "dedup-local"}, m, newMemoryIndex())
+	if err != nil {
+		t.Fatalf("newStore() FAILED. Unexpected error \"%s\"", err)
+	}
+	ctx := context.Background()
+
+	a, err := s.PutBytes(ctx, "a.txt", []byte("local content"))
+	if err != nil {
+		t.Fatalf("PutBytes() FAILED. Unexpected error \"%s\"", err)
+	}
+	_, err = s.PutBytes(ctx, "b.txt", []byte("local content"))
+	content, getErr := s.Get(ctx, "b.txt")
+	blob, _ := d.FileExists(s.blobPath(a.Hash))
+	if err == nil && getErr == nil && string(content) == "local content" && blob {
+		t.Logf("PutBytes() local PASS. Expected the blob stored under its hash, got \"%s\"\n", s.blobPath(a.Hash))
+	} else {
+		t.Errorf("PutBytes() local FAILED. Expected the blob stored under its hash, got %v, %v, %v\n", err, getErr, blob)
+	}
+}
@@ -0,0 +1,47 @@
+package dedup
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrNotFound = errors.New("dedup file is not stored")
+
+// Entry is a logical path pointing at a blob
+type Entry struct {
+	Path      string    `json:"path"`
+	Hash      string    `json:"hash"`
+	Size      int64     `json:"size"`
+	Mime      string    `json:"mime"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Blob is content stored once, Refcount counts the entries pointing at it
+type Blob struct {
+	Hash     string
+	Size     int64
+	Refcount int64
+	// UpdatedAt is the last time the blob was referenced or released
+	UpdatedAt time.Time
+}
+
+// Index records which blob every logical path points at and how many paths reference
+// every blob. Blobs are locked while check and remove run, so a blob cannot be
+// collected while it is referenced again
+type Index interface {
+	// Get returns the entry of path, ErrNotFound when path is not stored
+	Get(ctx context.Context, path string) (*Entry, error)
+	// Link points path at the blob of e and references it, the blob path pointed at
+	// before is released. check, when not nil, runs while the blob is locked, nothing
+	// is recorded when it fails
+	Link(ctx context.Context, e Entry, check func() error) error
+	// Unlink removes path and releases its blob, ErrNotFound when path is not stored
+	Unlink(ctx context.Context, path string) error
+	// Rename points to at the blob of from and removes from, the blob to pointed at
+	// before is released
+	Rename(ctx context.Context, from, to string) error
+	// Collect calls remove for at most limit blobs unreferenced since before, the record
+	// of a blob is deleted once remove succeeds. It returns the number of removed blobs
+	Collect(ctx context.Context, before time.Time, limit int, remove func(Blob) error) (int, error)
+}
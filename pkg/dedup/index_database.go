@@ -0,0 +1,230 @@
+package dedup
+
+import (
+	"context"
+	"github.com/kurneo/go-template/pkg/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type fileRecord struct {
+	Path      string    `gorm:"column:path;primaryKey"`
+	Hash      string    `gorm:"column:hash"`
+	Size      int64     `gorm:"column:size"`
+	Mime      string    `gorm:"column:mime"`
+	UpdatedAt time.Time `gorm:"column:updated_at"`
+}
+
+type blobRecord struct {
+	Hash      string    `gorm:"column:hash;primaryKey"`
+	Size      int64     `gorm:"column:size"`
+	Refcount  int64     `gorm:"column:refcount"`
+	UpdatedAt time.Time `gorm:"column:updated_at"`
+}
+
+// databaseIndex keeps the index in two tables, blob rows are locked with SELECT ... FOR
+// UPDATE or by the upsert referencing them
+type databaseIndex struct {
+	db     database.Contract
+	files  string
+	blobs  string
+	locked clause.Locking
+}
+
+func (i databaseIndex) Get(ctx context.Context, path string) (*Entry, error) {
+	var records []fileRecord
+	err := i.db.GetConnection(ctx).Table(i.files).
+		Where("path = ?", path).
+		Limit(1).
+		Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, ErrNotFound
+	}
+	return records[0].entry(), nil
+}
+
+func (i databaseIndex) Link(ctx context.Context, e Entry, check func() error) error {
+	return i.db.GetConnection(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		// a new path has no row to lock, a placeholder is inserted first so concurrent
+		// links of one path wait for each other and release what the first one linked
+		err := tx.Table(i.files).
+			Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&fileRecord{Path: e.Path, UpdatedAt: now}).Error
+		if err != nil {
+			return err
+		}
+		old, err := i.file(tx, e.Path)
+		if err != nil {
+			return err
+		}
+		if old != nil && old.Hash == "" {
+			old = nil
+		}
+
+		if old == nil || old.Hash != e.Hash {
+			// the upsert locks the blob row until the transaction ends
+			err = tx.Table(i.blobs).
+				Clauses(clause.OnConflict{
+					Columns: []clause.Column{{Name: "hash"}},
+					DoUpdates: clause.Assignments(map[string]interface{}{
+						"refcount":   gorm.Expr("refcount + 1"),
+						"updated_at": now,
+					}),
+				}).
+				Create(&blobRecord{Hash: e.Hash, Size: e.Size, Refcount: 1, UpdatedAt: now}).Error
+			if err != nil {
+				return err
+			}
+		}
+		if check != nil {
+			if err = check(); err != nil {
+				return err
+			}
+		}
+		if old != nil && old.Hash != e.Hash {
+			if err = i.release(tx, old.Hash, now); err != nil {
+				return err
+			}
+		}
+
+		return tx.Table(i.files).
+			Where("path = ?", e.Path).
+			Updates(map[string]interface{}{"hash": e.Hash, "size": e.Size, "mime": e.Mime, "updated_at": now}).Error
+	})
+}
+
+func (i databaseIndex) Unlink(ctx context.Context, path string) error {
+	return i.db.GetConnection(ctx).Transaction(func(tx *gorm.DB) error {
+		old, err := i.file(tx, path)
+		if err != nil {
+			return err
+		}
+		if old == nil {
+			return ErrNotFound
+		}
+		if err = tx.Table(i.files).Where("path = ?", path).Delete(&fileRecord{}).Error; err != nil {
+			return err
+		}
+		return i.release(tx, old.Hash, time.Now())
+	})
+}
+
+func (i databaseIndex) Rename(ctx context.Context, from, to string) error {
+	return i.db.GetConnection(ctx).Transaction(func(tx *gorm.DB) error {
+		src, err := i.file(tx, from)
+		if err != nil {
+			return err
+		}
+		if src == nil {
+			return ErrNotFound
+		}
+		if from == to {
+			return nil
+		}
+
+		now := time.Now()
+		dst, err := i.file(tx, to)
+		if err != nil {
+			return err
+		}
+		if dst != nil {
+			if err = tx.Table(i.files).Where("path = ?", to).Delete(&fileRecord{}).Error; err != nil {
+				return err
+			}
+			if err = i.release(tx, dst.Hash, now); err != nil {
+				return err
+			}
+		}
+
+		return tx.Table(i.files).
+			Where("path = ?", from).
+			Updates(map[string]interface{}{"path": to, "updated_at": now}).Error
+	})
+}
+
+func (i databaseIndex) Collect(ctx context.Context, before time.Time, limit int, remove func(Blob) error) (int, error) {
+	var candidates []blobRecord
+	err := i.db.GetConnection(ctx).Table(i.blobs).
+		Where("refcount <= 0 AND updated_at < ?", before).
+		Order("updated_at").
+		Limit(limit).
+		Find(&candidates).Error
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, c := range candidates {
+		// the blob is locked and checked again, it may have been referenced meanwhile
+		err = i.db.GetConnection(ctx).Transaction(func(tx *gorm.DB) error {
+			var records []blobRecord
+			err := tx.Table(i.blobs).
+				Clauses(i.locked).
+				Where("hash = ? AND refcount <= 0 AND updated_at < ?", c.Hash, before).
+				Limit(1).
+				Find(&records).Error
+			if err != nil || len(records) == 0 {
+				return err
+			}
+			if err = remove(records[0].blob()); err != nil {
+				return err
+			}
+			removed++
+			return tx.Table(i.blobs).Where("hash = ?", c.Hash).Delete(&blobRecord{}).Error
+		})
+		if err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// file returns the locked record of path, nil when path is not stored
+func (i databaseIndex) file(tx *gorm.DB, path string) (*fileRecord, error) {
+	var records []fileRecord
+	err := tx.Table(i.files).
+		Clauses(i.locked).
+		Where("path = ?", path).
+		Limit(1).
+		Find(&records).Error
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	return &records[0], nil
+}
+
+func (i databaseIndex) release(tx *gorm.DB, hash string, now time.Time) error {
+	return tx.Table(i.blobs).
+		Where("hash = ?", hash).
+		Updates(map[string]interface{}{"refcount": gorm.Expr("refcount - 1"), "updated_at": now}).Error
+}
+
+func (r fileRecord) entry() *Entry {
+	return &Entry{Path: r.Path, Hash: r.Hash, Size: r.Size, Mime: r.Mime, UpdatedAt: r.UpdatedAt}
+}
+
+func (r blobRecord) blob() Blob {
+	return Blob{Hash: r.Hash, Size: r.Size, Refcount: r.Refcount, UpdatedAt: r.UpdatedAt}
+}
+
+// newDatabaseIndex returns an index stored in the files and blobs tables, see the
+// dedup migration for their schema
+func newDatabaseIndex(db database.Contract, files, blobs string) Index {
+	if files == "" {
+		files = defaultFilesTable
+	}
+	if blobs == "" {
+		blobs = defaultBlobsTable
+	}
+	return &databaseIndex{
+		db:     db,
+		files:  files,
+		blobs:  blobs,
+		locked: clause.Locking{Strength: "UPDATE"},
+	}
+}
@@ -0,0 +1,145 @@
+package dedup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// sqliteDB is a database.Contract over a sqlite file, transactions behave like the
+// postgres and mysql drivers, GetDB returns the open one
+type sqliteDB struct {
+	db *gorm.DB
+	tx *gorm.DB
+}
+
+func (s *sqliteDB) Close() error {
+	db, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return db.Close()
+}
+
+func (s *sqliteDB) Connect() error {
+	return nil
+}
+
+func (s *sqliteDB) Begin() error {
+	s.tx = s.db.Begin()
+	return s.tx.Error
+}
+
+func (s *sqliteDB) Commit() error {
+	err := s.tx.Commit().Error
+	s.tx = nil
+	return err
+}
+
+func (s *sqliteDB) Rollback() error {
+	err := s.tx.Rollback().Error
+	s.tx = nil
+	return err
+}
+
+func (s *sqliteDB) IsTransaction() bool {
+	return s.tx != nil
+}
+
+func (s *sqliteDB) IsNotFound(err error) bool {
+	return errors.Is(err, gorm.ErrRecordNotFound)
+}
+
+func (s *sqliteDB) GetDB(ctx context.Context) *gorm.DB {
+	if s.tx != nil {
+		return s.tx.WithContext(ctx)
+	}
+	return s.db.WithContext(ctx)
+}
+
+func (s *sqliteDB) GetConnection(ctx context.Context) *gorm.DB {
+	return s.db.WithContext(ctx)
+}
+
+func setupDatabaseIndex(t *testing.T) (Index, *sqliteDB) {
+	gdb, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "dedup.db")+"?_pragma=busy_timeout(5000)"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("gorm.Open() FAILED. Unexpected error \"%s\"", err)
+	}
+	for _, q := range []string{
+		`CREATE TABLE dedup_blobs (hash VARCHAR(64) PRIMARY KEY, size BIGINT NOT NULL, refcount BIGINT NOT NULL, updated_at TIMESTAMP NOT NULL)`,
+		`CREATE TABLE dedup_files ("path" VARCHAR(1024) PRIMARY KEY, hash VARCHAR(64) NOT NULL, size BIGINT NOT NULL, mime VARCHAR(255), updated_at TIMESTAMP NOT NULL)`,
+	} {
+		if err = gdb.Exec(q).Error; err != nil {
+			t.Fatalf("CREATE TABLE FAILED. Unexpected error \"%s\"", err)
+		}
+	}
+	db := &sqliteDB{db: gdb}
+	t.Cleanup(func() { _ = db.Close() })
+	return newDatabaseIndex(db, "", ""), db
+}
+
+func TestDatabaseIndexOutsideTransaction(t *testing.T) {
+	index, db := setupDatabaseIndex(t)
+	ctx := context.Background()
+
+	if err := db.Begin(); err != nil {
+		t.Fatalf("Begin() FAILED. Unexpected error \"%s\"", err)
+	}
+	err := index.Link(ctx, Entry{Path: "a.txt", Hash: "abcd", Size: 1, Mime: "text/plain"}, func() error { return nil })
+	_ = db.Rollback()
+
+	// the blob is placed on the disk, its index rows must outlive the request transaction
+	e, getErr := index.Get(ctx, "a.txt")
+	if err == nil && getErr == nil && e.Hash == "abcd" {
+		t.Logf("Link() PASS. Expected the entry kept after a rollback, got %+v\n", e)
+	} else {
+		t.Errorf("Link() FAILED. Expected the entry kept after a rollback, got %v, %v\n", err, getErr)
+	}
+
+	_ = index.Unlink(ctx, "a.txt")
+	n, err := index.Collect(ctx, time.Now().Add(time.Minute), collectBatchSize, func(Blob) error { return nil })
+	if err == nil && n == 1 {
+		t.Logf("Collect() PASS. Expected 1 blob collected, got %d\n", n)
+	} else {
+		t.Errorf("Collect() FAILED. Expected 1 blob collected, got %d, %v\n", n, err)
+	}
+}
+
+func TestDatabaseIndexConcurrentLink(t *testing.T) {
+	index, db := setupDatabaseIndex(t)
+	ctx := context.Background()
+
+	for n := 0; n < 10; n++ {
+		p := fmt.Sprintf("new/%d.txt", n)
+		errs := make(chan error, 2)
+		for _, hash := range []string{fmt.Sprintf("a%d", n), fmt.Sprintf("b%d", n)} {
+			go func(hash string) {
+				errs <- index.Link(ctx, Entry{Path: p, Hash: hash, Size: 1}, nil)
+			}(hash)
+		}
+		err := errors.Join(<-errs, <-errs)
+
+		e, getErr := index.Get(ctx, p)
+		var blobs []blobRecord
+		_ = db.db.Table("dedup_blobs").Where("hash IN ?", []string{fmt.Sprintf("a%d", n), fmt.Sprintf("b%d", n)}).Find(&blobs).Error
+		refs := map[string]int64{}
+		for _, b := range blobs {
+			refs[b.Hash] = b.Refcount
+		}
+		loser := fmt.Sprintf("a%d", n)
+		if getErr == nil && e.Hash == loser {
+			loser = fmt.Sprintf("b%d", n)
+		}
+		if err == nil && getErr == nil && refs[e.Hash] == 1 && refs[loser] == 0 {
+			t.Logf("Link() concurrent PASS. Expected the losing blob released, got %v\n", refs)
+		} else {
+			t.Errorf("Link() concurrent FAILED. Expected refcounts 1 and 0, got %v, %v, %v\n", refs, err, getErr)
+		}
+	}
+}
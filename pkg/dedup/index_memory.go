@@ -0,0 +1,127 @@
+package dedup
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// memoryIndex keeps the index in memory, a single lock serialises every change
+type memoryIndex struct {
+	mu    sync.Mutex
+	files map[string]Entry
+	blobs map[string]Blob
+}
+
+func (i *memoryIndex) Get(_ context.Context, path string) (*Entry, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	e, ok := i.files[path]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &e, nil
+}
+
+func (i *memoryIndex) Link(_ context.Context, e Entry, check func() error) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if check != nil {
+		if err := check(); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	e.UpdatedAt = now
+	old, exists := i.files[e.Path]
+	if !exists || old.Hash != e.Hash {
+		b, ok := i.blobs[e.Hash]
+		if !ok {
+			b = Blob{Hash: e.Hash, Size: e.Size}
+		}
+		b.Refcount++
+		b.UpdatedAt = now
+		i.blobs[e.Hash] = b
+		if exists {
+			i.release(old.Hash, now)
+		}
+	}
+	i.files[e.Path] = e
+	return nil
+}
+
+func (i *memoryIndex) Unlink(_ context.Context, path string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	e, ok := i.files[path]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(i.files, path)
+	i.release(e.Hash, time.Now())
+	return nil
+}
+
+func (i *memoryIndex) Rename(_ context.Context, from, to string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	e, ok := i.files[from]
+	if !ok {
+		return ErrNotFound
+	}
+	if from == to {
+		return nil
+	}
+
+	now := time.Now()
+	if old, ok := i.files[to]; ok {
+		i.release(old.Hash, now)
+	}
+	delete(i.files, from)
+	e.Path = to
+	e.UpdatedAt = now
+	i.files[to] = e
+	return nil
+}
+
+func (i *memoryIndex) Collect(_ context.Context, before time.Time, limit int, remove func(Blob) error) (int, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	candidates := make([]Blob, 0)
+	for _, b := range i.blobs {
+		if b.Refcount <= 0 && b.UpdatedAt.Before(before) {
+			candidates = append(candidates, b)
+		}
+	}
+	sort.Slice(candidates, func(a, b int) bool { return candidates[a].UpdatedAt.Before(candidates[b].UpdatedAt) })
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+
+	for n, b := range candidates {
+		if err := remove(b); err != nil {
+			return n, err
+		}
+		delete(i.blobs, b.Hash)
+	}
+	return len(candidates), nil
+}
+
+func (i *memoryIndex) release(hash string, now time.Time) {
+	b := i.blobs[hash]
+	b.Refcount--
+	b.UpdatedAt = now
+	i.blobs[hash] = b
+}
+
+// newMemoryIndex returns an index kept in memory for tests
+func newMemoryIndex() Index {
+	return &memoryIndex{
+		files: make(map[string]Entry),
+		blobs: make(map[string]Blob),
+	}
+}
@@ -0,0 +1,528 @@
+package filesystem
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"github.com/kurneo/go-template/pkg/filesystem/helper"
+	"io"
+	"io/fs"
+	"os"
+	pathPkg "path"
+	"strings"
+	"time"
+)
+
+type ArchiveFormat string
+
+const (
+	ArchiveZip   ArchiveFormat = "zip"
+	ArchiveTarGz ArchiveFormat = "tar.gz"
+)
+
+const (
+	defaultExtractMaxFiles     = 10_000
+	defaultExtractMaxFileSize  = 512 * 1024 * 1024
+	defaultExtractMaxTotalSize = 2 * 1024 * 1024 * 1024
+)
+
+var (
+	ErrArchiveFormatNotSupported = errors.New("archive format is not supported")
+	ErrArchiveTooLarge           = errors.New("archive exceeds the extraction limits")
+)
+
+var archivePreFixer = helper.NewPreFixer("", "/")
+
+// ParseArchiveFormat parses "zip", "tar.gz" or "tgz"
+func ParseArchiveFormat(s string) (ArchiveFormat, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "zip":
+		return ArchiveZip, nil
+	case "tar.gz", "tgz":
+		return ArchiveTarGz, nil
+	default:
+		return "", fmt.Errorf("%w: \"%s\"", ErrArchiveFormatNotSupported, s)
+	}
+}
+
+func (f ArchiveFormat) Mime() string {
+	if f == ArchiveZip {
+		return "application/zip"
+	}
+	return "application/gzip"
+}
+
+// Extension returns the file extension of the format with its leading dot
+func (f ArchiveFormat) Extension() string {
+	return "." + string(f)
+}
+
+// ExtractLimits bounds what an archive may extract, the sizes are counted on the
+// decompressed content rather than trusted from the archive headers
+type ExtractLimits struct {
+	// MaxFiles extracted, directories included, defaults to 10000
+	MaxFiles int
+	// MaxFileSize of a single file, defaults to 512 MiB
+	MaxFileSize int64
+	// MaxTotalSize of all files, defaults to 2 GiB. A zip archive read from a stream is
+	// buffered in a temporary file, it may not exceed MaxTotalSize either
+	MaxTotalSize int64
+}
+
+func (l ExtractLimits) withDefaults() ExtractLimits {
+	if l.MaxFiles <= 0 {
+		l.MaxFiles = defaultExtractMaxFiles
+	}
+	if l.MaxFileSize <= 0 {
+		l.MaxFileSize = defaultExtractMaxFileSize
+	}
+	if l.MaxTotalSize <= 0 {
+		l.MaxTotalSize = defaultExtractMaxTotalSize
+	}
+	return l
+}
+
+// Archive streams the directory tree at dir of disk into w, entries are named relative to dir
+func (m *Manager) Archive(w io.Writer, disk, dir string, format ArchiveFormat) error {
+	d, err := m.Disk(disk)
+	if err != nil {
+		return err
+	}
+	return WriteArchive(w, d, dir, format)
+}
+
+// ArchiveTo streams the directory tree at dir of srcDisk into an archive written to dstPath
+// of dstDisk, the archive is never held in memory
+func (m *Manager) ArchiveTo(srcDisk, dir, dstDisk, dstPath string, format ArchiveFormat) error {
+	src, err := m.Disk(srcDisk)
+	if err != nil {
+		return err
+	}
+	dst, err := m.Disk(dstDisk)
+	if err != nil {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(WriteArchive(pw, src, dir, format))
+	}()
+	err = dst.PutStream(dstPath, pr, PutOptions{ContentType: format.Mime()})
+	// stops the writer when the disk gave up before the end of the archive
+	pr.CloseWithError(err)
+	return err
+}
+
+// Extract extracts the archive read from r into dir of disk and returns the paths of the
+// extracted files. Entries leaving dir fail with ErrPathOutsideRoot, links and devices are
+// skipped and the files extracted so far are deleted when any entry fails
+func (m *Manager) Extract(r io.Reader, format ArchiveFormat, disk, dir string, limits ExtractLimits) ([]string, error) {
+	d, err := m.Disk(disk)
+	if err != nil {
+		return nil, err
+	}
+	return ExtractArchive(r, format, d, dir, limits)
+}
+
+// WriteArchive writes the directory tree at dir of d into w, empty directories included
+func WriteArchive(w io.Writer, d DriverContract, dir string, format ArchiveFormat) error {
+	root, err := archivePreFixer.Normalize(dir)
+	if err != nil {
+		return err
+	}
+
+	var aw archiveWriter
+	switch format {
+	case ArchiveZip:
+		aw = &zipArchiveWriter{w: zip.NewWriter(w)}
+	case ArchiveTarGz:
+		gz := gzip.NewWriter(w)
+		aw = &tarArchiveWriter{gz: gz, w: tar.NewWriter(gz)}
+	default:
+		return fmt.Errorf("%w: \"%s\"", ErrArchiveFormatNotSupported, format)
+	}
+
+	err = d.Walk(root, func(file *File, directory *Directory) error {
+		if directory != nil {
+			name, err := archiveEntryName(root, directory.Path)
+			if err != nil {
+				return err
+			}
+			return aw.dir(name, modTime(directory.ModTime))
+		}
+
+		name, err := archiveEntryName(root, file.Path)
+		if err != nil {
+			return err
+		}
+		// listed sizes may differ from the content, e.g. on encrypted disks
+		size, err := d.Size(file.Path)
+		if err != nil {
+			return err
+		}
+		r, err := d.ReadStream(file.Path)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := r.Close(); err != nil {
+				fmt.Println(err)
+			}
+		}()
+		return aw.file(name, size, modTime(file.ModTime), r)
+	})
+	if err != nil {
+		return err
+	}
+	return aw.Close()
+}
+
+// ExtractArchive extracts the archive read from r into dir of d, see Manager.Extract
+func ExtractArchive(r io.Reader, format ArchiveFormat, d DriverContract, dir string, limits ExtractLimits) ([]string, error) {
+	root, err := archivePreFixer.Normalize(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	x := &extractor{d: d, root: root, limits: limits.withDefaults(), paths: make([]string, 0), created: map[string]bool{}}
+	switch format {
+	case ArchiveZip:
+		err = x.zip(r)
+	case ArchiveTarGz:
+		err = x.tarGz(r)
+	default:
+		err = fmt.Errorf("%w: \"%s\"", ErrArchiveFormatNotSupported, format)
+	}
+
+	if err != nil {
+		// files the archive overwrote are left in place, only new ones are removed
+		for _, p := range x.paths {
+			if x.created[p] {
+				_ = d.Delete(p)
+			}
+		}
+		for i := len(x.dirs) - 1; i >= 0; i-- {
+			_ = d.Delete(x.dirs[i])
+		}
+		return nil, err
+	}
+	return x.paths, nil
+}
+
+type archiveWriter interface {
+	dir(name string, modTime time.Time) error
+	file(name string, size int64, modTime time.Time, r io.Reader) error
+	Close() error
+}
+
+type zipArchiveWriter struct {
+	w *zip.Writer
+}
+
+func (a *zipArchiveWriter) dir(name string, modTime time.Time) error {
+	_, err := a.w.CreateHeader(&zip.FileHeader{Name: name + "/", Modified: modTime})
+	return err
+}
+
+func (a *zipArchiveWriter) file(name string, _ int64, modTime time.Time, r io.Reader) error {
+	w, err := a.w.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+func (a *zipArchiveWriter) Close() error {
+	return a.w.Close()
+}
+
+type tarArchiveWriter struct {
+	gz *gzip.Writer
+	w  *tar.Writer
+}
+
+func (a *tarArchiveWriter) dir(name string, modTime time.Time) error {
+	return a.w.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: name + "/", Mode: 0755, ModTime: modTime})
+}
+
+func (a *tarArchiveWriter) file(name string, size int64, modTime time.Time, r io.Reader) error {
+	err := a.w.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Size: size, Mode: 0644, ModTime: modTime})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(a.w, r)
+	return err
+}
+
+func (a *tarArchiveWriter) Close() error {
+	if err := a.w.Close(); err != nil {
+		return err
+	}
+	return a.gz.Close()
+}
+
+type extractor struct {
+	d      DriverContract
+	root   string
+	limits ExtractLimits
+	total  int64
+	// entries counts files and directories against limits.MaxFiles
+	entries int
+	paths   []string
+	// dirs are the directories that did not exist before the extraction
+	dirs []string
+	// created tells for every checked path whether it did not exist before the extraction
+	created map[string]bool
+}
+
+func (x *extractor) zip(r io.Reader) error {
+	ra, size, cleanup, err := x.readerAt(r)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	zr, err := zip.NewReader(ra, size)
+	if err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		mode := f.Mode()
+		if mode.IsDir() {
+			if err = x.dir(f.Name); err != nil {
+				return err
+			}
+			continue
+		}
+		if !mode.IsRegular() {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = x.file(f.Name, rc)
+		_ = rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readerAt returns r when it supports random access, otherwise r is buffered in a
+// temporary file removed by cleanup
+func (x *extractor) readerAt(r io.Reader) (io.ReaderAt, int64, func(), error) {
+	if ra, ok := r.(interface {
+		io.ReaderAt
+		Size() int64
+	}); ok {
+		return ra, ra.Size(), func() {}, nil
+	}
+	if f, ok := r.(*os.File); ok {
+		info, err := f.Stat()
+		if err == nil && info.Mode().IsRegular() {
+			return f, info.Size(), func() {}, nil
+		}
+	}
+
+	tmp, err := os.CreateTemp("", "extract-*.zip")
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	cleanup := func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}
+	n, err := io.Copy(tmp, io.LimitReader(r, x.limits.MaxTotalSize+1))
+	if err == nil && n > x.limits.MaxTotalSize {
+		err = ErrArchiveTooLarge
+	}
+	if err != nil {
+		cleanup()
+		return nil, 0, nil, err
+	}
+	return tmp, n, cleanup, nil
+}
+
+func (x *extractor) tarGz(r io.Reader) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = gz.Close()
+	}()
+
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch h.Typeflag {
+		case tar.TypeDir:
+			err = x.dir(h.Name)
+		case tar.TypeReg:
+			err = x.file(h.Name, tr)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (x *extractor) dir(name string) error {
+	p, err := x.path(name)
+	if err != nil || p == x.root {
+		return err
+	}
+	if err = x.count(); err != nil {
+		return err
+	}
+	if err = x.trackDirs(p); err != nil {
+		return err
+	}
+	if err = x.d.MakeDir(p, 0755); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	return nil
+}
+
+func (x *extractor) file(name string, r io.Reader) error {
+	p, err := x.path(name)
+	if err != nil {
+		return err
+	}
+	if p == x.root {
+		return fmt.Errorf("archive entry \"%s\" has no name", name)
+	}
+	if err = x.count(); err != nil {
+		return err
+	}
+	if err = x.trackDirs(pathPkg.Dir(p)); err != nil {
+		return err
+	}
+
+	limit := min(x.limits.MaxFileSize, x.limits.MaxTotalSize-x.total)
+	lr := &extractLimitReader{r: r, n: limit}
+	if _, checked := x.created[p]; !checked {
+		exists, err := x.d.FileExists(p)
+		if err != nil {
+			return err
+		}
+		x.created[p] = !exists
+	}
+	x.paths = append(x.paths, p)
+	if err = x.d.PutStream(p, lr, PutOptions{}); err != nil {
+		if lr.exceeded {
+			return fmt.Errorf("%w: \"%s\"", ErrArchiveTooLarge, name)
+		}
+		return err
+	}
+	if lr.exceeded {
+		return fmt.Errorf("%w: \"%s\"", ErrArchiveTooLarge, name)
+	}
+	x.total += limit - lr.n
+	return nil
+}
+
+// count fails once the archive holds more than limits.MaxFiles entries
+func (x *extractor) count() error {
+	x.entries++
+	if x.entries > x.limits.MaxFiles {
+		return fmt.Errorf("%w: more than %d entries", ErrArchiveTooLarge, x.limits.MaxFiles)
+	}
+	return nil
+}
+
+// trackDirs records dir and its parents missing before the extraction, they are
+// created by MakeDir or PutStream and removed again when the extraction fails
+func (x *extractor) trackDirs(dir string) error {
+	var missing []string
+	for ; dir != "" && dir != "." && dir != "/"; dir = pathPkg.Dir(dir) {
+		if _, checked := x.created[dir]; checked {
+			break
+		}
+		exists, err := x.d.DirExists(dir)
+		if err != nil {
+			return err
+		}
+		x.created[dir] = !exists
+		if exists {
+			break
+		}
+		missing = append(missing, dir)
+	}
+	// parents first so the rollback removes the deepest directories first
+	for i := len(missing) - 1; i >= 0; i-- {
+		x.dirs = append(x.dirs, missing[i])
+	}
+	return nil
+}
+
+// path joins the entry name to the extraction root, names resolving outside of it
+// (zip slip) fail with a PathTraversalError
+func (x *extractor) path(name string) (string, error) {
+	if strings.HasPrefix(name, "/") || strings.HasPrefix(name, "\\") {
+		return "", &PathTraversalError{Path: name}
+	}
+	rel, err := archivePreFixer.Normalize(name)
+	if err != nil {
+		return "", err
+	}
+	if x.root == "" {
+		return rel, nil
+	}
+	if rel == "" {
+		return x.root, nil
+	}
+	return x.root + "/" + rel, nil
+}
+
+// extractLimitReader fails once more than n bytes are read
+type extractLimitReader struct {
+	r        io.Reader
+	n        int64
+	exceeded bool
+}
+
+func (l *extractLimitReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) > l.n {
+		l.exceeded = true
+		l.n = 0
+		return 0, ErrArchiveTooLarge
+	}
+	l.n -= int64(n)
+	return n, err
+}
+
+func archiveEntryName(root, p string) (string, error) {
+	p, err := archivePreFixer.Normalize(p)
+	if err != nil {
+		return "", err
+	}
+	if root == "" {
+		return p, nil
+	}
+	return strings.TrimPrefix(p, root+"/"), nil
+}
+
+func modTime(t *time.Time) time.Time {
+	if t == nil {
+		return time.Now()
+	}
+	return *t
+}
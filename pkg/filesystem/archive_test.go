@@ -0,0 +1,183 @@
+package filesystem
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func setupArchiveDriver() DriverContract {
+	d := NewDriverMemory()
+	_ = d.Put("assets/logo.svg", []byte("<svg></svg>"))
+	_ = d.Put("assets/css/app.css", []byte(strings.Repeat("body{}", 100)))
+	_ = d.MakeDir("assets/empty", 0755)
+	_ = d.Put("other.txt", []byte("not archived"))
+	return d
+}
+
+func newZip(entries map[string]string) []byte {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for name, content := range entries {
+		f, _ := w.Create(name)
+		_, _ = f.Write([]byte(content))
+	}
+	_ = w.Close()
+	return buf.Bytes()
+}
+
+func newTarGz(entries map[string]string) []byte {
+	buf := new(bytes.Buffer)
+	gz := gzip.NewWriter(buf)
+	w := tar.NewWriter(gz)
+	for name, content := range entries {
+		_ = w.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Size: int64(len(content)), Mode: 0644})
+		_, _ = w.Write([]byte(content))
+	}
+	_ = w.WriteHeader(&tar.Header{Typeflag: tar.TypeSymlink, Name: "link", Linkname: "/etc/passwd"})
+	_ = w.Close()
+	_ = gz.Close()
+	return buf.Bytes()
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	for _, format := range []ArchiveFormat{ArchiveZip, ArchiveTarGz} {
+		src := setupArchiveDriver()
+		buf := new(bytes.Buffer)
+		if err := WriteArchive(buf, src, "/assets/", format); err != nil {
+			t.Fatalf("WriteArchive(%s) FAILED. Unexpected error \"%s\"", format, err)
+		}
+
+		dst := NewDriverMemory()
+		// a plain reader forces zip archives through the temporary file
+		paths, err := ExtractArchive(io.MultiReader(buf), format, dst, "restored", ExtractLimits{})
+		sort.Strings(paths)
+		css, _ := dst.Get("restored/css/app.css")
+		empty, _ := dst.DirExists("restored/empty")
+		expect := []string{"restored/css/app.css", "restored/logo.svg"}
+		if err == nil && strings.Join(paths, ",") == strings.Join(expect, ",") && string(css) == strings.Repeat("body{}", 100) && empty {
+			t.Logf("ExtractArchive(%s) PASS. Expected %v, got %v\n", format, expect, paths)
+		} else {
+			t.Errorf("ExtractArchive(%s) FAILED. Expected %v, got %v, %v (empty dir %t)\n", format, expect, paths, err, empty)
+		}
+	}
+}
+
+func TestArchiveTo(t *testing.T) {
+	m := setupTransferManager(t)
+	src, _ := m.Disk("memory")
+	_ = src.Put("assets/logo.svg", []byte("<svg></svg>"))
+
+	err := m.ArchiveTo("memory", "assets", "archive", "exports/assets.zip", ArchiveZip)
+	dst, _ := m.Disk("archive")
+	content, _ := dst.Get("exports/assets.zip")
+	r, zipErr := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err == nil && zipErr == nil && len(r.File) == 1 && r.File[0].Name == "logo.svg" {
+		t.Logf("ArchiveTo() PASS. Expected zip holding logo.svg\n")
+	} else {
+		t.Errorf("ArchiveTo() FAILED. Expected zip holding logo.svg, got %v, %v\n", err, zipErr)
+	}
+
+	if err = m.ArchiveTo("memory", "missing", "archive", "exports/missing.zip", ArchiveZip); err != nil {
+		t.Logf("ArchiveTo() PASS. Expected error for a missing directory, got \"%s\"\n", err)
+	} else {
+		t.Errorf("ArchiveTo() FAILED. Expected error for a missing directory, got nil\n")
+	}
+}
+
+func TestExtractZipSlip(t *testing.T) {
+	for name, archive := range map[string]struct {
+		format  ArchiveFormat
+		content []byte
+	}{
+		"zip parent":      {format: ArchiveZip, content: newZip(map[string]string{"a.txt": "a", "../../evil.txt": "evil"})},
+		"zip absolute":    {format: ArchiveZip, content: newZip(map[string]string{"/etc/cron.d/evil": "evil"})},
+		"zip backslash":   {format: ArchiveZip, content: newZip(map[string]string{"a\\..\\..\\evil.txt": "evil"})},
+		"tar.gz parent":   {format: ArchiveTarGz, content: newTarGz(map[string]string{"docs/../../evil.txt": "evil"})},
+		"tar.gz absolute": {format: ArchiveTarGz, content: newTarGz(map[string]string{"/evil.txt": "evil"})},
+	} {
+		d := NewDriverMemory()
+		_, err := ExtractArchive(bytes.NewReader(archive.content), archive.format, d, "uploads", ExtractLimits{})
+		files, _, _ := d.ListContentsRecursive("")
+		if errors.Is(err, ErrPathOutsideRoot) && len(files) == 0 {
+			t.Logf("ExtractArchive() %s PASS. Expected ErrPathOutsideRoot, got \"%s\"\n", name, err)
+		} else {
+			t.Errorf("ExtractArchive() %s FAILED. Expected ErrPathOutsideRoot and no files, got %v with %d files\n", name, err, len(files))
+		}
+	}
+
+	d := NewDriverMemory()
+	paths, err := ExtractArchive(bytes.NewReader(newTarGz(map[string]string{"a.txt": "a"})), ArchiveTarGz, d, "uploads", ExtractLimits{})
+	if err == nil && len(paths) == 1 && paths[0] == "uploads/a.txt" {
+		t.Logf("ExtractArchive() PASS. Expected symlink to be skipped, got %v\n", paths)
+	} else {
+		t.Errorf("ExtractArchive() FAILED. Expected [uploads/a.txt], got %v, %v\n", paths, err)
+	}
+}
+
+func TestExtractLimits(t *testing.T) {
+	big := strings.Repeat("0", 10_000)
+	for name, tc := range map[string]struct {
+		content []byte
+		limits  ExtractLimits
+	}{
+		"file size":  {content: newZip(map[string]string{"a.txt": "a", "b.txt": big}), limits: ExtractLimits{MaxFileSize: 1000}},
+		"total size": {content: newZip(map[string]string{"a.txt": big[:600], "b.txt": big[:600]}), limits: ExtractLimits{MaxTotalSize: 1000}},
+		"files":      {content: newZip(map[string]string{"a.txt": "a", "b.txt": "b", "c.txt": "c"}), limits: ExtractLimits{MaxFiles: 2}},
+		"dirs":       {content: newZip(map[string]string{"a/": "", "b/": "", "c/": ""}), limits: ExtractLimits{MaxFiles: 2}},
+	} {
+		d := NewDriverMemory()
+		_, err := ExtractArchive(bytes.NewReader(tc.content), ArchiveZip, d, "", tc.limits)
+		files, dirs, _ := d.ListContentsRecursive("")
+		if errors.Is(err, ErrArchiveTooLarge) && len(files) == 0 && len(dirs) == 0 {
+			t.Logf("ExtractArchive() %s PASS. Expected ErrArchiveTooLarge, got \"%s\"\n", name, err)
+		} else {
+			t.Errorf("ExtractArchive() %s FAILED. Expected ErrArchiveTooLarge and nothing extracted, got %v with %d files, %d dirs\n", name, err, len(files), len(dirs))
+		}
+	}
+
+	_, err := ExtractArchive(io.MultiReader(bytes.NewReader(newZip(map[string]string{"a.txt": big}))), ArchiveZip, NewDriverMemory(), "", ExtractLimits{MaxTotalSize: 100})
+	if errors.Is(err, ErrArchiveTooLarge) {
+		t.Logf("ExtractArchive() PASS. Expected streamed archive above the limit to fail, got \"%s\"\n", err)
+	} else {
+		t.Errorf("ExtractArchive() FAILED. Expected ErrArchiveTooLarge for a streamed archive, got %v\n", err)
+	}
+}
+
+func TestExtractRollback(t *testing.T) {
+	d := NewDriverMemory()
+	_ = d.Put("uploads/a.txt", []byte("existing"))
+	_ = d.MakeDir("uploads/kept", 0755)
+
+	// entries in order, the last one breaks the limits
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for _, e := range []struct{ name, content string }{
+		{"a.txt", "a"},
+		{"kept/", ""},
+		{"empty/", ""},
+		{"b.txt", "b"},
+		{"nested/deep/c.txt", "c"},
+		{"d.txt", strings.Repeat("0", 10_000)},
+	} {
+		f, _ := w.Create(e.name)
+		_, _ = f.Write([]byte(e.content))
+	}
+	_ = w.Close()
+	_, err := ExtractArchive(bytes.NewReader(buf.Bytes()), ArchiveZip, d, "uploads", ExtractLimits{MaxFileSize: 1000})
+
+	aExists, _ := d.FileExists("uploads/a.txt")
+	keptExists, _ := d.DirExists("uploads/kept")
+	files, dirs, _ := d.ListContentsRecursive("uploads")
+	if errors.Is(err, ErrArchiveTooLarge) && aExists && keptExists && len(files) == 1 && len(dirs) == 1 {
+		t.Logf("ExtractArchive() rollback PASS. Expected only new files and directories removed, got \"%s\"\n", err)
+	} else {
+		t.Errorf("ExtractArchive() rollback FAILED. Expected a.txt and kept/ only, got %v, %v, %v\n", err, files, dirs)
+	}
+}
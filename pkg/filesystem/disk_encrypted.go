@@ -0,0 +1,310 @@
+package filesystem
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+type EncryptionConfig struct {
+	// Disk wrapped by the encrypted disk, empty means the default disk
+	Disk string
+	// KeyId of the key new files are encrypted with, it may be empty when a single key is configured
+	KeyId string
+	// Keys by id, AES-128, AES-192 or AES-256 keys. Retired keys are kept to read the files
+	// written before a rotation
+	Keys map[string][]byte
+	// ChunkSize of plaintext sealed at once, defaults to 64 KiB
+	ChunkSize int
+}
+
+// diskEncrypted encrypts every file written to disk with AES-GCM and decrypts it when read.
+// Paths, directories and visibility are left as is, only the content is encrypted
+type diskEncrypted struct {
+	disk      DriverContract
+	keys      map[string][]byte
+	keyId     string
+	chunkSize int
+}
+
+func (s diskEncrypted) FileExists(path string) (bool, error) {
+	return s.disk.FileExists(path)
+}
+
+func (s diskEncrypted) DirExists(path string) (bool, error) {
+	return s.disk.DirExists(path)
+}
+
+func (s diskEncrypted) Put(path string, content []byte) error {
+	return s.PutStream(path, bytes.NewReader(content), PutOptions{Size: int64(len(content))})
+}
+
+func (s diskEncrypted) Get(path string) ([]byte, error) {
+	r, err := s.ReadStream(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := r.Close(); err != nil {
+			fmt.Println(err)
+		}
+	}()
+	return io.ReadAll(r)
+}
+
+func (s diskEncrypted) MakeDir(path string, perm os.FileMode) error {
+	return s.disk.MakeDir(path, perm)
+}
+
+func (s diskEncrypted) Delete(path string) error {
+	return s.disk.Delete(path)
+}
+
+func (s diskEncrypted) Rename(from, to string) error {
+	return s.disk.Rename(from, to)
+}
+
+// ListContents lists the files of the wrapped disk, their Size is the encrypted size
+func (s diskEncrypted) ListContents(path string) ([]File, []Directory, error) {
+	return s.disk.ListContents(path)
+}
+
+func (s diskEncrypted) ListContentsRecursive(path string) ([]File, []Directory, error) {
+	return s.disk.ListContentsRecursive(path)
+}
+
+func (s diskEncrypted) Walk(path string, fn WalkFunc) error {
+	return s.disk.Walk(path, fn)
+}
+
+// Move and Copy keep the content encrypted, the path is not part of the sealed data
+func (s diskEncrypted) Move(from, to string) error {
+	return s.disk.Move(from, to)
+}
+
+func (s diskEncrypted) Copy(from, to string) error {
+	return s.disk.Copy(from, to)
+}
+
+// Mime returns the type of the extension, or the sniffed type of the decrypted first bytes
+func (s diskEncrypted) Mime(path string) string {
+	if t := mime.TypeByExtension(filepath.Ext(path)); t != "" {
+		return t
+	}
+	r, err := s.ReadStream(path)
+	if err != nil {
+		return ""
+	}
+	defer func() {
+		if err := r.Close(); err != nil {
+			fmt.Println(err)
+		}
+	}()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return ""
+	}
+	return http.DetectContentType(head[:n])
+}
+
+func (s diskEncrypted) RealPath(path string) string {
+	return s.disk.RealPath(path)
+}
+
+func (s diskEncrypted) RealDirPath(path string) string {
+	return s.disk.RealDirPath(path)
+}
+
+func (s diskEncrypted) IsDir(path string) (bool, error) {
+	return s.disk.IsDir(path)
+}
+
+func (s diskEncrypted) IsFile(path string) (bool, error) {
+	return s.disk.IsFile(path)
+}
+
+// PutStream encrypts r chunk by chunk with the current key while it is written
+func (s diskEncrypted) PutStream(path string, r io.Reader, opts PutOptions) error {
+	h, err := newEncryptionHeader(s.keyId, s.chunkSize)
+	if err != nil {
+		return err
+	}
+	opts.ContentType, r = detectContentType(path, r, opts)
+	if opts.Size > 0 {
+		opts.Size = h.encryptedSize(opts.Size)
+	}
+	aead, err := h.aead(s.keys[s.keyId])
+	if err != nil {
+		return err
+	}
+	return s.disk.PutStream(path, newEncryptReader(r, aead, h), opts)
+}
+
+// ReadStream decrypts path with the key it was written with, it fails with ErrNotEncrypted
+// for files written to the wrapped disk directly and ErrEncryptionKeyNotFound when the key
+// was removed from the config
+func (s diskEncrypted) ReadStream(path string) (io.ReadCloser, error) {
+	rc, err := s.disk.ReadStream(path)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(rc)
+	h, err := readEncryptionHeader(br)
+	if err == nil {
+		var aead cipher.AEAD
+		if aead, err = s.aead(h); err == nil {
+			return newDecryptReader(br, rc, aead, h), nil
+		}
+	}
+	if err := rc.Close(); err != nil {
+		fmt.Println(err)
+	}
+	return nil, err
+}
+
+// Size returns the plaintext size of path
+func (s diskEncrypted) Size(path string) (int64, error) {
+	size, err := s.disk.Size(path)
+	if err != nil {
+		return 0, err
+	}
+	h, err := s.header(path)
+	if err != nil {
+		return 0, err
+	}
+	return h.plainSize(size)
+}
+
+func (s diskEncrypted) LastModified(path string) (time.Time, error) {
+	return s.disk.LastModified(path)
+}
+
+// Checksum returns the SHA-256 of the plaintext, so it matches the checksum of the uploaded content
+func (s diskEncrypted) Checksum(path string) (string, error) {
+	return checksum(s.ReadStream(path))
+}
+
+func (s diskEncrypted) SetVisibility(path string, v Visibility) error {
+	return s.disk.SetVisibility(path, v)
+}
+
+func (s diskEncrypted) GetVisibility(path string) (Visibility, error) {
+	return s.disk.GetVisibility(path)
+}
+
+// KeyId returns the id of the key path is encrypted with
+func (s diskEncrypted) KeyId(path string) (string, error) {
+	h, err := s.header(path)
+	if err != nil {
+		return "", err
+	}
+	return h.keyId, nil
+}
+
+// Reencrypt encrypts path again with the current key when it was written with another one.
+// The file is written next to path first and moved over it once complete
+func (s diskEncrypted) Reencrypt(path string) error {
+	keyId, err := s.KeyId(path)
+	if err != nil || keyId == s.keyId {
+		return err
+	}
+	v, err := s.disk.GetVisibility(path)
+	if err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 8)
+	if _, err = rand.Read(suffix); err != nil {
+		return err
+	}
+	tmp := path + ".reencrypt-" + hex.EncodeToString(suffix)
+
+	r, err := s.ReadStream(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := r.Close(); err != nil {
+			fmt.Println(err)
+		}
+	}()
+	if err = s.PutStream(tmp, r, PutOptions{ContentType: s.disk.Mime(path), Visibility: v}); err != nil {
+		_ = s.disk.Delete(tmp)
+		return err
+	}
+	return s.disk.Move(tmp, path)
+}
+
+func (s diskEncrypted) header(path string) (*encryptionHeader, error) {
+	r, err := s.disk.ReadStream(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := r.Close(); err != nil {
+			fmt.Println(err)
+		}
+	}()
+	return readEncryptionHeader(r)
+}
+
+func (s diskEncrypted) aead(h *encryptionHeader) (cipher.AEAD, error) {
+	key, ok := s.keys[h.keyId]
+	if !ok {
+		return nil, fmt.Errorf("%w: \"%s\"", ErrEncryptionKeyNotFound, h.keyId)
+	}
+	return h.aead(key)
+}
+
+func NewDiskEncrypted(d DriverContract, c EncryptionConfig) (DiskEncryptedContract, error) {
+	if len(c.Keys) == 0 {
+		return nil, errors.New("encrypted disk requires at least one key")
+	}
+	if c.KeyId == "" {
+		if len(c.Keys) > 1 {
+			ids := make([]string, 0, len(c.Keys))
+			for id := range c.Keys {
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+			return nil, fmt.Errorf("encrypted disk key id is required, configured keys are %v", ids)
+		}
+		for id := range c.Keys {
+			c.KeyId = id
+		}
+	}
+	if c.ChunkSize == 0 {
+		c.ChunkSize = defaultEncryptionChunkSize
+	}
+	if c.ChunkSize < 0 || c.ChunkSize > maxEncryptionChunkSize {
+		return nil, fmt.Errorf("encrypted disk chunk size must be between 1 and %d bytes", maxEncryptionChunkSize)
+	}
+
+	keys := make(map[string][]byte, len(c.Keys))
+	for id, key := range c.Keys {
+		if id == "" || len(id) > 255 {
+			return nil, fmt.Errorf("encryption key id \"%s\" must be 1 to 255 bytes long", id)
+		}
+		if _, err := newAEAD(key); err != nil {
+			return nil, fmt.Errorf("encryption key \"%s\": %w", id, err)
+		}
+		keys[id] = key
+	}
+	if _, ok := keys[c.KeyId]; !ok {
+		return nil, fmt.Errorf("%w: \"%s\"", ErrEncryptionKeyNotFound, c.KeyId)
+	}
+
+	return &diskEncrypted{disk: d, keys: keys, keyId: c.KeyId, chunkSize: c.ChunkSize}, nil
+}
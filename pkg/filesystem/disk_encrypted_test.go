@@ -0,0 +1,183 @@
+package filesystem
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+var (
+	encryptionKeyOld = bytes.Repeat([]byte{1}, 32)
+	encryptionKeyNew = bytes.Repeat([]byte{2}, 16)
+)
+
+func setupEncryptedDisk(t *testing.T, keyId string) (DiskEncryptedContract, DriverContract) {
+	d := NewDriverMemory()
+	s, err := NewDiskEncrypted(d, EncryptionConfig{
+		KeyId:     keyId,
+		Keys:      map[string][]byte{"old": encryptionKeyOld, "new": encryptionKeyNew},
+		ChunkSize: 16,
+	})
+	if err != nil {
+		t.Fatalf("NewDiskEncrypted() FAILED. Unexpected error \"%s\"", err)
+	}
+	return s, d
+}
+
+func TestEncryptedPutGet(t *testing.T) {
+	s, d := setupEncryptedDisk(t, "old")
+
+	for _, size := range []int{0, 1, 15, 16, 17, 32, 100} {
+		content := []byte(strings.Repeat("personal data. ", 7)[:size])
+		path := "exports/" + strings.Repeat("a", size+1) + ".txt"
+		if err := s.Put(path, content); err != nil {
+			t.Fatalf("Put(\"%s\") FAILED. Unexpected error \"%s\"", path, err)
+		}
+
+		result, err := s.Get(path)
+		stored, _ := d.Get(path)
+		// a few plaintext bytes may appear by chance in the random salt
+		encrypted := size < 8 || !bytes.Contains(stored, content[:8])
+		if err == nil && bytes.Equal(result, content) && encrypted {
+			t.Logf("Get() %d bytes PASS. Expected decrypted content\n", size)
+		} else {
+			t.Errorf("Get() %d bytes FAILED. Expected \"%s\", got \"%s\", %v (encrypted %t)\n", size, content, result, err, encrypted)
+		}
+
+		n, err := s.Size(path)
+		if err == nil && n == int64(size) {
+			t.Logf("Size() %d bytes PASS. Expected %d, got %d\n", size, size, n)
+		} else {
+			t.Errorf("Size() %d bytes FAILED. Expected %d, got %d, %v\n", size, size, n, err)
+		}
+	}
+}
+
+func TestEncryptedFileKeys(t *testing.T) {
+	s, d := setupEncryptedDisk(t, "new")
+	content := []byte(strings.Repeat("same content ", 4))
+	_ = s.Put("a.txt", content)
+	_ = s.Put("b.txt", content)
+
+	a, _ := d.Get("a.txt")
+	b, _ := d.Get("b.txt")
+	ha, _ := readEncryptionHeader(bytes.NewReader(a))
+	hb, _ := readEncryptionHeader(bytes.NewReader(b))
+	if ha != nil && hb != nil && !bytes.Equal(ha.salt, hb.salt) && !bytes.Equal(a[len(ha.raw):], b[len(hb.raw):]) {
+		t.Logf("Put() PASS. Expected every file sealed with its own key\n")
+	} else {
+		t.Errorf("Put() FAILED. Expected different salts and ciphertexts for the same content\n")
+	}
+}
+
+func TestEncryptedStream(t *testing.T) {
+	s, _ := setupEncryptedDisk(t, "new")
+	content := strings.Repeat("0123456789", 50)
+
+	if err := s.PutStream("export.csv", strings.NewReader(content), PutOptions{}); err != nil {
+		t.Fatalf("PutStream() FAILED. Unexpected error \"%s\"", err)
+	}
+	r, err := s.ReadStream("export.csv")
+	if err != nil {
+		t.Fatalf("ReadStream() FAILED. Unexpected error \"%s\"", err)
+	}
+	result, err := io.ReadAll(r)
+	_ = r.Close()
+	if err == nil && string(result) == content {
+		t.Logf("ReadStream() PASS. Expected decrypted stream\n")
+	} else {
+		t.Errorf("ReadStream() FAILED. Expected \"%s\", got \"%s\", %v\n", content, result, err)
+	}
+
+	sum := sha256.Sum256([]byte(content))
+	expect := hex.EncodeToString(sum[:])
+	if result, err := s.Checksum("export.csv"); err == nil && result == expect {
+		t.Logf("Checksum() PASS. Expected plaintext checksum \"%s\"\n", expect)
+	} else {
+		t.Errorf("Checksum() FAILED. Expected \"%s\", got \"%s\", %v\n", expect, result, err)
+	}
+}
+
+func TestEncryptedTampering(t *testing.T) {
+	s, d := setupEncryptedDisk(t, "old")
+	_ = s.Put("a.txt", []byte(strings.Repeat("x", 40)))
+	stored, _ := d.Get("a.txt")
+
+	for name, content := range map[string][]byte{
+		"flipped byte":     append(append(append([]byte{}, stored[:30]...), stored[30]^1), stored[31:]...),
+		"dropped chunk":    stored[:len(stored)-(8+encryptionTagSize)],
+		"truncated header": stored[:10],
+		"plain":            []byte("not encrypted at all"),
+	} {
+		_ = d.Put("b.txt", content)
+		_, err := s.Get("b.txt")
+		if errors.Is(err, ErrDecryptionFailed) || errors.Is(err, ErrNotEncrypted) {
+			t.Logf("Get() %s PASS. Expected error, got \"%s\"\n", name, err)
+		} else {
+			t.Errorf("Get() %s FAILED. Expected ErrDecryptionFailed or ErrNotEncrypted, got %v\n", name, err)
+		}
+	}
+}
+
+func TestEncryptedKeyRotation(t *testing.T) {
+	old, d := setupEncryptedDisk(t, "old")
+	_ = old.Put("a.txt", []byte("written before the rotation"))
+
+	s, err := NewDiskEncrypted(d, EncryptionConfig{
+		KeyId: "new",
+		Keys:  map[string][]byte{"old": encryptionKeyOld, "new": encryptionKeyNew},
+	})
+	if err != nil {
+		t.Fatalf("NewDiskEncrypted() FAILED. Unexpected error \"%s\"", err)
+	}
+
+	if result, err := s.Get("a.txt"); err == nil && string(result) == "written before the rotation" {
+		t.Logf("Get() PASS. Expected file of the old key to be readable\n")
+	} else {
+		t.Errorf("Get() FAILED. Expected file of the old key to be readable, got \"%s\", %v\n", result, err)
+	}
+
+	err = s.Reencrypt("a.txt")
+	keyId, _ := s.KeyId("a.txt")
+	result, _ := s.Get("a.txt")
+	files, _, _ := d.ListContents("")
+	if err == nil && keyId == "new" && string(result) == "written before the rotation" && len(files) == 1 {
+		t.Logf("Reencrypt() PASS. Expected key \"new\", got \"%s\"\n", keyId)
+	} else {
+		t.Errorf("Reencrypt() FAILED. Expected key \"new\", got \"%s\", %v with %d files\n", keyId, err, len(files))
+	}
+
+	retired, _ := NewDiskEncrypted(d, EncryptionConfig{Keys: map[string][]byte{"other": encryptionKeyOld}})
+	if _, err = retired.Get("a.txt"); errors.Is(err, ErrEncryptionKeyNotFound) {
+		t.Logf("Get() PASS. Expected ErrEncryptionKeyNotFound, got \"%s\"\n", err)
+	} else {
+		t.Errorf("Get() FAILED. Expected ErrEncryptionKeyNotFound, got %v\n", err)
+	}
+}
+
+func TestNewDiskEncrypted(t *testing.T) {
+	for name, c := range map[string]EncryptionConfig{
+		"no keys":         {},
+		"missing key id":  {Keys: map[string][]byte{"a": encryptionKeyOld, "b": encryptionKeyNew}},
+		"unknown key id":  {KeyId: "c", Keys: map[string][]byte{"a": encryptionKeyOld}},
+		"invalid key":     {Keys: map[string][]byte{"a": []byte("short")}},
+		"huge chunk size": {Keys: map[string][]byte{"a": encryptionKeyOld}, ChunkSize: maxEncryptionChunkSize + 1},
+	} {
+		if _, err := NewDiskEncrypted(NewDriverMemory(), c); err != nil {
+			t.Logf("NewDiskEncrypted() %s PASS. Expected error, got \"%s\"\n", name, err)
+		} else {
+			t.Errorf("NewDiskEncrypted() %s FAILED. Expected error, got nil\n", name)
+		}
+	}
+
+	keys, err := ParseEncryptionKeys("2024:AQEBAQEBAQEBAQEBAQEBAQ== 2023:AgICAgICAgICAgICAgICAg==")
+	if err == nil && len(keys) == 2 && bytes.Equal(keys["2024"], bytes.Repeat([]byte{1}, 16)) {
+		t.Logf("ParseEncryptionKeys() PASS. Got %d keys\n", len(keys))
+	} else {
+		t.Errorf("ParseEncryptionKeys() FAILED. Expected 2 keys, got %v, %v\n", keys, err)
+	}
+}
@@ -2,11 +2,14 @@ package filesystem
 
 import (
 	"github.com/kurneo/go-template/pkg/filesystem/helper"
+	"io"
 	"os"
+	"time"
 )
 
 type diskLocal struct {
 	driver *driverLocal
+	signer *UrlSigner
 }
 
 func (s diskLocal) FileExists(path string) (bool, error) {
@@ -41,6 +44,14 @@ func (s diskLocal) ListContents(path string) ([]File, []Directory, error) {
 	return s.driver.ListContents(path)
 }
 
+func (s diskLocal) ListContentsRecursive(path string) ([]File, []Directory, error) {
+	return s.driver.ListContentsRecursive(path)
+}
+
+func (s diskLocal) Walk(path string, fn WalkFunc) error {
+	return s.driver.Walk(path, fn)
+}
+
 func (s diskLocal) Move(from, to string) error {
 	return s.driver.Move(from, to)
 }
@@ -69,10 +80,45 @@ func (s diskLocal) RealDirPath(path string) string {
 	return s.driver.RealDirPath(path)
 }
 
-func NewDiskLocal(prefix, separator string) DiskLocalContract {
+func (s diskLocal) PutStream(path string, r io.Reader, opts PutOptions) error {
+	return s.driver.PutStream(path, r, opts)
+}
+
+func (s diskLocal) ReadStream(path string) (io.ReadCloser, error) {
+	return s.driver.ReadStream(path)
+}
+
+func (s diskLocal) Size(path string) (int64, error) {
+	return s.driver.Size(path)
+}
+
+func (s diskLocal) LastModified(path string) (time.Time, error) {
+	return s.driver.LastModified(path)
+}
+
+func (s diskLocal) Checksum(path string) (string, error) {
+	return s.driver.Checksum(path)
+}
+
+func (s diskLocal) SetVisibility(path string, v Visibility) error {
+	return s.driver.SetVisibility(path, v)
+}
+
+func (s diskLocal) GetVisibility(path string) (Visibility, error) {
+	return s.driver.GetVisibility(path)
+}
+
+// TemporaryUrl returns a signed url served by the route of the signer
+func (s diskLocal) TemporaryUrl(path string, expiry time.Duration) (string, error) {
+	return s.signer.Sign(path, expiry)
+}
+
+func NewDiskLocal(prefix, separator string, visibility Visibility, signer *UrlSigner) DiskLocalContract {
 	return &diskLocal{
 		driver: &driverLocal{
-			preFixer: helper.NewPreFixer(prefix, separator),
+			preFixer:   helper.NewPreFixer(prefix, separator),
+			visibility: visibility,
 		},
+		signer: signer,
 	}
 }
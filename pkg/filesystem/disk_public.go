@@ -2,13 +2,16 @@ package filesystem
 
 import (
 	"github.com/kurneo/go-template/pkg/filesystem/helper"
+	"io"
 	"os"
 	"strings"
+	"time"
 )
 
 type diskPublic struct {
 	driver *driverLocal
 	url    string
+	signer *UrlSigner
 }
 
 func (s diskPublic) FileExists(path string) (bool, error) {
@@ -43,6 +46,14 @@ func (s diskPublic) ListContents(path string) ([]File, []Directory, error) {
 	return s.driver.ListContents(path)
 }
 
+func (s diskPublic) ListContentsRecursive(path string) ([]File, []Directory, error) {
+	return s.driver.ListContentsRecursive(path)
+}
+
+func (s diskPublic) Walk(path string, fn WalkFunc) error {
+	return s.driver.Walk(path, fn)
+}
+
 func (s diskPublic) Move(from, to string) error {
 	return s.driver.Move(from, to)
 }
@@ -71,6 +82,26 @@ func (s diskPublic) RealDirPath(path string) string {
 	return s.driver.RealDirPath(path)
 }
 
+func (s diskPublic) PutStream(path string, r io.Reader, opts PutOptions) error {
+	return s.driver.PutStream(path, r, opts)
+}
+
+func (s diskPublic) ReadStream(path string) (io.ReadCloser, error) {
+	return s.driver.ReadStream(path)
+}
+
+func (s diskPublic) Size(path string) (int64, error) {
+	return s.driver.Size(path)
+}
+
+func (s diskPublic) LastModified(path string) (time.Time, error) {
+	return s.driver.LastModified(path)
+}
+
+func (s diskPublic) Checksum(path string) (string, error) {
+	return s.driver.Checksum(path)
+}
+
 func (s diskPublic) Url(path string) string {
 	if s.url == "" {
 		return ""
@@ -78,11 +109,26 @@ func (s diskPublic) Url(path string) string {
 	return strings.TrimRight(s.url, "\\/") + "/" + path
 }
 
-func NewDiskPublic(prefix, separator, url string) DiskPublicContract {
+func (s diskPublic) SetVisibility(path string, v Visibility) error {
+	return s.driver.SetVisibility(path, v)
+}
+
+func (s diskPublic) GetVisibility(path string) (Visibility, error) {
+	return s.driver.GetVisibility(path)
+}
+
+// TemporaryUrl returns a signed url served by the route of the signer
+func (s diskPublic) TemporaryUrl(path string, expiry time.Duration) (string, error) {
+	return s.signer.Sign(path, expiry)
+}
+
+func NewDiskPublic(prefix, separator, url string, visibility Visibility, signer *UrlSigner) DiskPublicContract {
 	return &diskPublic{
 		driver: &driverLocal{
-			preFixer: helper.NewPreFixer(prefix, separator),
+			preFixer:   helper.NewPreFixer(prefix, separator),
+			visibility: visibility,
 		},
-		url: url,
+		url:    url,
+		signer: signer,
 	}
 }
@@ -1,24 +1,34 @@
 package filesystem
 
-import "strings"
+import (
+	"strings"
+	"time"
+)
 
-type S3Disk struct {
-	DiskContract
+// diskS3 exposes a bucket backed driver (s3 or any S3 compatible service such as minio) with public urls
+type diskS3 struct {
+	DriverContract
 	url string
 }
 
-func (s S3Disk) Url(path string) string {
+func (s diskS3) Url(path string) string {
 	if s.url == "" {
 		return ""
 	}
-	return strings.TrimRight(s.url, "\\/") + "/" + path
+	return strings.TrimRight(s.url, "\\/") + "/" + strings.TrimLeft(path, "\\/")
 }
 
-func NewS3Disk(driver DriverContract, url string) PublicDiskContract {
-	return PublicDisk{
-		disk{
-			driver: driver,
-		},
-		url,
+// TemporaryUrl returns a presigned url when the driver supports it
+func (s diskS3) TemporaryUrl(path string, expiry time.Duration) (string, error) {
+	if t, ok := s.DriverContract.(TemporaryUrlContract); ok {
+		return t.TemporaryUrl(path, expiry)
+	}
+	return "", ErrTemporaryUrlNotSupported
+}
+
+func NewDiskS3(driver DriverContract, url string) DiskS3Contract {
+	return &diskS3{
+		DriverContract: driver,
+		url:            url,
 	}
 }
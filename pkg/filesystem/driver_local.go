@@ -5,18 +5,25 @@ import (
 	"github.com/kurneo/go-template/pkg/filesystem/helper"
 	cp "github.com/otiai10/copy"
 	"io"
+	"io/fs"
 	"mime"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 type driverLocal struct {
-	preFixer helper.PathPreFixer
+	preFixer   helper.PathPreFixer
+	visibility Visibility
 }
 
 func (d driverLocal) FileExists(path string) (bool, error) {
-	stat, err := os.Stat(d.preFixer.PrefixPath(path))
+	p, err := d.preFixer.PrefixPath(path)
+	if err != nil {
+		return false, err
+	}
+	stat, err := os.Stat(p)
 
 	if stat != nil {
 		return stat.IsDir() == false, nil
@@ -32,7 +39,11 @@ func (d driverLocal) FileExists(path string) (bool, error) {
 }
 
 func (d driverLocal) DirExists(path string) (bool, error) {
-	stat, err := os.Stat(d.preFixer.PrefixPath(path))
+	p, err := d.preFixer.PrefixPath(path)
+	if err != nil {
+		return false, err
+	}
+	stat, err := os.Stat(p)
 
 	if stat != nil {
 		return stat.IsDir(), nil
@@ -48,7 +59,11 @@ func (d driverLocal) DirExists(path string) (bool, error) {
 }
 
 func (d driverLocal) Put(path string, content []byte) error {
-	f, err := os.Create(d.preFixer.PrefixPath(path))
+	p, err := d.preFixer.PrefixPath(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(p)
 	if err != nil {
 		return err
 	}
@@ -65,11 +80,15 @@ func (d driverLocal) Put(path string, content []byte) error {
 		return err
 	}
 
-	return nil
+	return f.Chmod(d.defaultVisibility().fileMode())
 }
 
 func (d driverLocal) Get(path string) ([]byte, error) {
-	file, err := os.Open(d.preFixer.PrefixPath(path))
+	p, err := d.preFixer.PrefixPath(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(p)
 	if err != nil {
 		return nil, err
 	}
@@ -89,7 +108,11 @@ func (d driverLocal) Get(path string) ([]byte, error) {
 }
 
 func (d driverLocal) MakeDir(path string, perm os.FileMode) error {
-	err := os.MkdirAll(d.preFixer.PrefixPath(path), perm)
+	p, err := d.preFixer.PrefixPath(path)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(p, perm)
 	if err != nil {
 		return err
 	}
@@ -97,7 +120,11 @@ func (d driverLocal) MakeDir(path string, perm os.FileMode) error {
 }
 
 func (d driverLocal) Delete(path string) error {
-	err := os.RemoveAll(d.preFixer.PrefixPath(path))
+	p, err := d.preFixer.PrefixPath(path)
+	if err != nil {
+		return err
+	}
+	err = os.RemoveAll(p)
 	if err != nil {
 		return err
 	}
@@ -105,7 +132,11 @@ func (d driverLocal) Delete(path string) error {
 }
 
 func (d driverLocal) Rename(from, to string) error {
-	err := os.Rename(d.preFixer.PrefixPath(from), d.preFixer.PrefixPath(to))
+	src, dst, err := d.prefixPaths(from, to)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(src, dst)
 	if err != nil {
 		return err
 	}
@@ -113,7 +144,11 @@ func (d driverLocal) Rename(from, to string) error {
 }
 
 func (d driverLocal) ListContents(path string) ([]File, []Directory, error) {
-	reader, err := os.Open(d.preFixer.PrefixPath(path))
+	p, err := d.preFixer.PrefixPath(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	reader, err := os.Open(p)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -155,12 +190,24 @@ func (d driverLocal) ListContents(path string) ([]File, []Directory, error) {
 	return files, directories, nil
 }
 
+func (d driverLocal) ListContentsRecursive(path string) ([]File, []Directory, error) {
+	return listContentsRecursive(d, path)
+}
+
+func (d driverLocal) Walk(path string, fn WalkFunc) error {
+	return walkLevels(d, path, fn)
+}
+
 func (d driverLocal) Move(from, to string) error {
 	return d.Rename(from, to)
 }
 
 func (d driverLocal) Copy(from, to string) error {
-	err := cp.Copy(d.preFixer.PrefixPath(from), d.preFixer.PrefixPath(to))
+	src, dst, err := d.prefixPaths(from, to)
+	if err != nil {
+		return err
+	}
+	err = cp.Copy(src, dst)
 	if err != nil {
 		return err
 	}
@@ -171,16 +218,25 @@ func (d driverLocal) Mime(path string) string {
 	return mime.TypeByExtension(filepath.Ext(path))
 }
 
+// RealPath returns the path on the host, or an empty string when path leaves the root
 func (d driverLocal) RealPath(path string) string {
-	return d.preFixer.PrefixPath(path)
+	p, err := d.preFixer.PrefixPath(path)
+	if err != nil {
+		return ""
+	}
+	return p
 }
 
 func (d driverLocal) RealDirPath(path string) string {
-	return d.preFixer.PrefixDirectoryPath(path)
+	p, err := d.preFixer.PrefixDirectoryPath(path)
+	if err != nil {
+		return ""
+	}
+	return p
 }
 
 func (d driverLocal) IsDir(path string) (bool, error) {
-	fi, err := os.Stat(d.preFixer.PrefixPath(path))
+	fi, err := d.stat(path)
 	if err != nil {
 		return false, err
 	} else if fi.IsDir() {
@@ -190,7 +246,7 @@ func (d driverLocal) IsDir(path string) (bool, error) {
 }
 
 func (d driverLocal) IsFile(path string) (bool, error) {
-	fi, err := os.Stat(d.preFixer.PrefixPath(path))
+	fi, err := d.stat(path)
 	if err != nil {
 		return false, err
 	} else if !fi.IsDir() {
@@ -199,8 +255,164 @@ func (d driverLocal) IsFile(path string) (bool, error) {
 	return false, nil
 }
 
-func NewDriverLocal(prefix, separator string) DriverContract {
+func (d driverLocal) PutStream(path string, r io.Reader, opts PutOptions) error {
+	target, err := d.preFixer.PrefixPath(path)
+	if err != nil {
+		return err
+	}
+
+	// like object stores, streamed writes create missing parent directories
+	if err = os.MkdirAll(filepath.Dir(target), d.defaultVisibility().dirMode()); err != nil {
+		return err
+	}
+
+	// write next to the target and rename so readers never see a partial file
+	f, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(f, r); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+
+	if err = os.Chmod(f.Name(), opts.Visibility.or(d.defaultVisibility()).fileMode()); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+
+	if err = os.Rename(f.Name(), target); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+func (d driverLocal) ReadStream(path string) (io.ReadCloser, error) {
+	p, err := d.preFixer.PrefixPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+func (d driverLocal) Size(path string) (int64, error) {
+	fi, err := d.stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return fi.Size(), nil
+}
+
+func (d driverLocal) LastModified(path string) (time.Time, error) {
+	fi, err := d.stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return fi.ModTime(), nil
+}
+
+func (d driverLocal) Checksum(path string) (string, error) {
+	return checksum(d.ReadStream(path))
+}
+
+func (d driverLocal) SetVisibility(path string, v Visibility) error {
+	p, err := d.preFixer.PrefixPath(path)
+	if err != nil {
+		return err
+	}
+	fi, err := os.Stat(p)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return os.Chmod(p, v.dirMode())
+	}
+	return os.Chmod(p, v.fileMode())
+}
+
+func (d driverLocal) GetVisibility(path string) (Visibility, error) {
+	fi, err := d.stat(path)
+	if err != nil {
+		return "", err
+	}
+	return visibilityOfMode(fi.Mode()), nil
+}
+
+// copyFrom copies with the host filesystem when src is another local root
+func (d driverLocal) copyFrom(src DriverContract, from, to string, v Visibility) (bool, error) {
+	var other driverLocal
+	switch l := src.(type) {
+	case driverLocal:
+		other = l
+	case *driverLocal:
+		other = *l
+	default:
+		return false, nil
+	}
+
+	srcPath, err := other.preFixer.PrefixPath(from)
+	if err != nil {
+		return true, err
+	}
+	dstPath, err := d.preFixer.PrefixPath(to)
+	if err != nil {
+		return true, err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(dstPath), d.defaultVisibility().dirMode()); err != nil {
+		return true, err
+	}
+	if err = cp.Copy(srcPath, dstPath); err != nil {
+		return true, err
+	}
+	// cp.Copy copies whole trees, every copied entry takes the visibility
+	v = v.or(d.defaultVisibility())
+	return true, filepath.WalkDir(dstPath, func(p string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if e.IsDir() {
+			return os.Chmod(p, v.dirMode())
+		}
+		return os.Chmod(p, v.fileMode())
+	})
+}
+
+func (d driverLocal) stat(path string) (os.FileInfo, error) {
+	p, err := d.preFixer.PrefixPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return os.Stat(p)
+}
+
+func (d driverLocal) prefixPaths(from, to string) (string, string, error) {
+	src, err := d.preFixer.PrefixPath(from)
+	if err != nil {
+		return "", "", err
+	}
+	dst, err := d.preFixer.PrefixPath(to)
+	if err != nil {
+		return "", "", err
+	}
+	return src, dst, nil
+}
+
+func (d driverLocal) defaultVisibility() Visibility {
+	return d.visibility.or(VisibilityPrivate)
+}
+
+func NewDriverLocal(prefix, separator string, visibility Visibility) DriverContract {
 	return driverLocal{
-		preFixer: helper.NewPreFixer(prefix, separator),
+		preFixer:   helper.NewPreFixer(prefix, separator),
+		visibility: visibility,
 	}
 }
@@ -1,9 +1,14 @@
 package filesystem
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"github.com/kurneo/go-template/pkg/filesystem/helper"
+	"io"
 	"log"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -247,3 +252,133 @@ func TestCopy(t *testing.T) {
 		t.Errorf("FileExists(\"%s\") FAILED. Expected %t, got %t\n", copyFile, true, result)
 	}
 }
+
+func TestPutStream(t *testing.T) {
+	d := setupLocalDriver()
+	defer func() { teardownLocalDriver() }()
+
+	fileName := "stream.txt"
+	content := strings.Repeat("streamed content ", 1024)
+
+	err := d.PutStream(fileName, strings.NewReader(content), PutOptions{})
+	if err == nil {
+		t.Logf("PutStream(\"%s\") PASS. Expected error nil, got nil\n", fileName)
+	} else {
+		t.Errorf("PutStream(\"%s\") FAILED. Expected error nil, got error \"%s\"\n", fileName, err.Error())
+	}
+
+	r, err := d.ReadStream(fileName)
+	if err != nil {
+		t.Fatalf("ReadStream(\"%s\") FAILED. Expected error nil, got error \"%s\"\n", fileName, err.Error())
+	}
+	b, _ := io.ReadAll(r)
+	_ = r.Close()
+	if string(b) == content {
+		t.Logf("ReadStream(\"%s\") PASS. Expected written content, got %d bytes\n", fileName, len(b))
+	} else {
+		t.Errorf("ReadStream(\"%s\") FAILED. Expected written content, got %d bytes\n", fileName, len(b))
+	}
+
+	_, err = d.ReadStream("missing.txt")
+	if err != nil {
+		t.Logf("ReadStream(\"%s\") PASS. Expected error, got \"%s\"\n", "missing.txt", err.Error())
+	} else {
+		t.Errorf("ReadStream(\"%s\") FAILED. Expected error, got nil\n", "missing.txt")
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	d := setupLocalDriver()
+	defer func() { teardownLocalDriver() }()
+
+	fileName := "test.txt"
+
+	size, err := d.Size(fileName)
+	if err == nil && size == int64(len("test file")) {
+		t.Logf("Size(\"%s\") PASS. Expected %d, got %d\n", fileName, len("test file"), size)
+	} else {
+		t.Errorf("Size(\"%s\") FAILED. Expected %d, got %d, %v\n", fileName, len("test file"), size, err)
+	}
+
+	modTime, err := d.LastModified(fileName)
+	if err == nil && !modTime.IsZero() {
+		t.Logf("LastModified(\"%s\") PASS. Got %s\n", fileName, modTime)
+	} else {
+		t.Errorf("LastModified(\"%s\") FAILED. Expected modification time, got %s, %v\n", fileName, modTime, err)
+	}
+
+	sum := sha256.Sum256([]byte("test file"))
+	expect := hex.EncodeToString(sum[:])
+	actual, err := d.Checksum(fileName)
+	if err == nil && actual == expect {
+		t.Logf("Checksum(\"%s\") PASS. Expected \"%s\", got \"%s\"\n", fileName, expect, actual)
+	} else {
+		t.Errorf("Checksum(\"%s\") FAILED. Expected \"%s\", got \"%s\", %v\n", fileName, expect, actual, err)
+	}
+}
+
+func TestVisibility(t *testing.T) {
+	d := setupLocalDriver()
+	defer func() { teardownLocalDriver() }()
+
+	fileName := "visibility.txt"
+	_ = d.Put(fileName, []byte("private by default"))
+
+	v, err := d.GetVisibility(fileName)
+	if err == nil && v == VisibilityPrivate {
+		t.Logf("GetVisibility(\"%s\") PASS. Expected \"%s\", got \"%s\"\n", fileName, VisibilityPrivate, v)
+	} else {
+		t.Errorf("GetVisibility(\"%s\") FAILED. Expected \"%s\", got \"%s\", %v\n", fileName, VisibilityPrivate, v, err)
+	}
+
+	_ = d.SetVisibility(fileName, VisibilityPublic)
+	v, _ = d.GetVisibility(fileName)
+	if v == VisibilityPublic {
+		t.Logf("SetVisibility(\"%s\") PASS. Expected \"%s\", got \"%s\"\n", fileName, VisibilityPublic, v)
+	} else {
+		t.Errorf("SetVisibility(\"%s\") FAILED. Expected \"%s\", got \"%s\"\n", fileName, VisibilityPublic, v)
+	}
+
+	fileName = "stream-public.txt"
+	_ = d.PutStream(fileName, strings.NewReader("public"), PutOptions{Visibility: VisibilityPublic})
+	fi, _ := os.Stat(d.RealPath(fileName))
+	if fi != nil && fi.Mode().Perm() == 0644 {
+		t.Logf("PutStream(\"%s\") PASS. Expected mode 0644, got %o\n", fileName, fi.Mode().Perm())
+	} else {
+		t.Errorf("PutStream(\"%s\") FAILED. Expected mode 0644, got %v\n", fileName, fi)
+	}
+}
+
+func TestPathTraversal(t *testing.T) {
+	d := setupLocalDriver()
+	defer func() { teardownLocalDriver() }()
+
+	for _, path := range []string{"../../etc/passwd", "..\\..\\etc\\passwd", "test/../../test.txt"} {
+		_, err := d.Get(path)
+		if errors.Is(err, ErrPathOutsideRoot) {
+			t.Logf("Get(\"%s\") PASS. Expected ErrPathOutsideRoot, got \"%s\"\n", path, err)
+		} else {
+			t.Errorf("Get(\"%s\") FAILED. Expected ErrPathOutsideRoot, got %v\n", path, err)
+		}
+
+		if err = d.Put(path, []byte("escaped")); errors.Is(err, ErrPathOutsideRoot) {
+			t.Logf("Put(\"%s\") PASS. Expected ErrPathOutsideRoot, got \"%s\"\n", path, err)
+		} else {
+			t.Errorf("Put(\"%s\") FAILED. Expected ErrPathOutsideRoot, got %v\n", path, err)
+		}
+	}
+
+	path := "test/../test.txt"
+	if content, err := d.Get(path); err == nil && string(content) == "test file" {
+		t.Logf("Get(\"%s\") PASS. Expected \"test file\", got \"%s\"\n", path, content)
+	} else {
+		t.Errorf("Get(\"%s\") FAILED. Expected \"test file\", got \"%s\", %v\n", path, content, err)
+	}
+
+	path = "test\\..\\test.txt"
+	if exist, err := d.FileExists(path); err == nil && exist {
+		t.Logf("FileExists(\"%s\") PASS. Expected true, got %t\n", path, exist)
+	} else {
+		t.Errorf("FileExists(\"%s\") FAILED. Expected true, got %t, %v\n", path, exist, err)
+	}
+}
@@ -0,0 +1,489 @@
+package filesystem
+
+import (
+	"bytes"
+	"github.com/kurneo/go-template/pkg/filesystem/helper"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	pathPkg "path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryFile struct {
+	content    []byte
+	modTime    time.Time
+	visibility Visibility
+}
+
+// driverMemory keeps files in memory, it is meant for tests of code using disks.
+// Like object stores, parent directories are created implicitly on write
+type driverMemory struct {
+	mu    sync.RWMutex
+	files map[string]*memoryFile
+	dirs  map[string]time.Time
+}
+
+func (d *driverMemory) FileExists(path string) (bool, error) {
+	p, err := cleanMemoryPath(path)
+	if err != nil {
+		return false, err
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	_, ok := d.files[p]
+	return ok, nil
+}
+
+func (d *driverMemory) DirExists(path string) (bool, error) {
+	p, err := cleanMemoryPath(path)
+	if err != nil {
+		return false, err
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.dirExists(p), nil
+}
+
+func (d *driverMemory) Put(path string, content []byte) error {
+	return d.write(path, bytes.Clone(content), VisibilityPrivate)
+}
+
+func (d *driverMemory) Get(path string) ([]byte, error) {
+	p, err := cleanMemoryPath(path)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	f, ok := d.files[p]
+	if !ok {
+		return nil, memoryNotExist("open", path)
+	}
+	return bytes.Clone(f.content), nil
+}
+
+func (d *driverMemory) MakeDir(path string, perm os.FileMode) error {
+	p, err := cleanMemoryPath(path)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.files[p]; ok {
+		return &fs.PathError{Op: "mkdir", Path: path, Err: fs.ErrExist}
+	}
+	d.makeDirs(p, time.Now())
+	return nil
+}
+
+func (d *driverMemory) Delete(path string) error {
+	p, err := cleanMemoryPath(path)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if p == "" {
+		d.files = make(map[string]*memoryFile)
+		d.dirs = make(map[string]time.Time)
+		return nil
+	}
+	delete(d.files, p)
+	delete(d.dirs, p)
+	prefix := p + "/"
+	for k := range d.files {
+		if strings.HasPrefix(k, prefix) {
+			delete(d.files, k)
+		}
+	}
+	for k := range d.dirs {
+		if strings.HasPrefix(k, prefix) {
+			delete(d.dirs, k)
+		}
+	}
+	return nil
+}
+
+func (d *driverMemory) Rename(from, to string) error {
+	return d.Move(from, to)
+}
+
+func (d *driverMemory) ListContents(path string) ([]File, []Directory, error) {
+	p, err := cleanMemoryPath(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if !d.dirExists(p) {
+		return nil, nil, memoryNotExist("open", path)
+	}
+
+	prefix := ""
+	if p != "" {
+		prefix = p + "/"
+	}
+
+	files := make([]File, 0)
+	dirNames := make(map[string]struct{})
+
+	for k, f := range d.files {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		rest := k[len(prefix):]
+		if i := strings.Index(rest, "/"); i >= 0 {
+			dirNames[rest[:i]] = struct{}{}
+			continue
+		}
+		t := f.modTime
+		s := int64(len(f.content))
+		e := filepath.Ext(rest)
+		m := d.mime(k, f.content)
+		files = append(files, File{
+			Path:      k,
+			Name:      rest,
+			ModTime:   &t,
+			Size:      &s,
+			Mime:      &m,
+			Extension: &e,
+		})
+	}
+	for k := range d.dirs {
+		if !strings.HasPrefix(k, prefix) || k == p {
+			continue
+		}
+		rest := k[len(prefix):]
+		if i := strings.Index(rest, "/"); i >= 0 {
+			rest = rest[:i]
+		}
+		dirNames[rest] = struct{}{}
+	}
+
+	directories := make([]Directory, 0, len(dirNames))
+	for name := range dirNames {
+		directory := Directory{
+			Path: prefix + name,
+			Name: name,
+		}
+		if t, ok := d.dirs[prefix+name]; ok {
+			directory.ModTime = &t
+		}
+		directories = append(directories, directory)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	sort.Slice(directories, func(i, j int) bool { return directories[i].Name < directories[j].Name })
+
+	return files, directories, nil
+}
+
+func (d *driverMemory) ListContentsRecursive(path string) ([]File, []Directory, error) {
+	return listContentsRecursive(d, path)
+}
+
+func (d *driverMemory) Walk(path string, fn WalkFunc) error {
+	return walkLevels(d, path, fn)
+}
+
+func (d *driverMemory) Move(from, to string) error {
+	src, dst, err := cleanMemoryPaths(from, to)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if src == dst {
+		return nil
+	}
+	if err = d.copy(src, dst, from); err != nil {
+		return err
+	}
+	delete(d.files, src)
+	delete(d.dirs, src)
+	prefix := src + "/"
+	for k := range d.files {
+		if strings.HasPrefix(k, prefix) {
+			delete(d.files, k)
+		}
+	}
+	for k := range d.dirs {
+		if strings.HasPrefix(k, prefix) {
+			delete(d.dirs, k)
+		}
+	}
+	return nil
+}
+
+func (d *driverMemory) Copy(from, to string) error {
+	src, dst, err := cleanMemoryPaths(from, to)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.copy(src, dst, from)
+}
+
+func (d *driverMemory) Mime(path string) string {
+	p, err := cleanMemoryPath(path)
+	if err != nil {
+		return ""
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	var content []byte
+	if f, ok := d.files[p]; ok {
+		content = f.content
+	}
+	return d.mime(p, content)
+}
+
+func (d *driverMemory) RealPath(path string) string {
+	p, err := cleanMemoryPath(path)
+	if err != nil {
+		return ""
+	}
+	return p
+}
+
+func (d *driverMemory) RealDirPath(path string) string {
+	p, err := cleanMemoryPath(path)
+	if err != nil || p == "" {
+		return ""
+	}
+	return p + "/"
+}
+
+func (d *driverMemory) IsDir(path string) (bool, error) {
+	p, err := cleanMemoryPath(path)
+	if err != nil {
+		return false, err
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if _, ok := d.files[p]; ok {
+		return false, nil
+	}
+	if d.dirExists(p) {
+		return true, nil
+	}
+	return false, memoryNotExist("stat", path)
+}
+
+func (d *driverMemory) IsFile(path string) (bool, error) {
+	p, err := cleanMemoryPath(path)
+	if err != nil {
+		return false, err
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if _, ok := d.files[p]; ok {
+		return true, nil
+	}
+	if d.dirExists(p) {
+		return false, nil
+	}
+	return false, memoryNotExist("stat", path)
+}
+
+func (d *driverMemory) PutStream(path string, r io.Reader, opts PutOptions) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return d.write(path, b, opts.Visibility.or(VisibilityPrivate))
+}
+
+func (d *driverMemory) ReadStream(path string) (io.ReadCloser, error) {
+	b, err := d.Get(path)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (d *driverMemory) Size(path string) (int64, error) {
+	p, err := cleanMemoryPath(path)
+	if err != nil {
+		return 0, err
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	f, ok := d.files[p]
+	if !ok {
+		return 0, memoryNotExist("stat", path)
+	}
+	return int64(len(f.content)), nil
+}
+
+func (d *driverMemory) LastModified(path string) (time.Time, error) {
+	p, err := cleanMemoryPath(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	f, ok := d.files[p]
+	if !ok {
+		return time.Time{}, memoryNotExist("stat", path)
+	}
+	return f.modTime, nil
+}
+
+func (d *driverMemory) Checksum(path string) (string, error) {
+	return checksum(d.ReadStream(path))
+}
+
+func (d *driverMemory) SetVisibility(path string, v Visibility) error {
+	p, err := cleanMemoryPath(path)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	f, ok := d.files[p]
+	if !ok {
+		return memoryNotExist("chmod", path)
+	}
+	f.visibility = v
+	return nil
+}
+
+func (d *driverMemory) GetVisibility(path string) (Visibility, error) {
+	p, err := cleanMemoryPath(path)
+	if err != nil {
+		return "", err
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	f, ok := d.files[p]
+	if !ok {
+		return "", memoryNotExist("stat", path)
+	}
+	return f.visibility, nil
+}
+
+func (d *driverMemory) write(path string, content []byte, v Visibility) error {
+	p, err := cleanMemoryPath(path)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if p == "" || d.dirExists(p) {
+		return &fs.PathError{Op: "open", Path: path, Err: fs.ErrInvalid}
+	}
+	now := time.Now()
+	d.makeDirs(pathPkg.Dir(p), now)
+	d.files[p] = &memoryFile{content: content, modTime: now, visibility: v}
+	return nil
+}
+
+// copy duplicates a file or a directory tree between cleaned paths, from is only
+// used in errors. d.mu must be held
+func (d *driverMemory) copy(src, dst, from string) error {
+	now := time.Now()
+
+	if f, ok := d.files[src]; ok {
+		d.makeDirs(pathPkg.Dir(dst), now)
+		d.files[dst] = &memoryFile{content: bytes.Clone(f.content), modTime: now, visibility: f.visibility}
+		return nil
+	}
+
+	if src == "" || !d.dirExists(src) {
+		return memoryNotExist("copy", from)
+	}
+	if dst == src || strings.HasPrefix(dst, src+"/") {
+		return &fs.PathError{Op: "copy", Path: from, Err: fs.ErrInvalid}
+	}
+
+	d.makeDirs(dst, now)
+	prefix := src + "/"
+	for k, f := range d.files {
+		if strings.HasPrefix(k, prefix) {
+			target := pathPkg.Join(dst, k[len(prefix):])
+			d.makeDirs(pathPkg.Dir(target), now)
+			d.files[target] = &memoryFile{content: bytes.Clone(f.content), modTime: now, visibility: f.visibility}
+		}
+	}
+	for k := range d.dirs {
+		if strings.HasPrefix(k, prefix) {
+			d.makeDirs(pathPkg.Join(dst, k[len(prefix):]), now)
+		}
+	}
+	return nil
+}
+
+// makeDirs registers p and its parents, d.mu must be held
+func (d *driverMemory) makeDirs(p string, t time.Time) {
+	for p != "" && p != "." {
+		if _, ok := d.dirs[p]; !ok {
+			d.dirs[p] = t
+		}
+		p = pathPkg.Dir(p)
+	}
+}
+
+// dirExists reports whether p was created or holds files, d.mu must be held
+func (d *driverMemory) dirExists(p string) bool {
+	if p == "" {
+		return true
+	}
+	if _, ok := d.dirs[p]; ok {
+		return true
+	}
+	prefix := p + "/"
+	for k := range d.files {
+		if strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *driverMemory) mime(p string, content []byte) string {
+	if m := mime.TypeByExtension(filepath.Ext(p)); m != "" || content == nil {
+		return m
+	}
+	return http.DetectContentType(content)
+}
+
+// memoryPreFixer normalises paths the same way the other drivers do, without a root
+var memoryPreFixer = helper.NewPreFixer("", "/")
+
+func cleanMemoryPath(path string) (string, error) {
+	return memoryPreFixer.PrefixPath(path)
+}
+
+func cleanMemoryPaths(from, to string) (string, string, error) {
+	src, err := cleanMemoryPath(from)
+	if err != nil {
+		return "", "", err
+	}
+	dst, err := cleanMemoryPath(to)
+	if err != nil {
+		return "", "", err
+	}
+	return src, dst, nil
+}
+
+func memoryNotExist(op, path string) error {
+	return &fs.PathError{Op: op, Path: path, Err: fs.ErrNotExist}
+}
+
+func NewDriverMemory() DriverContract {
+	return &driverMemory{
+		files: make(map[string]*memoryFile),
+		dirs:  make(map[string]time.Time),
+	}
+}
@@ -0,0 +1,189 @@
+package filesystem
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func setupMemoryDriver() DriverContract {
+	d := NewDriverMemory()
+	_ = d.Put("test.txt", []byte("test file"))
+	_ = d.MakeDir("test", 0755)
+	_ = d.Put("docs/readme.md", []byte("# readme"))
+	return d
+}
+
+func TestMemoryExist(t *testing.T) {
+	d := setupMemoryDriver()
+
+	for path, expect := range map[string]bool{"test.txt": true, "/test.txt": true, "test2.txt": false, "docs": false} {
+		result, _ := d.FileExists(path)
+		if result == expect {
+			t.Logf("FileExists(\"%s\") PASS. Expected %t, got %t\n", path, expect, result)
+		} else {
+			t.Errorf("FileExists(\"%s\") FAILED. Expected %t, got %t\n", path, expect, result)
+		}
+	}
+
+	for path, expect := range map[string]bool{"test": true, "docs": true, "test.txt": false, "missing": false} {
+		result, _ := d.DirExists(path)
+		if result == expect {
+			t.Logf("DirExists(\"%s\") PASS. Expected %t, got %t\n", path, expect, result)
+		} else {
+			t.Errorf("DirExists(\"%s\") FAILED. Expected %t, got %t\n", path, expect, result)
+		}
+	}
+}
+
+func TestMemoryPutGet(t *testing.T) {
+	d := setupMemoryDriver()
+
+	content, err := d.Get("docs/readme.md")
+	if err == nil && string(content) == "# readme" {
+		t.Logf("Get(\"%s\") PASS. Got \"%s\"\n", "docs/readme.md", content)
+	} else {
+		t.Errorf("Get(\"%s\") FAILED. Expected \"# readme\", got \"%s\", %v\n", "docs/readme.md", content, err)
+	}
+
+	_, err = d.Get("missing.txt")
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Logf("Get(\"%s\") PASS. Expected fs.ErrNotExist, got \"%s\"\n", "missing.txt", err)
+	} else {
+		t.Errorf("Get(\"%s\") FAILED. Expected fs.ErrNotExist, got %v\n", "missing.txt", err)
+	}
+
+	if err = d.Put("test", []byte("x")); err != nil {
+		t.Logf("Put(\"%s\") PASS. Expected error writing over a directory, got \"%s\"\n", "test", err)
+	} else {
+		t.Errorf("Put(\"%s\") FAILED. Expected error writing over a directory, got nil\n", "test")
+	}
+
+	if err = d.PutStream("stream/a.bin", strings.NewReader("streamed"), PutOptions{}); err != nil {
+		t.Fatalf("PutStream() FAILED. Unexpected error \"%s\"", err)
+	}
+	r, _ := d.ReadStream("stream/a.bin")
+	b, _ := io.ReadAll(r)
+	size, _ := d.Size("stream/a.bin")
+	if string(b) == "streamed" && size == int64(len("streamed")) {
+		t.Logf("ReadStream(\"%s\") PASS. Got \"%s\"\n", "stream/a.bin", b)
+	} else {
+		t.Errorf("ReadStream(\"%s\") FAILED. Expected \"streamed\", got \"%s\" (size %d)\n", "stream/a.bin", b, size)
+	}
+}
+
+func TestMemoryListContents(t *testing.T) {
+	d := setupMemoryDriver()
+
+	files, dirs, err := d.ListContents("")
+	if err == nil && len(files) == 1 && len(dirs) == 2 && dirs[0].Name == "docs" && dirs[1].Name == "test" {
+		t.Logf("ListContents(\"\") PASS. Got %d files, %d directories\n", len(files), len(dirs))
+	} else {
+		t.Errorf("ListContents(\"\") FAILED. Expected 1 file and 2 directories, got %d, %d, %v\n", len(files), len(dirs), err)
+	}
+
+	files, _, _ = d.ListContents("docs")
+	if len(files) == 1 && files[0].Path == "docs/readme.md" && *files[0].Mime != "" {
+		t.Logf("ListContents(\"docs\") PASS. Got \"%s\" (%s)\n", files[0].Path, *files[0].Mime)
+	} else {
+		t.Errorf("ListContents(\"docs\") FAILED. Expected docs/readme.md, got %+v\n", files)
+	}
+
+	if _, _, err = d.ListContents("missing"); err != nil {
+		t.Logf("ListContents(\"missing\") PASS. Expected error, got \"%s\"\n", err)
+	} else {
+		t.Errorf("ListContents(\"missing\") FAILED. Expected error, got nil\n")
+	}
+}
+
+func TestMemoryCopyMoveDelete(t *testing.T) {
+	d := setupMemoryDriver()
+
+	if err := d.Copy("docs", "backup/docs"); err != nil {
+		t.Fatalf("Copy() FAILED. Unexpected error \"%s\"", err)
+	}
+	if ok, _ := d.FileExists("backup/docs/readme.md"); ok {
+		t.Logf("Copy(\"docs\", \"backup/docs\") PASS. Directory tree copied\n")
+	} else {
+		t.Errorf("Copy(\"docs\", \"backup/docs\") FAILED. Expected backup/docs/readme.md to exist\n")
+	}
+
+	if err := d.Copy("docs", "docs/nested"); err != nil {
+		t.Logf("Copy(\"docs\", \"docs/nested\") PASS. Expected error, got \"%s\"\n", err)
+	} else {
+		t.Errorf("Copy(\"docs\", \"docs/nested\") FAILED. Expected error copying into itself, got nil\n")
+	}
+
+	if err := d.Move("test.txt", "moved/test.txt"); err != nil {
+		t.Fatalf("Move() FAILED. Unexpected error \"%s\"", err)
+	}
+	oldExists, _ := d.FileExists("test.txt")
+	newExists, _ := d.FileExists("moved/test.txt")
+	if !oldExists && newExists {
+		t.Logf("Move(\"test.txt\", \"moved/test.txt\") PASS\n")
+	} else {
+		t.Errorf("Move(\"test.txt\", \"moved/test.txt\") FAILED. Expected only the new path, got old %t new %t\n", oldExists, newExists)
+	}
+
+	_ = d.Delete("backup")
+	if ok, _ := d.DirExists("backup/docs"); !ok {
+		t.Logf("Delete(\"backup\") PASS. Directory tree removed\n")
+	} else {
+		t.Errorf("Delete(\"backup\") FAILED. Expected backup/docs to be removed\n")
+	}
+}
+
+func TestMemoryMime(t *testing.T) {
+	d := setupMemoryDriver()
+	_ = d.Put("noext", []byte("<html><body></body></html>"))
+
+	for path, expect := range map[string]string{"test.txt": "text/plain; charset=utf-8", "noext": "text/html; charset=utf-8"} {
+		actual := d.Mime(path)
+		if actual == expect {
+			t.Logf("Mime(\"%s\") PASS. Expected \"%s\", got \"%s\"\n", path, expect, actual)
+		} else {
+			t.Errorf("Mime(\"%s\") FAILED. Expected \"%s\", got \"%s\"\n", path, expect, actual)
+		}
+	}
+}
+
+func TestMemoryVisibility(t *testing.T) {
+	d := setupMemoryDriver()
+
+	_ = d.PutStream("public.txt", strings.NewReader("x"), PutOptions{Visibility: VisibilityPublic})
+	_ = d.Copy("public.txt", "copy.txt")
+
+	for path, expect := range map[string]Visibility{"test.txt": VisibilityPrivate, "public.txt": VisibilityPublic, "copy.txt": VisibilityPublic} {
+		v, _ := d.GetVisibility(path)
+		if v == expect {
+			t.Logf("GetVisibility(\"%s\") PASS. Expected \"%s\", got \"%s\"\n", path, expect, v)
+		} else {
+			t.Errorf("GetVisibility(\"%s\") FAILED. Expected \"%s\", got \"%s\"\n", path, expect, v)
+		}
+	}
+
+	if err := d.SetVisibility("missing.txt", VisibilityPublic); errors.Is(err, fs.ErrNotExist) {
+		t.Logf("SetVisibility(\"missing.txt\") PASS. Expected fs.ErrNotExist, got \"%s\"\n", err)
+	} else {
+		t.Errorf("SetVisibility(\"missing.txt\") FAILED. Expected fs.ErrNotExist, got %v\n", err)
+	}
+}
+
+func TestMemoryPathTraversal(t *testing.T) {
+	d := setupMemoryDriver()
+
+	path := "../test.txt"
+	if _, err := d.Get(path); errors.Is(err, ErrPathOutsideRoot) {
+		t.Logf("Get(\"%s\") PASS. Expected ErrPathOutsideRoot, got \"%s\"\n", path, err)
+	} else {
+		t.Errorf("Get(\"%s\") FAILED. Expected ErrPathOutsideRoot, got %v\n", path, err)
+	}
+
+	if err := d.Copy("test.txt", path); errors.Is(err, ErrPathOutsideRoot) {
+		t.Logf("Copy(\"test.txt\", \"%s\") PASS. Expected ErrPathOutsideRoot, got \"%s\"\n", path, err)
+	} else {
+		t.Errorf("Copy(\"test.txt\", \"%s\") FAILED. Expected ErrPathOutsideRoot, got %v\n", path, err)
+	}
+}
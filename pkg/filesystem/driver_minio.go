@@ -2,45 +2,112 @@ package filesystem
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
-	"github.com/h2non/filetype"
+	"github.com/kurneo/go-template/pkg/filesystem/helper"
+	"github.com/kurneo/go-template/pkg/log"
 	"github.com/minio/minio-go/v6"
+	"io"
+	"mime"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
-type MinioDriver struct {
-	client *minio.Client
-	bucket string
+const minioVisibilityHeader = "X-Amz-Meta-Visibility"
+
+type MinioConfig struct {
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+	Region    string
+	Bucket    string
+	Prefix    string
+	Url       string
+	// PartSize of multipart uploads in bytes, zero lets the client decide
+	PartSize uint64
+	// Visibility of written objects when not given, defaults to private
+	Visibility Visibility
+}
+
+type driverMinio struct {
+	client   *minio.Client
+	l        log.Contract
+	bucket   string
+	partSize uint64
+	pf       helper.PathPreFixer
+	v        Visibility
+	// backend identifies the endpoint and account, buckets of one backend copy server side
+	backend string
 }
 
-func (d MinioDriver) Exists(path string) bool {
-	_, err := d.client.StatObject(d.bucket, path, minio.StatObjectOptions{})
+func (d driverMinio) FileExists(path string) (bool, error) {
+	key, err := d.pf.PrefixPath(path)
 	if err != nil {
-		return false
+		return false, err
 	}
-	return true
+	_, err = d.client.StatObject(d.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		if isMinioNotFound(err) {
+			return false, nil
+		}
+		d.l.Error(err)
+		return false, err
+	}
+	return true, nil
 }
 
-func (d MinioDriver) NotExists(path string) bool {
-	return !d.Exists(path)
+func (d driverMinio) DirExists(path string) (bool, error) {
+	prefix, err := d.pf.PrefixDirectoryPath(path)
+	if err != nil {
+		return false, err
+	}
+
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+
+	// directories without a marker object exist as long as they contain objects
+	for object := range d.client.ListObjectsV2(d.bucket, prefix, false, doneCh) {
+		if object.Err != nil {
+			d.l.Error(object.Err)
+			return false, object.Err
+		}
+		return true, nil
+	}
+	return false, nil
 }
 
-func (d MinioDriver) Put(path, content string) error {
-	reader := bytes.NewReader([]byte(content))
-	ext := filepath.Ext(path)
-	mime := filetype.GetType(ext).MIME.Type
-	_, err := d.client.PutObject(d.bucket, path, reader, reader.Size(), minio.PutObjectOptions{ContentType: mime})
+func (d driverMinio) Put(path string, content []byte) error {
+	key, err := d.pf.PrefixPath(path)
+	if err != nil {
+		return err
+	}
+	reader := bytes.NewReader(content)
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		contentType = http.DetectContentType(content)
+	}
+	_, err = d.client.PutObject(d.bucket, key, reader, reader.Size(), minio.PutObjectOptions{
+		ContentType:  contentType,
+		UserMetadata: minioVisibilityMeta(d.v),
+	})
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (d MinioDriver) Get(path string) (string, error) {
-	reader, err := d.client.GetObject(d.bucket, path, minio.GetObjectOptions{})
+func (d driverMinio) Get(path string) ([]byte, error) {
+	key, err := d.pf.PrefixPath(path)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	reader, err := d.client.GetObject(d.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
 	}
 
 	defer func() {
@@ -52,44 +119,398 @@ func (d MinioDriver) Get(path string) (string, error) {
 
 	buf := new(bytes.Buffer)
 	if _, err = buf.ReadFrom(reader); err != nil {
-		return "", err
+		return nil, err
 	}
-	return buf.String(), nil
+	return buf.Bytes(), nil
 }
 
-func (d MinioDriver) MakeDir(path string, perm os.FileMode) error {
+func (d driverMinio) MakeDir(path string, perm os.FileMode) error {
+	key, err := d.pf.PrefixDirectoryPath(path)
+	if err != nil {
+		return err
+	}
 	reader := bytes.NewReader(make([]byte, 0))
-	_, err := d.client.PutObject(d.bucket, path+"/", reader, reader.Size(), minio.PutObjectOptions{})
+	_, err = d.client.PutObject(d.bucket, key, reader, reader.Size(), minio.PutObjectOptions{
+		ContentType:  "application/x-directory; charset=UTF-8",
+		UserMetadata: minioVisibilityMeta(d.v),
+	})
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func NewMinioDriver() (*MinioDriver, error) {
-	endpoint := "minio:9000"
-	accessKeyID := "MqLknlISEZ1wPOp6Ra1T"
-	secretAccessKey := "m3HHJSLgmRkbv186exPqQC4mHu5dUWnmgPIS23zc"
-	useSSL := false
+// Delete removes the object at path and, like the local driver, everything below it
+func (d driverMinio) Delete(path string) error {
+	key, err := d.pf.PrefixPath(path)
+	if err != nil {
+		return err
+	}
+	prefix, err := d.pf.PrefixDirectoryPath(path)
+	if err != nil {
+		return err
+	}
+
+	if err = d.client.RemoveObject(d.bucket, key); err != nil && !isMinioNotFound(err) {
+		return err
+	}
+
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+
+	objectsCh := make(chan string)
+	var listErr error
+	go func() {
+		defer close(objectsCh)
+		for object := range d.client.ListObjectsV2(d.bucket, prefix, true, doneCh) {
+			if object.Err != nil {
+				listErr = object.Err
+				return
+			}
+			objectsCh <- object.Key
+		}
+	}()
+
+	// drain every result so the listing goroutine is never left blocked
+	var removeErr error
+	for e := range d.client.RemoveObjects(d.bucket, objectsCh) {
+		if e.Err != nil && removeErr == nil {
+			removeErr = e.Err
+		}
+	}
+	if removeErr != nil {
+		return removeErr
+	}
+	return listErr
+}
+
+func (d driverMinio) Rename(from, to string) error {
+	return d.Move(from, to)
+}
+
+// ListContents lists the direct children of path, the client follows continuation tokens
+func (d driverMinio) ListContents(path string) ([]File, []Directory, error) {
+	fPath, err := d.pf.PrefixDirectoryPath(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	doneCh := make(chan struct{})
+	defer close(doneCh)
 
-	// Initialize minio client object.
-	minioClient, err := minio.New(endpoint, accessKeyID, secretAccessKey, useSSL)
+	files := make([]File, 0)
+	directories := make([]Directory, 0)
 
+	for object := range d.client.ListObjectsV2(d.bucket, fPath, false, doneCh) {
+		if object.Err != nil {
+			return nil, nil, object.Err
+		}
+		switch true {
+		case object.Key == fPath:
+			break
+		case strings.HasSuffix(object.Key, "/"):
+			var modTime = object.LastModified
+			directory := Directory{
+				Path: d.pf.StripPrefix(d.pf.StripTrailingSeparator(object.Key)),
+				Name: filepath.Base(object.Key),
+			}
+			if !modTime.IsZero() {
+				directory.ModTime = &modTime
+			}
+			directories = append(directories, directory)
+		default:
+			files = append(files, d.objectFile(object))
+		}
+	}
+
+	return files, directories, nil
+}
+
+// ListContentsRecursive lists every key below path, the client follows continuation
+// tokens, directories without a marker object are derived from the keys
+func (d driverMinio) ListContentsRecursive(path string) ([]File, []Directory, error) {
+	fPath, err := d.pf.PrefixDirectoryPath(path)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	doneCh := make(chan struct{})
 	defer close(doneCh)
-	for object := range minioClient.ListObjects("template", "ahhi/", true, doneCh) {
+
+	files := make([]File, 0)
+	directories := newKeyDirectories(d.pf, fPath)
+
+	for object := range d.client.ListObjectsV2(d.bucket, fPath, true, doneCh) {
 		if object.Err != nil {
-			fmt.Println(object.Err)
-			return nil, nil
+			return nil, nil, object.Err
+		}
+		if object.Key == fPath {
+			continue
+		}
+		modTime := object.LastModified
+		directories.addKey(object.Key, &modTime)
+		if !strings.HasSuffix(object.Key, "/") {
+			files = append(files, d.objectFile(object))
+		}
+	}
+
+	return files, directories.list, nil
+}
+
+func (d driverMinio) Walk(path string, fn WalkFunc) error {
+	return walkLevels(d, path, fn)
+}
+
+func (d driverMinio) Move(from, to string) error {
+	err := d.Copy(from, to)
+
+	if err != nil {
+		return err
+	}
+
+	return d.Delete(from)
+}
+
+func (d driverMinio) Copy(from, to string) error {
+	src, err := d.pf.PrefixPath(from)
+	if err != nil {
+		return err
+	}
+	key, err := d.pf.PrefixPath(to)
+	if err != nil {
+		return err
+	}
+	dst, err := minio.NewDestinationInfo(d.bucket, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	return d.client.CopyObject(dst, minio.NewSourceInfo(d.bucket, src, nil))
+}
+
+func (d driverMinio) Mime(path string) string {
+	info, err := d.stat(path)
+	if err != nil || info.ContentType == "" {
+		return mime.TypeByExtension(filepath.Ext(path))
+	}
+	return info.ContentType
+}
+
+// RealPath returns the object key, or an empty string when path leaves the root
+func (d driverMinio) RealPath(path string) string {
+	key, err := d.pf.PrefixPath(path)
+	if err != nil {
+		return ""
+	}
+	return key
+}
+
+func (d driverMinio) RealDirPath(path string) string {
+	prefix, err := d.pf.PrefixDirectoryPath(path)
+	if err != nil {
+		return ""
+	}
+	return prefix
+}
+
+func (d driverMinio) IsDir(path string) (bool, error) {
+	return d.DirExists(path)
+}
+
+func (d driverMinio) IsFile(path string) (bool, error) {
+	_, err := d.stat(path)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// PutStream uploads r, the client switches to multipart uploads for large or unsized streams
+func (d driverMinio) PutStream(path string, r io.Reader, opts PutOptions) error {
+	key, err := d.pf.PrefixPath(path)
+	if err != nil {
+		return err
+	}
+	contentType, r := detectContentType(path, r, opts)
+	size := opts.Size
+	if size <= 0 {
+		size = -1
+	}
+	_, err = d.client.PutObject(d.bucket, key, r, size, minio.PutObjectOptions{
+		ContentType:  contentType,
+		PartSize:     d.partSize,
+		UserMetadata: minioVisibilityMeta(opts.Visibility.or(d.v)),
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d driverMinio) ReadStream(path string) (io.ReadCloser, error) {
+	key, err := d.pf.PrefixPath(path)
+	if err != nil {
+		return nil, err
+	}
+	object, err := d.client.GetObject(d.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	// GetObject is lazy, stat surfaces missing objects before the first read
+	if _, err = object.Stat(); err != nil {
+		_ = object.Close()
+		return nil, err
+	}
+	return object, nil
+}
+
+func (d driverMinio) Size(path string) (int64, error) {
+	info, err := d.stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size, nil
+}
+
+func (d driverMinio) LastModified(path string) (time.Time, error) {
+	info, err := d.stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.LastModified, nil
+}
+
+func (d driverMinio) Checksum(path string) (string, error) {
+	return checksum(d.ReadStream(path))
+}
+
+// SetVisibility rewrites the object metadata in place. MinIO itself has no object ACLs,
+// public access must be granted with a bucket policy, the ACL header is honoured by
+// S3 compatible services that support it
+func (d driverMinio) SetVisibility(path string, v Visibility) error {
+	key, err := d.pf.PrefixPath(path)
+	if err != nil {
+		return err
+	}
+	info, err := d.client.StatObject(d.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return err
+	}
+
+	meta := minioVisibilityMeta(v)
+	for k, values := range info.Metadata {
+		if strings.HasPrefix(strings.ToLower(k), "x-amz-meta-") && !strings.EqualFold(k, minioVisibilityHeader) && len(values) > 0 {
+			meta[k] = values[0]
 		}
-		fmt.Println(object.Key)
+	}
+	if info.ContentType != "" {
+		meta["Content-Type"] = info.ContentType
+	}
+
+	dst, err := minio.NewDestinationInfo(d.bucket, key, nil, meta)
+	if err != nil {
+		return err
+	}
+	return d.client.CopyObject(dst, minio.NewSourceInfo(d.bucket, key, nil))
+}
+
+func (d driverMinio) GetVisibility(path string) (Visibility, error) {
+	info, err := d.stat(path)
+	if err != nil {
+		return "", err
+	}
+	return ParseVisibility(info.Metadata.Get(minioVisibilityHeader), d.v)
+}
+
+// TemporaryUrl returns a presigned GET url, minio accepts expiries between 1 second and 7 days
+func (d driverMinio) TemporaryUrl(path string, expiry time.Duration) (string, error) {
+	key, err := d.pf.PrefixPath(path)
+	if err != nil {
+		return "", err
+	}
+	u, err := d.client.PresignedGetObject(d.bucket, key, expiry, nil)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// copyFrom copies server side when src is a bucket of the same backend, the object
+// metadata, visibility included, is copied along
+func (d driverMinio) copyFrom(src DriverContract, from, to string, v Visibility) (bool, error) {
+	other, ok := src.(*driverMinio)
+	if !ok || other.backend != d.backend {
+		return false, nil
+	}
+
+	srcKey, err := other.pf.PrefixPath(from)
+	if err != nil {
+		return true, err
+	}
+	dstKey, err := d.pf.PrefixPath(to)
+	if err != nil {
+		return true, err
+	}
+
+	dst, err := minio.NewDestinationInfo(d.bucket, dstKey, nil, nil)
+	if err != nil {
+		return true, err
+	}
+	return true, d.client.CopyObject(dst, minio.NewSourceInfo(other.bucket, srcKey, nil))
+}
+
+func (d driverMinio) objectFile(object minio.ObjectInfo) File {
+	modTime := object.LastModified
+	size := object.Size
+	e := filepath.Ext(object.Key)
+	m := object.ContentType
+	if m == "" {
+		m = mime.TypeByExtension(e)
+	}
+	return File{
+		Path:      d.pf.StripPrefix(object.Key),
+		Name:      filepath.Base(object.Key),
+		ModTime:   &modTime,
+		Size:      &size,
+		Mime:      &m,
+		Extension: &e,
+	}
+}
+
+func (d driverMinio) stat(path string) (minio.ObjectInfo, error) {
+	key, err := d.pf.PrefixPath(path)
+	if err != nil {
+		return minio.ObjectInfo{}, err
+	}
+	return d.client.StatObject(d.bucket, key, minio.StatObjectOptions{})
+}
+
+func minioVisibilityMeta(v Visibility) map[string]string {
+	return map[string]string{
+		"x-amz-acl":  v.acl(),
+		"visibility": string(v),
+	}
+}
+
+func isMinioNotFound(err error) bool {
+	code := minio.ToErrorResponse(err).Code
+	return code == "NoSuchKey" || code == "NotFound"
+}
+
+func NewDriverMinio(c MinioConfig, l log.Contract) (DriverContract, error) {
+	if c.Endpoint == "" || c.Bucket == "" {
+		return nil, errors.New("minio endpoint or bucket is not configured")
+	}
+
+	client, err := minio.NewWithRegion(c.Endpoint, c.AccessKey, c.SecretKey, c.UseSSL, c.Region)
+	if err != nil {
+		return nil, err
 	}
 
-	return &MinioDriver{
-		client: minioClient,
-		bucket: "template",
+	return &driverMinio{
+		client:   client,
+		l:        l,
+		bucket:   c.Bucket,
+		partSize: c.PartSize,
+		pf:       helper.NewPreFixer(c.Prefix, "/"),
+		v:        c.Visibility.or(VisibilityPrivate),
+		backend:  strings.Join([]string{c.Endpoint, c.AccessKey}, "|"),
 	}, nil
 }
@@ -2,35 +2,71 @@ package filesystem
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/kurneo/go-template/pkg/filesystem/helper"
 	"github.com/kurneo/go-template/pkg/log"
+	"io"
+	"mime"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
-type S3Driver struct {
+const s3AllUsersUri = "http://acs.amazonaws.com/groups/global/AllUsers"
+
+type S3Config struct {
+	Region string
+	Bucket string
+	Key    string
+	Secret string
+	// Endpoint overrides the AWS endpoint for S3 compatible services
+	Endpoint     string
+	UsePathStyle bool
+	Prefix       string
+	Url          string
+	// PartSize of multipart uploads in bytes, defaults to the sdk minimum (5 MB)
+	PartSize int64
+	// UploadConcurrency is the number of parts uploaded in parallel
+	UploadConcurrency int
+	// Visibility of written objects when not given, defaults to private
+	Visibility Visibility
+}
+
+type driverS3 struct {
 	s  *s3.S3
+	u  *s3manager.Uploader
 	l  log.Contract
 	b  string
 	r  string
 	p  helper.S3PathHelper
 	pf helper.PathPreFixer
+	v  Visibility
+	// backend identifies the endpoint and account, buckets of one backend copy server side
+	backend string
 }
 
-func (s S3Driver) FileExists(path string) (bool, error) {
-	_, err := s.s.HeadObject(&s3.HeadObjectInput{
+func (s driverS3) FileExists(path string) (bool, error) {
+	key, err := s.pf.PrefixPath(path)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = s.s.HeadObject(&s3.HeadObjectInput{
 		Bucket: aws.String(s.b),
-		Key:    aws.String(s.pf.PrefixPath(path)),
+		Key:    aws.String(key),
 	})
 
 	if err != nil {
-		if strings.HasPrefix(err.Error(), "NotFound") {
+		if isS3NotFound(err) {
 			return false, nil
 		}
 		s.l.Error(err)
@@ -40,28 +76,50 @@ func (s S3Driver) FileExists(path string) (bool, error) {
 	return true, nil
 }
 
-func (s S3Driver) DirExists(path string) (bool, error) {
-	_, err := s.s.HeadObject(&s3.HeadObjectInput{
+func (s driverS3) DirExists(path string) (bool, error) {
+	prefix, err := s.pf.PrefixDirectoryPath(path)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = s.s.HeadObject(&s3.HeadObjectInput{
 		Bucket: aws.String(s.b),
-		Key:    aws.String(s.pf.PrefixDirectoryPath(path)),
+		Key:    aws.String(prefix),
 	})
 
+	if err == nil {
+		return true, nil
+	}
+
+	if !isS3NotFound(err) {
+		s.l.Error(err)
+		return false, err
+	}
+
+	// directories without a marker object exist as long as they contain objects
+	resp, err := s.s.ListObjectsV2(&s3.ListObjectsV2Input{
+		Bucket:  aws.String(s.b),
+		Prefix:  aws.String(prefix),
+		MaxKeys: aws.Int64(1),
+	})
 	if err != nil {
-		if strings.HasPrefix(err.Error(), "NotFound") {
-			return false, nil
-		}
 		s.l.Error(err)
 		return false, err
 	}
 
-	return true, nil
+	return len(resp.Contents) > 0, nil
 }
 
-func (s S3Driver) Put(path string, content []byte) error {
-	_, err := s.s.PutObject(&s3.PutObjectInput{
+func (s driverS3) Put(path string, content []byte) error {
+	key, err := s.pf.PrefixPath(path)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.s.PutObject(&s3.PutObjectInput{
 		Bucket:        aws.String(s.b),
-		ACL:           aws.String("public-read"),
-		Key:           aws.String(s.pf.PrefixPath(path)),
+		ACL:           aws.String(s.v.acl()),
+		Key:           aws.String(key),
 		Body:          bytes.NewReader(content),
 		ContentType:   aws.String(http.DetectContentType(content)),
 		ContentLength: aws.Int64(int64(len(content))),
@@ -73,32 +131,46 @@ func (s S3Driver) Put(path string, content []byte) error {
 	return nil
 }
 
-func (s S3Driver) Get(path string) (string, error) {
+func (s driverS3) Get(path string) ([]byte, error) {
+	key, err := s.pf.PrefixPath(path)
+	if err != nil {
+		return nil, err
+	}
+
 	r, err := s.s.GetObject(&s3.GetObjectInput{
 		Bucket: aws.String(s.b),
-		Key:    aws.String(s.pf.PrefixPath(path)),
+		Key:    aws.String(key),
 	})
 
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			fmt.Println(err)
+		}
+	}()
+
 	buf := new(bytes.Buffer)
 	if _, err = buf.ReadFrom(r.Body); err != nil {
-		return "", err
+		return nil, err
 	}
-	return buf.String(), nil
+	return buf.Bytes(), nil
 }
 
-func (s S3Driver) MakeDir(path string, perm os.FileMode) error {
-	acl := "private"
-	if perm == 777 {
-		acl = "public-read"
+// MakeDir creates a directory marker object, perm has no meaning on S3 so the marker
+// gets the default visibility of the disk
+func (s driverS3) MakeDir(path string, perm os.FileMode) error {
+	key, err := s.pf.PrefixDirectoryPath(path)
+	if err != nil {
+		return err
 	}
-	_, err := s.s.PutObject(&s3.PutObjectInput{
+
+	_, err = s.s.PutObject(&s3.PutObjectInput{
 		Bucket:      aws.String(s.b),
-		Key:         aws.String(s.pf.PrefixDirectoryPath(path)),
-		ACL:         aws.String(acl),
+		Key:         aws.String(key),
+		ACL:         aws.String(s.v.acl()),
 		ContentType: aws.String("application/x-directory; charset=UTF-8"),
 	})
 	if err != nil {
@@ -107,74 +179,145 @@ func (s S3Driver) MakeDir(path string, perm os.FileMode) error {
 	return nil
 }
 
-func (s S3Driver) Delete(path string) error {
-	_, err := s.s.DeleteObject(&s3.DeleteObjectInput{
+// Delete removes the object at path and, like the local driver, everything below it
+func (s driverS3) Delete(path string) error {
+	key, err := s.pf.PrefixPath(path)
+	if err != nil {
+		return err
+	}
+	prefix, err := s.pf.PrefixDirectoryPath(path)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.s.DeleteObject(&s3.DeleteObjectInput{
 		Bucket: aws.String(s.b),
-		Key:    aws.String(s.pf.PrefixPath(path)),
+		Key:    aws.String(key),
 	})
 
 	if err != nil {
 		return err
 	}
-	return nil
+
+	var objects []*s3.ObjectIdentifier
+	var deleteErr error
+	err = s.s.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(s.b),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		objects = objects[:0]
+		for _, v := range page.Contents {
+			objects = append(objects, &s3.ObjectIdentifier{Key: v.Key})
+		}
+		if len(objects) == 0 {
+			return true
+		}
+		_, deleteErr = s.s.DeleteObjects(&s3.DeleteObjectsInput{
+			Bucket: aws.String(s.b),
+			Delete: &s3.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		return deleteErr == nil
+	})
+
+	if err != nil {
+		return err
+	}
+	return deleteErr
 }
 
-func (s S3Driver) ListContents(path string) ([]File, []Directory, error) {
-	fPath := s.pf.PrefixDirectoryPath(s.p.GetDirectoryPath(path))
-	resp, err := s.s.ListObjects(&s3.ListObjectsInput{
+// ListContents lists the direct children of path, following continuation tokens so
+// directories with more than 1000 keys are complete
+func (s driverS3) ListContents(path string) ([]File, []Directory, error) {
+	fPath, err := s.pf.PrefixDirectoryPath(s.p.GetDirectoryPath(path))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	files := make([]File, 0)
+	directories := make([]Directory, 0)
+
+	err = s.s.ListObjectsV2Pages(&s3.ListObjectsV2Input{
 		Bucket:    aws.String(s.b),
 		Prefix:    aws.String(fPath),
 		Delimiter: aws.String("/"),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, v := range page.CommonPrefixes {
+			switch true {
+			case *v.Prefix == fPath:
+				break
+			default:
+				directories = append(directories, Directory{
+					Path:    s.pf.StripPrefix(s.pf.StripTrailingSeparator(*v.Prefix)),
+					Name:    filepath.Base(*v.Prefix),
+					ModTime: nil,
+				})
+			}
+		}
+
+		for _, v := range page.Contents {
+			switch true {
+			case *v.Key == fPath:
+				break
+			case strings.HasSuffix(*v.Key, "/"):
+				directories = append(directories, Directory{
+					Path:    s.pf.StripPrefix(s.pf.StripTrailingSeparator(*v.Key)),
+					Name:    filepath.Base(*v.Key),
+					ModTime: v.LastModified,
+				})
+				break
+			default:
+				files = append(files, s.objectFile(v))
+			}
+		}
+		return true
 	})
 
 	if err != nil {
 		return nil, nil, err
 	}
 
-	files := make([]File, 0)
-	directories := make([]Directory, 0)
+	return files, directories, nil
+}
 
-	for _, v := range resp.CommonPrefixes {
-		switch true {
-		case *v.Prefix == fPath:
-			break
-		default:
-			directories = append(directories, Directory{
-				Path:    s.pf.StripPrefix(s.pf.StripTrailingSeparator(*v.Prefix)),
-				Name:    filepath.Base(*v.Prefix),
-				ModTime: nil,
-			})
-		}
+// ListContentsRecursive lists every key below path page by page, directories without a
+// marker object are derived from the keys
+func (s driverS3) ListContentsRecursive(path string) ([]File, []Directory, error) {
+	fPath, err := s.pf.PrefixDirectoryPath(s.p.GetDirectoryPath(path))
+	if err != nil {
+		return nil, nil, err
 	}
 
-	for _, v := range resp.Contents {
-		switch true {
-		case *v.Key == fPath:
-			break
-		case strings.HasSuffix(*v.Key, "/"):
-			directories = append(directories, Directory{
-				Path:    s.pf.StripPrefix(s.pf.StripTrailingSeparator(*v.Key)),
-				Name:    filepath.Base(*v.Key),
-				ModTime: v.LastModified,
-			})
-			break
-		default:
-			e := filepath.Ext(*v.Key)
-			files = append(files, File{
-				Path:      s.pf.StripPrefix(*v.Key),
-				Name:      filepath.Base(*v.Key),
-				ModTime:   v.LastModified,
-				Size:      v.Size,
-				Mime:      nil,
-				Extension: &e,
-			})
+	files := make([]File, 0)
+	directories := newKeyDirectories(s.pf, fPath)
+
+	err = s.s.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(s.b),
+		Prefix: aws.String(fPath),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, v := range page.Contents {
+			if *v.Key == fPath {
+				continue
+			}
+			directories.addKey(*v.Key, v.LastModified)
+			if !strings.HasSuffix(*v.Key, "/") {
+				files = append(files, s.objectFile(v))
+			}
 		}
+		return true
+	})
+
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return files, directories, nil
+	return files, directories.list, nil
+}
+
+func (s driverS3) Walk(path string, fn WalkFunc) error {
+	return walkLevels(s, path, fn)
 }
 
-func (s S3Driver) Move(from, to string) error {
+func (s driverS3) Move(from, to string) error {
 	err := s.Copy(from, to)
 
 	if err != nil {
@@ -190,14 +333,32 @@ func (s S3Driver) Move(from, to string) error {
 	return nil
 }
 
-func (s S3Driver) Copy(from, to string) error {
+// Copy duplicates the object keeping the visibility of the source
+func (s driverS3) Copy(from, to string) error {
+	src, err := s.pf.PrefixPath(from)
+	if err != nil {
+		return err
+	}
+	dst, err := s.pf.PrefixPath(to)
+	if err != nil {
+		return err
+	}
+
+	// buckets with ACLs disabled have no visibility to keep, the copy takes the default one
+	v, err := s.GetVisibility(from)
+	if isS3AclNotSupported(err) {
+		v, err = s.v, nil
+	}
+	if err != nil {
+		return err
+	}
 
-	_, err := s.s.CopyObject(&s3.CopyObjectInput{
+	_, err = s.s.CopyObject(&s3.CopyObjectInput{
 		Bucket:            aws.String(s.b),
-		Key:               aws.String(s.pf.PrefixPath(to)),
-		ACL:               aws.String("public-read"),
+		Key:               aws.String(dst),
+		ACL:               aws.String(v.acl()),
 		MetadataDirective: aws.String("COPY"),
-		CopySource:        aws.String(fmt.Sprintf("%s/%s", s.b, s.pf.PrefixPath(from))),
+		CopySource:        aws.String(fmt.Sprintf("%s/%s", s.b, src)),
 	})
 
 	if err != nil {
@@ -207,98 +368,273 @@ func (s S3Driver) Copy(from, to string) error {
 	return nil
 }
 
-func (s S3Driver) Rename(from, to string) error {
+func (s driverS3) Rename(from, to string) error {
 	return s.Move(from, to)
 }
 
-func (s S3Driver) Mime(path string) (string, string, error) {
-	resp, err := s.s.HeadObject(&s3.HeadObjectInput{
-		Bucket: aws.String(s.b),
-		Key:    aws.String(s.pf.PrefixPath(path)),
-	})
+func (s driverS3) Mime(path string) string {
+	resp, err := s.head(path)
+
+	if err != nil || resp.ContentType == nil {
+		return mime.TypeByExtension(filepath.Ext(path))
+	}
 
+	return *resp.ContentType
+}
+
+// RealPath returns the object key, or an empty string when path leaves the root
+func (s driverS3) RealPath(path string) string {
+	key, err := s.pf.PrefixPath(path)
 	if err != nil {
-		return "", "", err
+		return ""
 	}
+	return key
+}
 
-	return *resp.ContentType, filepath.Ext(path), nil
+func (s driverS3) RealDirPath(path string) string {
+	prefix, err := s.pf.PrefixDirectoryPath(path)
+	if err != nil {
+		return ""
+	}
+	return prefix
 }
 
-func (s S3Driver) ReadFile(path string) ([]byte, error) {
-	r, err := s.s.GetObject(&s3.GetObjectInput{
-		Bucket: aws.String(s.b),
-		Key:    aws.String(s.pf.PrefixPath(path)),
+func (s driverS3) IsDir(path string) (bool, error) {
+	return s.DirExists(path)
+}
+
+func (s driverS3) IsFile(path string) (bool, error) {
+	resp, err := s.head(path)
+
+	if err != nil {
+		return false, err
+	}
+
+	if resp.ContentType == nil {
+		return true, nil
+	}
+
+	return strings.Split(*resp.ContentType, ";")[0] != "application/x-directory", nil
+}
+
+// PutStream uploads r with a multipart upload so large files are never fully buffered
+func (s driverS3) PutStream(path string, r io.Reader, opts PutOptions) error {
+	key, err := s.pf.PrefixPath(path)
+	if err != nil {
+		return err
+	}
+
+	contentType, r := detectContentType(path, r, opts)
+	_, err = s.u.Upload(&s3manager.UploadInput{
+		Bucket:      aws.String(s.b),
+		ACL:         aws.String(opts.Visibility.or(s.v).acl()),
+		Key:         aws.String(key),
+		Body:        r,
+		ContentType: aws.String(contentType),
 	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
 
+func (s driverS3) ReadStream(path string) (io.ReadCloser, error) {
+	key, err := s.pf.PrefixPath(path)
 	if err != nil {
 		return nil, err
 	}
 
-	buf := new(bytes.Buffer)
-	if _, err = buf.ReadFrom(r.Body); err != nil {
+	r, err := s.s.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.b),
+		Key:    aws.String(key),
+	})
+	if err != nil {
 		return nil, err
 	}
-	return buf.Bytes(), nil
+	return r.Body, nil
+}
+
+func (s driverS3) Size(path string) (int64, error) {
+	resp, err := s.head(path)
+	if err != nil {
+		return 0, err
+	}
+	return aws.Int64Value(resp.ContentLength), nil
 }
 
-func (s S3Driver) Path(path string) string {
-	return s.pf.PrefixPath(path)
+func (s driverS3) LastModified(path string) (time.Time, error) {
+	resp, err := s.head(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return aws.TimeValue(resp.LastModified), nil
 }
 
-func (s S3Driver) DirPath(path string) string {
-	return s.pf.PrefixDirectoryPath(path)
+func (s driverS3) Checksum(path string) (string, error) {
+	return checksum(s.ReadStream(path))
 }
 
-func (s S3Driver) IsDir(path string) (bool, error) {
-	resp, err := s.s.HeadObject(&s3.HeadObjectInput{
+func (s driverS3) SetVisibility(path string, v Visibility) error {
+	key, err := s.pf.PrefixPath(path)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.s.PutObjectAcl(&s3.PutObjectAclInput{
 		Bucket: aws.String(s.b),
-		Key:    aws.String(s.pf.PrefixPath(path)),
+		Key:    aws.String(key),
+		ACL:    aws.String(v.acl()),
 	})
+	return err
+}
 
+// GetVisibility reports objects readable by the AllUsers group as public
+func (s driverS3) GetVisibility(path string) (Visibility, error) {
+	key, err := s.pf.PrefixPath(path)
 	if err != nil {
-		return false, err
+		return "", err
 	}
 
-	ct := strings.Split(*resp.ContentType, ";")
+	resp, err := s.s.GetObjectAcl(&s3.GetObjectAclInput{
+		Bucket: aws.String(s.b),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, g := range resp.Grants {
+		if g.Grantee == nil || aws.StringValue(g.Grantee.URI) != s3AllUsersUri {
+			continue
+		}
+		if p := aws.StringValue(g.Permission); p == s3.PermissionRead || p == s3.PermissionFullControl {
+			return VisibilityPublic, nil
+		}
+	}
+	return VisibilityPrivate, nil
+}
 
-	if len(ct) == 0 {
-		return false, nil
+// TemporaryUrl returns a presigned GET url
+func (s driverS3) TemporaryUrl(path string, expiry time.Duration) (string, error) {
+	key, err := s.pf.PrefixPath(path)
+	if err != nil {
+		return "", err
 	}
 
-	return ct[0] == "application/x-directory", nil
+	req, _ := s.s.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(s.b),
+		Key:    aws.String(key),
+	})
+	return req.Presign(expiry)
 }
 
-func (s S3Driver) PutObject(path string, f *bytes.Reader) error {
-	_, err := s.s.PutObject(&s3.PutObjectInput{
-		Bucket:             aws.String(s.b),
-		Key:                aws.String(s.pf.PrefixPath(path)),
-		ACL:                aws.String("public-read"),
-		Body:               f,
-		ContentDisposition: aws.String("attachment"),
-		// ContentLength:      aws.Int64(int64(len(buffer))),
-		// ContentType:        aws.String(http.DetectContentType(buffer)),
+// copyFrom copies server side when src is a bucket of the same backend
+func (s driverS3) copyFrom(src DriverContract, from, to string, v Visibility) (bool, error) {
+	other, ok := src.(*driverS3)
+	if !ok || other.backend != s.backend {
+		return false, nil
+	}
+
+	srcKey, err := other.pf.PrefixPath(from)
+	if err != nil {
+		return true, err
+	}
+	dstKey, err := s.pf.PrefixPath(to)
+	if err != nil {
+		return true, err
+	}
+
+	_, err = s.s.CopyObject(&s3.CopyObjectInput{
+		Bucket:            aws.String(s.b),
+		Key:               aws.String(dstKey),
+		ACL:               aws.String(v.or(s.v).acl()),
+		MetadataDirective: aws.String("COPY"),
+		CopySource:        aws.String(fmt.Sprintf("%s/%s", other.b, srcKey)),
 	})
+	return true, err
+}
 
+func (s driverS3) objectFile(v *s3.Object) File {
+	e := filepath.Ext(*v.Key)
+	m := mime.TypeByExtension(e)
+	return File{
+		Path:      s.pf.StripPrefix(*v.Key),
+		Name:      filepath.Base(*v.Key),
+		ModTime:   v.LastModified,
+		Size:      v.Size,
+		Mime:      &m,
+		Extension: &e,
+	}
+}
+
+func (s driverS3) head(path string) (*s3.HeadObjectOutput, error) {
+	key, err := s.pf.PrefixPath(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return s.s.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(s.b),
+		Key:    aws.String(key),
+	})
 }
 
-func NewS3Driver(r, b string, l log.Contract, p helper.S3PathHelper, pf helper.PathPreFixer) *S3Driver {
-	sess := session.Must(session.NewSession(&aws.Config{
-		Region: aws.String(r),
-	}))
+func isS3NotFound(err error) bool {
+	var aErr awserr.Error
+	if errors.As(err, &aErr) {
+		return aErr.Code() == "NotFound" || aErr.Code() == s3.ErrCodeNoSuchKey
+	}
+	return strings.HasPrefix(err.Error(), "NotFound")
+}
 
-	svc := s3.New(sess)
+// isS3AclNotSupported reports whether err comes from a bucket with ACLs disabled (object
+// ownership "bucket owner enforced") or a S3 compatible store without ACLs
+func isS3AclNotSupported(err error) bool {
+	var aErr awserr.Error
+	if errors.As(err, &aErr) {
+		return aErr.Code() == "AccessControlListNotSupported" || aErr.Code() == "NotImplemented"
+	}
+	return false
+}
+
+func NewDriverS3(c S3Config, l log.Contract) (DriverContract, error) {
+	if c.Bucket == "" {
+		return nil, errors.New("s3 bucket is not configured")
+	}
+
+	cfg := &aws.Config{
+		Region:           aws.String(c.Region),
+		S3ForcePathStyle: aws.Bool(c.UsePathStyle),
+	}
+	if c.Endpoint != "" {
+		cfg.Endpoint = aws.String(c.Endpoint)
+	}
+	if c.Key != "" {
+		cfg.Credentials = credentials.NewStaticCredentials(c.Key, c.Secret, "")
+	}
 
-	return &S3Driver{
-		s:  svc,
-		l:  l,
-		b:  b,
-		r:  r,
-		p:  p,
-		pf: pf,
+	sess, err := session.NewSession(cfg)
+	if err != nil {
+		return nil, err
 	}
+
+	svc := s3.New(sess)
+
+	return &driverS3{
+		s: svc,
+		u: s3manager.NewUploaderWithClient(svc, func(u *s3manager.Uploader) {
+			if c.PartSize > 0 {
+				u.PartSize = c.PartSize
+			}
+			if c.UploadConcurrency > 0 {
+				u.Concurrency = c.UploadConcurrency
+			}
+		}),
+		l:       l,
+		b:       c.Bucket,
+		r:       c.Region,
+		p:       helper.NewS3PathHelper(),
+		pf:      helper.NewPreFixer(c.Prefix, "/"),
+		v:       c.Visibility.or(VisibilityPrivate),
+		backend: strings.Join([]string{c.Endpoint, c.Region, c.Key}, "|"),
+	}, nil
 }
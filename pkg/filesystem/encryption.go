@@ -0,0 +1,291 @@
+package filesystem
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	"math"
+	"strings"
+)
+
+var (
+	ErrEncryptionKeyNotFound = errors.New("encryption key is not configured")
+	ErrNotEncrypted          = errors.New("file is not encrypted")
+	ErrDecryptionFailed      = errors.New("file cannot be decrypted, it is corrupted or truncated")
+)
+
+// An encrypted file is a header followed by chunks sealed with AES-GCM:
+//
+//	magic "KENC" | version | chunk size (uint32) | salt (32 bytes) | key id length | key id
+//
+// Every file is sealed with its own key, derived with HKDF-SHA256 from the configured key
+// and the random salt, so nonces never repeat under one key however many files are
+// written. Every chunk holds chunk size bytes of plaintext, the last one possibly less,
+// plus the GCM tag. The nonce of a chunk is the chunk counter and a flag set on the last
+// chunk, so reordered, dropped or truncated chunks fail to open. The header is the
+// additional data of every chunk
+const (
+	encryptionMagic            = "KENC"
+	encryptionVersion          = 1
+	encryptionSaltSize         = 32
+	encryptionHeaderSize       = 10 + encryptionSaltSize
+	encryptionCounterOffset    = 7
+	encryptionNonceSize        = 12
+	encryptionTagSize          = 16
+	defaultEncryptionChunkSize = 64 * 1024
+	maxEncryptionChunkSize     = 16 * 1024 * 1024
+)
+
+// ParseEncryptionKeys parses whitespace separated "id:base64 key" pairs, e.g.
+// "2024:q83v... 2023:Xk2a...", keys are 16, 24 or 32 bytes long
+func ParseEncryptionKeys(s string) (map[string][]byte, error) {
+	keys := make(map[string][]byte)
+	for _, v := range strings.Fields(s) {
+		id, encoded, ok := strings.Cut(v, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("encryption key \"%s\" is invalid, expected id:key", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key \"%s\" is not base64 encoded", id)
+		}
+		keys[id] = key
+	}
+	return keys, nil
+}
+
+type encryptionHeader struct {
+	keyId     string
+	chunkSize int
+	salt      []byte
+	raw       []byte
+}
+
+func newEncryptionHeader(keyId string, chunkSize int) (*encryptionHeader, error) {
+	salt := make([]byte, encryptionSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	raw := make([]byte, 0, encryptionHeaderSize+len(keyId))
+	raw = append(raw, encryptionMagic...)
+	raw = append(raw, encryptionVersion)
+	raw = binary.BigEndian.AppendUint32(raw, uint32(chunkSize))
+	raw = append(raw, salt...)
+	raw = append(raw, byte(len(keyId)))
+	raw = append(raw, keyId...)
+
+	return &encryptionHeader{keyId: keyId, chunkSize: chunkSize, salt: salt, raw: raw}, nil
+}
+
+func readEncryptionHeader(r io.Reader) (*encryptionHeader, error) {
+	fixed := make([]byte, encryptionHeaderSize)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrNotEncrypted
+		}
+		return nil, err
+	}
+	if string(fixed[:4]) != encryptionMagic || fixed[4] != encryptionVersion {
+		return nil, ErrNotEncrypted
+	}
+
+	chunkSize := int(binary.BigEndian.Uint32(fixed[5:]))
+	if chunkSize <= 0 || chunkSize > maxEncryptionChunkSize {
+		return nil, ErrDecryptionFailed
+	}
+	keyId := make([]byte, fixed[encryptionHeaderSize-1])
+	if _, err := io.ReadFull(r, keyId); err != nil {
+		return nil, ErrDecryptionFailed
+	}
+
+	return &encryptionHeader{
+		keyId:     string(keyId),
+		chunkSize: chunkSize,
+		salt:      fixed[9 : 9+encryptionSaltSize],
+		raw:       append(fixed, keyId...),
+	}, nil
+}
+
+// aead returns the cipher of the file, keyed with the file key derived from key
+func (h *encryptionHeader) aead(key []byte) (cipher.AEAD, error) {
+	fileKey := make([]byte, len(key))
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, h.salt, []byte(encryptionMagic+h.keyId)), fileKey); err != nil {
+		return nil, err
+	}
+	return newAEAD(fileKey)
+}
+
+func (h *encryptionHeader) nonce(counter uint32, last bool) []byte {
+	n := make([]byte, encryptionNonceSize)
+	binary.BigEndian.PutUint32(n[encryptionCounterOffset:], counter)
+	if last {
+		n[encryptionNonceSize-1] = 1
+	}
+	return n
+}
+
+// encryptedSize returns the size of size bytes of plaintext once encrypted
+func (h *encryptionHeader) encryptedSize(size int64) int64 {
+	chunks := max((size+int64(h.chunkSize)-1)/int64(h.chunkSize), 1)
+	return int64(len(h.raw)) + size + chunks*encryptionTagSize
+}
+
+// plainSize returns the plaintext size of an encrypted file of size bytes
+func (h *encryptionHeader) plainSize(size int64) (int64, error) {
+	payload := size - int64(len(h.raw))
+	sealed := int64(h.chunkSize + encryptionTagSize)
+	chunks := (payload + sealed - 1) / sealed
+	if chunks < 1 || payload-(chunks-1)*sealed < encryptionTagSize {
+		return 0, ErrDecryptionFailed
+	}
+	return payload - chunks*encryptionTagSize, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptReader reads plaintext from r and returns the header followed by the sealed chunks
+type encryptReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	h       *encryptionHeader
+	plain   []byte
+	sealed  []byte
+	out     []byte
+	counter uint32
+	done    bool
+}
+
+func newEncryptReader(r io.Reader, aead cipher.AEAD, h *encryptionHeader) *encryptReader {
+	return &encryptReader{
+		r:      bufio.NewReader(r),
+		aead:   aead,
+		h:      h,
+		plain:  make([]byte, h.chunkSize),
+		sealed: make([]byte, 0, h.chunkSize+encryptionTagSize),
+		out:    h.raw,
+	}
+}
+
+func (e *encryptReader) Read(p []byte) (int, error) {
+	for len(e.out) == 0 {
+		if e.done {
+			return 0, io.EOF
+		}
+		if err := e.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, e.out)
+	e.out = e.out[n:]
+	return n, nil
+}
+
+func (e *encryptReader) next() error {
+	n, err := io.ReadFull(e.r, e.plain)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return err
+	}
+	// a full chunk is the last one when nothing follows it
+	last := n < len(e.plain)
+	if !last {
+		if _, err = e.r.Peek(1); err != nil {
+			if !errors.Is(err, io.EOF) {
+				return err
+			}
+			last = true
+		}
+	}
+	if !last && e.counter == math.MaxUint32 {
+		return errors.New("file is too large to be encrypted")
+	}
+
+	e.out = e.aead.Seal(e.sealed[:0], e.h.nonce(e.counter, last), e.plain[:n], e.h.raw)
+	e.counter++
+	e.done = last
+	return nil
+}
+
+// decryptReader reads the chunks following the header from r and returns the plaintext,
+// a missing last chunk fails with ErrDecryptionFailed instead of a silent io.EOF
+type decryptReader struct {
+	r       *bufio.Reader
+	c       io.Closer
+	aead    cipher.AEAD
+	h       *encryptionHeader
+	sealed  []byte
+	plain   []byte
+	out     []byte
+	counter uint32
+	done    bool
+}
+
+func newDecryptReader(r *bufio.Reader, c io.Closer, aead cipher.AEAD, h *encryptionHeader) *decryptReader {
+	return &decryptReader{
+		r:      r,
+		c:      c,
+		aead:   aead,
+		h:      h,
+		sealed: make([]byte, h.chunkSize+encryptionTagSize),
+		plain:  make([]byte, 0, h.chunkSize),
+	}
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+func (d *decryptReader) next() error {
+	n, err := io.ReadFull(d.r, d.sealed)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return err
+	}
+	if n < encryptionTagSize {
+		return ErrDecryptionFailed
+	}
+	last := n < len(d.sealed)
+	if !last {
+		if _, err = d.r.Peek(1); err != nil {
+			if !errors.Is(err, io.EOF) {
+				return err
+			}
+			last = true
+		}
+	}
+
+	d.out, err = d.aead.Open(d.plain[:0], d.h.nonce(d.counter, last), d.sealed[:n], d.h.raw)
+	if err != nil {
+		return ErrDecryptionFailed
+	}
+	d.counter++
+	d.done = last
+	return nil
+}
+
+func (d *decryptReader) Close() error {
+	return d.c.Close()
+}
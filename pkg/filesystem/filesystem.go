@@ -1,14 +1,23 @@
 package filesystem
 
 import (
+	"github.com/kurneo/go-template/pkg/filesystem/helper"
+	"io"
 	"os"
 	"time"
 )
 
+// ErrPathOutsideRoot matches the errors of every disk operation given a path that
+// resolves outside the disk root, e.g. "../../etc/passwd"
+var ErrPathOutsideRoot = helper.ErrPathOutsideRoot
+
+type PathTraversalError = helper.PathTraversalError
+
 const (
-	DriverLocal = "local"
-	DriverS3    = "s3"
-	DriverMinio = "minio"
+	DriverLocal  = "local"
+	DriverS3     = "s3"
+	DriverMinio  = "minio"
+	DriverMemory = "memory"
 )
 
 type DriverContract interface {
@@ -20,6 +29,10 @@ type DriverContract interface {
 	Delete(path string) error
 	Rename(from, to string) error
 	ListContents(path string) ([]File, []Directory, error)
+	// ListContentsRecursive lists every file and directory below path
+	ListContentsRecursive(path string) ([]File, []Directory, error)
+	// Walk calls fn for every file and directory below path, see WalkFunc
+	Walk(path string, fn WalkFunc) error
 	Move(from, to string) error
 	Copy(from, to string) error
 	Mime(path string) string
@@ -27,20 +40,58 @@ type DriverContract interface {
 	RealDirPath(path string) string
 	IsDir(path string) (bool, error)
 	IsFile(path string) (bool, error)
+	// PutStream writes r to path without loading it into memory
+	PutStream(path string, r io.Reader, opts PutOptions) error
+	// ReadStream opens path for reading, the caller must close the reader
+	ReadStream(path string) (io.ReadCloser, error)
+	Size(path string) (int64, error)
+	LastModified(path string) (time.Time, error)
+	// Checksum returns the hex encoded SHA-256 of the content at path
+	Checksum(path string) (string, error)
+	// SetVisibility changes who can read path, it maps to ACLs on object stores and file modes on local disks
+	SetVisibility(path string, v Visibility) error
+	GetVisibility(path string) (Visibility, error)
+}
+
+type PutOptions struct {
+	// ContentType is detected from the extension or the first bytes when empty
+	ContentType string
+	// Size of the stream when known, zero or negative otherwise
+	Size int64
+	// Visibility of the written file, the disk default is used when empty
+	Visibility Visibility
 }
 
-type DiskLocalContract DriverContract
+type TemporaryUrlContract interface {
+	// TemporaryUrl returns a url granting read access to path until expiry elapses
+	TemporaryUrl(path string, expiry time.Duration) (string, error)
+}
+
+type DiskLocalContract interface {
+	DriverContract
+	TemporaryUrlContract
+}
 
 type DiskPublicContract interface {
 	DriverContract
+	TemporaryUrlContract
 	Url(path string) string
 }
 
 type DiskS3Contract interface {
 	DriverContract
+	TemporaryUrlContract
 	Url(path string) string
 }
 
+type DiskEncryptedContract interface {
+	DriverContract
+	// KeyId returns the id of the key path is encrypted with
+	KeyId(path string) (string, error)
+	// Reencrypt encrypts path again with the current key, used to retire old keys
+	Reencrypt(path string) error
+}
+
 type File struct {
 	Path      string
 	Name      string
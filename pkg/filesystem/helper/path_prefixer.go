@@ -1,16 +1,62 @@
 package helper
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 )
 
+// ErrPathOutsideRoot is matched by errors.Is for every PathTraversalError
+var ErrPathOutsideRoot = errors.New("path leaves the disk root")
+
+// PathTraversalError is returned for paths that resolve outside the disk root
+type PathTraversalError struct {
+	Path string
+}
+
+func (e *PathTraversalError) Error() string {
+	return fmt.Sprintf("path \"%s\" leaves the disk root", e.Path)
+}
+
+func (e *PathTraversalError) Is(target error) bool {
+	return target == ErrPathOutsideRoot
+}
+
 type PathPreFixer struct {
 	prefix    string
 	separator string
 }
 
-func (p PathPreFixer) PrefixPath(path string) string {
-	return p.prefix + strings.TrimLeft(path, "\\/")
+// Normalize converts path to a clean path relative to the root: both "/" and "\" are
+// accepted as separators, "." and empty segments are dropped and ".." is resolved.
+// Paths resolving above the root return a *PathTraversalError
+func (p PathPreFixer) Normalize(path string) (string, error) {
+	if strings.ContainsRune(path, 0) {
+		return "", &PathTraversalError{Path: path}
+	}
+
+	segments := make([]string, 0)
+	for _, s := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '\\' }) {
+		switch s {
+		case ".":
+		case "..":
+			if len(segments) == 0 {
+				return "", &PathTraversalError{Path: path}
+			}
+			segments = segments[:len(segments)-1]
+		default:
+			segments = append(segments, s)
+		}
+	}
+	return strings.Join(segments, p.separator), nil
+}
+
+func (p PathPreFixer) PrefixPath(path string) (string, error) {
+	normalized, err := p.Normalize(path)
+	if err != nil {
+		return "", err
+	}
+	return p.prefix + normalized, nil
 }
 
 func (p PathPreFixer) StripPrefix(path string) string {
@@ -21,14 +67,17 @@ func (p PathPreFixer) StripDirectoryPrefix(path string) string {
 	return strings.TrimRight(p.StripPrefix(path), "\\/")
 }
 
-func (p PathPreFixer) PrefixDirectoryPath(path string) string {
-	prefixedPath := p.PrefixPath(strings.TrimRight(path, "\\/"))
+func (p PathPreFixer) PrefixDirectoryPath(path string) (string, error) {
+	prefixedPath, err := p.PrefixPath(path)
+	if err != nil {
+		return "", err
+	}
 
 	if prefixedPath == "" || prefixedPath[len(prefixedPath)-1:] == p.separator {
-		return prefixedPath
+		return prefixedPath, nil
 	}
 
-	return prefixedPath + p.separator
+	return prefixedPath + p.separator, nil
 }
 
 func (p PathPreFixer) StripTrailingSeparator(path string) string {
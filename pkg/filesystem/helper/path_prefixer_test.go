@@ -1,6 +1,7 @@
 package helper
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -14,7 +15,7 @@ func TestPrefixPath(t *testing.T) {
 	preFixer := setupPreFixer()
 	path := "/image/image.png"
 	expect := "storage/image/image.png"
-	actual := preFixer.PrefixPath(path)
+	actual, _ := preFixer.PrefixPath(path)
 	if actual == expect {
 		t.Logf("PrefixPath(\"%s\") PASS. Expect \"%s\", got \"%s\"", path, expect, actual)
 	} else {
@@ -50,7 +51,7 @@ func TestPrefixDirectoryPath(t *testing.T) {
 	preFixer := setupPreFixer()
 	path := "image"
 	expect := "storage/image/"
-	actual := preFixer.PrefixDirectoryPath(path)
+	actual, _ := preFixer.PrefixDirectoryPath(path)
 	if actual == expect {
 		t.Logf("PrefixDirectoryPath(\"%s\") PASS. Expect \"%s\", got \"%s\"", path, expect, actual)
 	} else {
@@ -59,10 +60,42 @@ func TestPrefixDirectoryPath(t *testing.T) {
 
 	path = ""
 	expect = "storage/"
-	actual = preFixer.PrefixDirectoryPath(path)
+	actual, _ = preFixer.PrefixDirectoryPath(path)
 	if actual == expect {
 		t.Logf("PrefixDirectoryPath(\"%s\") PASS. Expect \"%s\", got \"%s\"", path, expect, actual)
 	} else {
 		t.Errorf("PrefixDirectoryPath(\"%s\") FAILED. Expect \"%s\", got \"%s\"", path, expect, actual)
 	}
 }
+
+func TestNormalize(t *testing.T) {
+	preFixer := setupPreFixer()
+	cases := map[string]string{
+		"image/./a/../image.png": "image/image.png",
+		"\\image\\sub\\a.png":    "image/sub/a.png",
+		"//image//a.png/":        "image/a.png",
+		"a/b/../../c":            "c",
+		"":                       "",
+	}
+	for path, expect := range cases {
+		actual, err := preFixer.Normalize(path)
+		if err == nil && actual == expect {
+			t.Logf("Normalize(\"%s\") PASS. Expect \"%s\", got \"%s\"", path, expect, actual)
+		} else {
+			t.Errorf("Normalize(\"%s\") FAILED. Expect \"%s\", got \"%s\", %v", path, expect, actual, err)
+		}
+	}
+}
+
+func TestPathTraversal(t *testing.T) {
+	preFixer := setupPreFixer()
+	for _, path := range []string{"../../etc/passwd", "image/../../secret", "..\\..\\windows", "a\x00b"} {
+		_, err := preFixer.PrefixPath(path)
+		var traversal *PathTraversalError
+		if errors.Is(err, ErrPathOutsideRoot) && errors.As(err, &traversal) {
+			t.Logf("PrefixPath(%q) PASS. Expect traversal error, got \"%s\"", path, err)
+		} else {
+			t.Errorf("PrefixPath(%q) FAILED. Expect traversal error, got %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"github.com/kurneo/go-template/pkg/log"
+	"sync"
+)
+
+var (
+	managerInstance *Manager
+	managerOnce     sync.Once
+)
+
+const (
+	DiskLocal     = "local"
+	DiskPublic    = "public"
+	DiskS3        = "s3"
+	DiskMinio     = "minio"
+	DiskEncrypted = "encrypted"
+)
+
+const (
+	defaultSeparator  = "/"
+	defaultLocalRoot  = "storage/app"
+	defaultPublicRoot = "storage/app/public"
+)
+
+type Config struct {
+	// Default is the disk returned by Disk("")
+	Default string
+	Local   struct {
+		Root       string
+		Visibility Visibility
+	}
+	Public struct {
+		Root       string
+		Url        string
+		Visibility Visibility
+	}
+	S3    S3Config
+	Minio MinioConfig
+	// Encryption registers the "encrypted" disk wrapping Encryption.Disk when keys are set
+	Encryption EncryptionConfig
+	// UrlSigning enables temporary urls of local disks when Key is set,
+	// Url is the base of the route serving them (e.g. "http://localhost:3000/files")
+	UrlSigning struct {
+		Key string
+		Url string
+	}
+}
+
+// Manager holds the configured disks by name, s3 and minio are only built when configured
+type Manager struct {
+	mu     sync.RWMutex
+	disks  map[string]DriverContract
+	def    string
+	signer *UrlSigner
+}
+
+// Disk returns the disk registered under name, an empty name returns the default disk
+func (m *Manager) Disk(name string) (DriverContract, error) {
+	name = m.diskName(name)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	d, ok := m.disks[name]
+	if !ok {
+		return nil, fmt.Errorf("filesystem disk \"%s\" is not configured", name)
+	}
+	return d, nil
+}
+
+func (m *Manager) diskName(name string) string {
+	if name == "" {
+		return m.def
+	}
+	return name
+}
+
+// Default returns the default disk
+func (m *Manager) Default() (DriverContract, error) {
+	return m.Disk(m.def)
+}
+
+// Signer returns the signer of local temporary urls, nil when signing is not configured
+func (m *Manager) Signer() *UrlSigner {
+	return m.signer
+}
+
+// Extend registers d under name, replacing any configured disk. Tests use it to swap
+// real disks for NewDriverMemory()
+func (m *Manager) Extend(name string, d DriverContract) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.disks[name] = d
+}
+
+func New(c Config, l log.Contract) (*Manager, error) {
+	var err error
+	managerOnce.Do(func() {
+		managerInstance, err = newManager(c, l)
+	})
+	return managerInstance, err
+}
+
+func newManager(c Config, l log.Contract) (*Manager, error) {
+	m := &Manager{
+		disks: make(map[string]DriverContract),
+		def:   c.Default,
+	}
+	if m.def == "" {
+		m.def = DiskLocal
+	}
+
+	if c.UrlSigning.Key != "" {
+		signer, err := NewUrlSigner(c.UrlSigning.Key, c.UrlSigning.Url)
+		if err != nil {
+			return nil, err
+		}
+		m.signer = signer
+	}
+
+	localRoot := c.Local.Root
+	if localRoot == "" {
+		localRoot = defaultLocalRoot
+	}
+	localVisibility, err := ParseVisibility(string(c.Local.Visibility), VisibilityPrivate)
+	if err != nil {
+		return nil, err
+	}
+	m.disks[DiskLocal] = NewDiskLocal(localRoot, defaultSeparator, localVisibility, m.signer.ForDisk(DiskLocal))
+
+	publicRoot := c.Public.Root
+	if publicRoot == "" {
+		publicRoot = defaultPublicRoot
+	}
+	publicVisibility, err := ParseVisibility(string(c.Public.Visibility), VisibilityPublic)
+	if err != nil {
+		return nil, err
+	}
+	m.disks[DiskPublic] = NewDiskPublic(publicRoot, defaultSeparator, c.Public.Url, publicVisibility, m.signer.ForDisk(DiskPublic))
+
+	if c.S3.Visibility, err = ParseVisibility(string(c.S3.Visibility), VisibilityPrivate); err != nil {
+		return nil, err
+	}
+	if c.Minio.Visibility, err = ParseVisibility(string(c.Minio.Visibility), VisibilityPrivate); err != nil {
+		return nil, err
+	}
+
+	if c.S3.Bucket != "" {
+		d, err := NewDriverS3(c.S3, l)
+		if err != nil {
+			return nil, err
+		}
+		m.disks[DiskS3] = NewDiskS3(d, c.S3.Url)
+	}
+
+	if c.Minio.Endpoint != "" {
+		d, err := NewDriverMinio(c.Minio, l)
+		if err != nil {
+			return nil, err
+		}
+		m.disks[DiskMinio] = NewDiskS3(d, c.Minio.Url)
+	}
+
+	if len(c.Encryption.Keys) > 0 {
+		name := c.Encryption.Disk
+		if name == "" {
+			name = m.def
+		}
+		d, ok := m.disks[name]
+		if !ok || name == DiskEncrypted {
+			return nil, fmt.Errorf("filesystem encrypted disk cannot wrap disk \"%s\"", name)
+		}
+		if m.disks[DiskEncrypted], err = NewDiskEncrypted(d, c.Encryption); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := m.disks[m.def]; !ok {
+		return nil, errors.New("filesystem default disk is invalid")
+	}
+
+	return m, nil
+}
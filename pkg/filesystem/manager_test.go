@@ -0,0 +1,77 @@
+package filesystem
+
+import (
+	"testing"
+)
+
+func setupManager(t *testing.T) *Manager {
+	c := Config{}
+	c.Local.Root = "./storage/testing/unit/local"
+	c.Public.Root = "./storage/testing/unit/public"
+	c.Public.Url = "http://localhost:3000/storage/"
+	m, err := newManager(c, nil)
+	if err != nil {
+		t.Fatalf("newManager() FAILED. Unexpected error \"%s\"", err)
+	}
+	return m
+}
+
+func TestManagerDisk(t *testing.T) {
+	m := setupManager(t)
+	defer func() { teardownLocalDriver() }()
+
+	d, err := m.Disk("")
+	if _, ok := d.(DiskLocalContract); err == nil && ok {
+		t.Logf("Disk(\"\") PASS. Got default local disk")
+	} else {
+		t.Errorf("Disk(\"\") FAILED. Expected local disk, got %T, %v", d, err)
+	}
+
+	d, err = m.Disk(DiskPublic)
+	p, ok := d.(DiskPublicContract)
+	if err == nil && ok && p.Url("a/b.png") == "http://localhost:3000/storage/a/b.png" {
+		t.Logf("Disk(\"%s\") PASS. Got \"%s\"", DiskPublic, p.Url("a/b.png"))
+	} else {
+		t.Errorf("Disk(\"%s\") FAILED. Expected public disk with url, got %T, %v", DiskPublic, d, err)
+	}
+
+	if _, err = m.Disk(DiskS3); err != nil {
+		t.Logf("Disk(\"%s\") PASS. Expected error, got \"%s\"", DiskS3, err)
+	} else {
+		t.Errorf("Disk(\"%s\") FAILED. Expected error for unconfigured disk, got nil", DiskS3)
+	}
+}
+
+func TestManagerInvalidDefault(t *testing.T) {
+	c := Config{Default: DiskMinio}
+	if _, err := newManager(c, nil); err != nil {
+		t.Logf("newManager() PASS. Expected error, got \"%s\"", err)
+	} else {
+		t.Errorf("newManager() FAILED. Expected error for unconfigured default disk, got nil")
+	}
+}
+
+func TestManagerS3Disk(t *testing.T) {
+	c := Config{Default: DiskS3}
+	c.S3 = S3Config{Region: "us-east-1", Bucket: "bucket", Key: "key", Secret: "secret", Url: "https://cdn.example.com"}
+	m, err := newManager(c, nil)
+	if err != nil {
+		t.Fatalf("newManager() FAILED. Unexpected error \"%s\"", err)
+	}
+	d, _ := m.Default()
+	if s, ok := d.(DiskS3Contract); ok && s.Url("/a.png") == "https://cdn.example.com/a.png" {
+		t.Logf("Default() PASS. Got s3 disk")
+	} else {
+		t.Errorf("Default() FAILED. Expected s3 disk, got %T", d)
+	}
+}
+
+func TestManagerInvalidVisibility(t *testing.T) {
+	c := Config{}
+	c.Local.Visibility = "world"
+	if _, err := newManager(c, nil); err != nil {
+		t.Logf("newManager() PASS. Expected error, got \"%s\"", err)
+	} else {
+		t.Errorf("newManager() FAILED. Expected error for invalid visibility, got nil")
+	}
+}
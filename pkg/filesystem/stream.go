@@ -0,0 +1,43 @@
+package filesystem
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+)
+
+// detectContentType returns opts.ContentType, the type of the extension or the sniffed type
+// of the first bytes of r, the returned reader must be used instead of r
+func detectContentType(path string, r io.Reader, opts PutOptions) (string, io.Reader) {
+	if opts.ContentType != "" {
+		return opts.ContentType, r
+	}
+	if t := mime.TypeByExtension(filepath.Ext(path)); t != "" {
+		return t, r
+	}
+	br := bufio.NewReaderSize(r, 512)
+	head, _ := br.Peek(512)
+	return http.DetectContentType(head), br
+}
+
+func checksum(r io.ReadCloser, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := r.Close(); err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	h := sha256.New()
+	if _, err = io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
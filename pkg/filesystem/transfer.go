@@ -0,0 +1,170 @@
+package filesystem
+
+import (
+	"fmt"
+	pathPkg "path"
+	"strings"
+)
+
+// serverSideCopier is implemented by drivers able to copy a file from another driver of
+// the same backend without streaming it through the application, ok is false when src
+// lives on another backend and the file has to be streamed
+type serverSideCopier interface {
+	copyFrom(src DriverContract, from, to string, v Visibility) (ok bool, err error)
+}
+
+// CopyBetween copies a file from one disk to another keeping its visibility. The copy
+// is done server side when both disks share a backend (e.g. two buckets of one S3
+// account or two local roots) and streamed otherwise
+func (m *Manager) CopyBetween(srcDisk, srcPath, dstDisk, dstPath string) error {
+	src, dst, same, err := m.disksBetween(srcDisk, dstDisk)
+	if err != nil {
+		return err
+	}
+	if same {
+		return src.Copy(srcPath, dstPath)
+	}
+	return copyFileBetween(src, srcPath, dst, dstPath)
+}
+
+// MoveBetween copies a file to another disk with CopyBetween, then deletes the source
+func (m *Manager) MoveBetween(srcDisk, srcPath, dstDisk, dstPath string) error {
+	src, dst, same, err := m.disksBetween(srcDisk, dstDisk)
+	if err != nil {
+		return err
+	}
+	if same {
+		return src.Move(srcPath, dstPath)
+	}
+	if err = copyFileBetween(src, srcPath, dst, dstPath); err != nil {
+		return err
+	}
+	return src.Delete(srcPath)
+}
+
+// CopyDirectoryBetween copies the directory tree at srcPath to dstPath of another disk,
+// empty directories included. Files are copied one by one like CopyBetween, so object
+// stores work as well
+func (m *Manager) CopyDirectoryBetween(srcDisk, srcPath, dstDisk, dstPath string) error {
+	src, dst, same, err := m.disksBetween(srcDisk, dstDisk)
+	if err != nil {
+		return err
+	}
+	if same && isSubPath(srcPath, dstPath) {
+		return fmt.Errorf("cannot copy directory \"%s\" into itself", srcPath)
+	}
+	return copyDirectoryBetween(src, srcPath, dst, dstPath)
+}
+
+// MoveDirectoryBetween copies a directory tree with CopyDirectoryBetween, then deletes
+// the source tree. Nothing is deleted when any file fails to copy
+func (m *Manager) MoveDirectoryBetween(srcDisk, srcPath, dstDisk, dstPath string) error {
+	src, dst, same, err := m.disksBetween(srcDisk, dstDisk)
+	if err != nil {
+		return err
+	}
+	if same && isSubPath(srcPath, dstPath) {
+		return fmt.Errorf("cannot move directory \"%s\" into itself", srcPath)
+	}
+	if err = copyDirectoryBetween(src, srcPath, dst, dstPath); err != nil {
+		return err
+	}
+	return src.Delete(srcPath)
+}
+
+func (m *Manager) disksBetween(srcDisk, dstDisk string) (DriverContract, DriverContract, bool, error) {
+	src, err := m.Disk(srcDisk)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	dst, err := m.Disk(dstDisk)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return src, dst, m.diskName(srcDisk) == m.diskName(dstDisk), nil
+}
+
+func copyFileBetween(src DriverContract, from string, dst DriverContract, to string) error {
+	// an empty visibility leaves the default of dst, for buckets with ACLs disabled
+	v, err := src.GetVisibility(from)
+	if isS3AclNotSupported(err) {
+		v, err = "", nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if c, ok := backendOf(dst).(serverSideCopier); ok {
+		if done, err := c.copyFrom(backendOf(src), from, to, v); done || err != nil {
+			return err
+		}
+	}
+
+	r, err := src.ReadStream(from)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := r.Close(); err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	// the size only helps the destination choose how to upload, it is not required
+	size, _ := src.Size(from)
+	return dst.PutStream(to, r, PutOptions{
+		ContentType: src.Mime(from),
+		Size:        size,
+		Visibility:  v,
+	})
+}
+
+func copyDirectoryBetween(src DriverContract, from string, dst DriverContract, to string) error {
+	files, directories, err := src.ListContents(from)
+	if err != nil {
+		return err
+	}
+
+	if err = dst.MakeDir(to, 0755); err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		if err = copyFileBetween(src, pathPkg.Join(from, f.Name), dst, pathPkg.Join(to, f.Name)); err != nil {
+			return err
+		}
+	}
+	for _, d := range directories {
+		if err = copyDirectoryBetween(src, pathPkg.Join(from, d.Name), dst, pathPkg.Join(to, d.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backendOf returns the driver behind a disk so drivers of one backend can recognise each other
+func backendOf(d DriverContract) DriverContract {
+	switch disk := d.(type) {
+	case *diskLocal:
+		return disk.driver
+	case *diskPublic:
+		return disk.driver
+	case *diskS3:
+		return disk.DriverContract
+	}
+	return d
+}
+
+// isSubPath reports whether p is dir or lies below it, paths that leave the root are
+// left for the disks to reject
+func isSubPath(dir, p string) bool {
+	dir, err := memoryPreFixer.Normalize(dir)
+	if err != nil {
+		return false
+	}
+	p, err = memoryPreFixer.Normalize(p)
+	if err != nil {
+		return false
+	}
+	return dir == "" || p == dir || strings.HasPrefix(p, dir+"/")
+}
@@ -0,0 +1,153 @@
+package filesystem
+
+import (
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"os"
+	"testing"
+)
+
+// aclDisabledDisk fails to read visibilities like a bucket with ACLs disabled
+type aclDisabledDisk struct {
+	DriverContract
+}
+
+func (d aclDisabledDisk) GetVisibility(string) (Visibility, error) {
+	return "", awserr.New("AccessControlListNotSupported", "The bucket does not allow ACLs", nil)
+}
+
+func setupTransferManager(t *testing.T) *Manager {
+	m := setupManager(t)
+	m.Extend("archive", NewDriverMemory())
+	m.Extend("memory", NewDriverMemory())
+
+	local, _ := m.Disk(DiskLocal)
+	_ = local.MakeDir("uploads/2024/empty", 0755)
+	_ = local.Put("uploads/a.txt", []byte("file a"))
+	_ = local.Put("uploads/2024/b.txt", []byte("file b"))
+	return m
+}
+
+func TestCopyBetween(t *testing.T) {
+	m := setupTransferManager(t)
+	defer func() { teardownLocalDriver() }()
+
+	if err := m.CopyBetween(DiskLocal, "uploads/a.txt", "archive", "copied/a.txt"); err != nil {
+		t.Errorf("CopyBetween() FAILED. Unexpected error \"%s\"\n", err)
+	}
+	archive, _ := m.Disk("archive")
+	content, err := archive.Get("copied/a.txt")
+	if err == nil && string(content) == "file a" {
+		t.Logf("CopyBetween(\"%s\", \"archive\") PASS. Expected \"file a\", got \"%s\"\n", DiskLocal, content)
+	} else {
+		t.Errorf("CopyBetween(\"%s\", \"archive\") FAILED. Expected \"file a\", got \"%s\", %v\n", DiskLocal, content, err)
+	}
+
+	// local and public share the host filesystem and are copied server side
+	if err = m.CopyBetween(DiskLocal, "uploads/a.txt", DiskPublic, "nested/a.txt"); err != nil {
+		t.Errorf("CopyBetween() FAILED. Unexpected error \"%s\"\n", err)
+	}
+	public, _ := m.Disk(DiskPublic)
+	fi, err := os.Stat(public.RealPath("nested/a.txt"))
+	if err == nil && fi.Mode().Perm() == VisibilityPrivate.fileMode() {
+		t.Logf("CopyBetween(\"%s\", \"%s\") PASS. Expected mode %o, got %o\n", DiskLocal, DiskPublic, VisibilityPrivate.fileMode(), fi.Mode().Perm())
+	} else {
+		t.Errorf("CopyBetween(\"%s\", \"%s\") FAILED. Expected mode %o, got %v, %v\n", DiskLocal, DiskPublic, VisibilityPrivate.fileMode(), fi, err)
+	}
+	// a directory is copied as a tree, it must stay traversable
+	if err = m.CopyBetween(DiskLocal, "uploads/2024", DiskPublic, "tree"); err != nil {
+		t.Errorf("CopyBetween() FAILED. Unexpected error \"%s\"\n", err)
+	}
+	local, _ := m.Disk(DiskLocal)
+	v, _ := local.GetVisibility("uploads/2024")
+	fi, err = os.Stat(public.RealPath("tree"))
+	content, errGet := public.Get("tree/b.txt")
+	if err == nil && fi.Mode().Perm() == v.dirMode() && errGet == nil && string(content) == "file b" {
+		t.Logf("CopyBetween(\"%s\", \"%s\") directory PASS. Expected mode %o, got %o\n", DiskLocal, DiskPublic, v.dirMode(), fi.Mode().Perm())
+	} else {
+		t.Errorf("CopyBetween(\"%s\", \"%s\") directory FAILED. Expected mode %o and \"file b\", got %v, %v, \"%s\", %v\n", DiskLocal, DiskPublic, v.dirMode(), fi, err, content, errGet)
+	}
+}
+
+func TestCopyBetweenAclDisabled(t *testing.T) {
+	m := setupTransferManager(t)
+	defer func() { teardownLocalDriver() }()
+	src := NewDriverMemory()
+	_ = src.Put("a.txt", []byte("file a"))
+	m.Extend("bucket", aclDisabledDisk{DriverContract: src})
+
+	err := m.CopyBetween("bucket", "a.txt", "archive", "a.txt")
+	archive, _ := m.Disk("archive")
+	content, errGet := archive.Get("a.txt")
+	if err == nil && errGet == nil && string(content) == "file a" {
+		t.Logf("CopyBetween(\"bucket\", \"archive\") PASS. Expected the default visibility when ACLs are disabled\n")
+	} else {
+		t.Errorf("CopyBetween(\"bucket\", \"archive\") FAILED. Expected \"file a\", got \"%s\", %v, %v\n", content, err, errGet)
+	}
+}
+
+func TestMoveBetween(t *testing.T) {
+	m := setupTransferManager(t)
+	defer func() { teardownLocalDriver() }()
+
+	if err := m.MoveBetween(DiskLocal, "uploads/a.txt", "archive", "a.txt"); err != nil {
+		t.Errorf("MoveBetween() FAILED. Unexpected error \"%s\"\n", err)
+	}
+
+	local, _ := m.Disk(DiskLocal)
+	archive, _ := m.Disk("archive")
+	srcExists, _ := local.FileExists("uploads/a.txt")
+	dstExists, _ := archive.FileExists("a.txt")
+	if !srcExists && dstExists {
+		t.Logf("MoveBetween(\"%s\", \"archive\") PASS. Expected source removed and target written\n", DiskLocal)
+	} else {
+		t.Errorf("MoveBetween(\"%s\", \"archive\") FAILED. Expected source removed and target written, got %t, %t\n", DiskLocal, srcExists, dstExists)
+	}
+}
+
+func TestCopyDirectoryBetween(t *testing.T) {
+	m := setupTransferManager(t)
+	defer func() { teardownLocalDriver() }()
+
+	if err := m.CopyDirectoryBetween(DiskLocal, "uploads", "archive", "backup"); err != nil {
+		t.Errorf("CopyDirectoryBetween() FAILED. Unexpected error \"%s\"\n", err)
+	}
+
+	archive, _ := m.Disk("archive")
+	for _, path := range []string{"backup/a.txt", "backup/2024/b.txt"} {
+		if exist, _ := archive.FileExists(path); exist {
+			t.Logf("CopyDirectoryBetween() PASS. Expected \"%s\" to exist\n", path)
+		} else {
+			t.Errorf("CopyDirectoryBetween() FAILED. Expected \"%s\" to exist\n", path)
+		}
+	}
+	if exist, _ := archive.DirExists("backup/2024/empty"); exist {
+		t.Logf("CopyDirectoryBetween() PASS. Expected empty directory to exist\n")
+	} else {
+		t.Errorf("CopyDirectoryBetween() FAILED. Expected empty directory to exist\n")
+	}
+
+	if err := m.CopyDirectoryBetween("archive", "backup", "archive", "backup/2024"); err != nil {
+		t.Logf("CopyDirectoryBetween() PASS. Expected error, got \"%s\"\n", err)
+	} else {
+		t.Errorf("CopyDirectoryBetween() FAILED. Expected error copying a directory into itself, got nil\n")
+	}
+}
+
+func TestMoveDirectoryBetween(t *testing.T) {
+	m := setupTransferManager(t)
+	defer func() { teardownLocalDriver() }()
+
+	if err := m.MoveDirectoryBetween(DiskLocal, "uploads", "archive", "uploads"); err != nil {
+		t.Errorf("MoveDirectoryBetween() FAILED. Unexpected error \"%s\"\n", err)
+	}
+
+	local, _ := m.Disk(DiskLocal)
+	archive, _ := m.Disk("archive")
+	srcExists, _ := local.DirExists("uploads")
+	dstExists, _ := archive.FileExists("uploads/2024/b.txt")
+	if !srcExists && dstExists {
+		t.Logf("MoveDirectoryBetween(\"%s\", \"archive\") PASS. Expected source removed and tree written\n", DiskLocal)
+	} else {
+		t.Errorf("MoveDirectoryBetween(\"%s\", \"archive\") FAILED. Expected source removed and tree written, got %t, %t\n", DiskLocal, srcExists, dstExists)
+	}
+}
@@ -0,0 +1,118 @@
+package filesystem
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrTemporaryUrlNotSupported = errors.New("temporary urls are not supported by this disk")
+	ErrInvalidSignature         = errors.New("url signature is invalid")
+	ErrUrlExpired               = errors.New("url is expired")
+)
+
+// UrlSigner creates and verifies HMAC signed urls for disks that are not served
+// by an object store, the files are streamed by the route mounted at RoutePath
+type UrlSigner struct {
+	key  []byte
+	url  string
+	disk string
+}
+
+// ForDisk returns a signer producing urls for the named disk
+func (s *UrlSigner) ForDisk(disk string) *UrlSigner {
+	if s == nil {
+		return nil
+	}
+	return &UrlSigner{key: s.key, url: s.url, disk: disk}
+}
+
+// RoutePath returns the path of the route that must serve signed urls, e.g. "/files"
+func (s *UrlSigner) RoutePath() string {
+	p, _ := routePath(s.url)
+	return p
+}
+
+// Route returns the route pattern serving signed urls, e.g. "/files/:disk/*"
+func (s *UrlSigner) Route() string {
+	return s.RoutePath() + "/:disk/*"
+}
+
+// Sign returns a url to path valid for expiry
+func (s *UrlSigner) Sign(path string, expiry time.Duration) (string, error) {
+	if s == nil || s.disk == "" {
+		return "", ErrTemporaryUrlNotSupported
+	}
+	path = strings.TrimLeft(path, "\\/")
+	expires := strconv.FormatInt(time.Now().Add(expiry).Unix(), 10)
+
+	segments := strings.Split(path, "/")
+	for i, v := range segments {
+		segments[i] = url.PathEscape(v)
+	}
+
+	q := url.Values{}
+	q.Set("expires", expires)
+	q.Set("signature", s.signature(s.disk, path, expires))
+
+	return strings.TrimRight(s.url, "/") + "/" + url.PathEscape(s.disk) + "/" + strings.Join(segments, "/") + "?" + q.Encode(), nil
+}
+
+// Verify checks the signature and expiry of a request for path on disk
+func (s *UrlSigner) Verify(disk, path string, query url.Values) error {
+	path = strings.TrimLeft(path, "\\/")
+	expires := query.Get("expires")
+	signature := query.Get("signature")
+
+	expected := s.signature(disk, path, expires)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return ErrInvalidSignature
+	}
+
+	at, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if time.Now().Unix() > at {
+		return ErrUrlExpired
+	}
+	return nil
+}
+
+func (s *UrlSigner) signature(disk, path, expires string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(disk + "\n" + path + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// routePath returns the path of baseUrl without trailing slashes, the route is mounted
+// next to the application routes so it cannot be the root
+func routePath(baseUrl string) (string, error) {
+	u, err := url.Parse(baseUrl)
+	if err != nil {
+		return "", err
+	}
+	p := strings.TrimRight(u.Path, "/")
+	if p == "" {
+		return "", fmt.Errorf("url signing url \"%s\" has no path, e.g. \"http://localhost:3000/files\"", baseUrl)
+	}
+	return p, nil
+}
+
+// NewUrlSigner creates a signer for urls under baseUrl (e.g. "http://localhost:3000/files")
+func NewUrlSigner(key, baseUrl string) (*UrlSigner, error) {
+	if key == "" {
+		return nil, errors.New("url signing key is empty")
+	}
+	if _, err := routePath(baseUrl); err != nil {
+		return nil, err
+	}
+	return &UrlSigner{key: []byte(key), url: baseUrl}, nil
+}
@@ -0,0 +1,110 @@
+package filesystem
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setupUrlSigner(t *testing.T) *UrlSigner {
+	s, err := NewUrlSigner("secret", "http://localhost:3000/files")
+	if err != nil {
+		t.Fatalf("NewUrlSigner() FAILED. Unexpected error \"%s\"", err)
+	}
+	return s.ForDisk(DiskLocal)
+}
+
+func parseSigned(t *testing.T, signed string) (string, url.Values) {
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatalf("url.Parse(\"%s\") FAILED. Unexpected error \"%s\"", signed, err)
+	}
+	return strings.TrimPrefix(u.Path, "/files/"+DiskLocal+"/"), u.Query()
+}
+
+func TestUrlSignerSign(t *testing.T) {
+	s := setupUrlSigner(t)
+	signed, err := s.Sign("/reports/a b.pdf", time.Minute)
+	if err != nil {
+		t.Fatalf("Sign() FAILED. Unexpected error \"%s\"", err)
+	}
+
+	if strings.HasPrefix(signed, "http://localhost:3000/files/local/reports/a%20b.pdf?") {
+		t.Logf("Sign() PASS. Got \"%s\"", signed)
+	} else {
+		t.Errorf("Sign() FAILED. Expected escaped url under the route, got \"%s\"", signed)
+	}
+
+	path, q := parseSigned(t, signed)
+	if err = s.Verify(DiskLocal, path, q); err == nil {
+		t.Logf("Verify() PASS. Expected nil, got nil")
+	} else {
+		t.Errorf("Verify() FAILED. Expected nil, got \"%s\"", err)
+	}
+
+	if err = s.Verify(DiskLocal, "reports/other.pdf", q); errors.Is(err, ErrInvalidSignature) {
+		t.Logf("Verify() PASS. Expected invalid signature for another path, got \"%s\"", err)
+	} else {
+		t.Errorf("Verify() FAILED. Expected invalid signature for another path, got %v", err)
+	}
+
+	if err = s.Verify(DiskPublic, path, q); errors.Is(err, ErrInvalidSignature) {
+		t.Logf("Verify() PASS. Expected invalid signature for another disk, got \"%s\"", err)
+	} else {
+		t.Errorf("Verify() FAILED. Expected invalid signature for another disk, got %v", err)
+	}
+}
+
+func TestUrlSignerExpired(t *testing.T) {
+	s := setupUrlSigner(t)
+	signed, _ := s.Sign("a.txt", -time.Minute)
+	path, q := parseSigned(t, signed)
+
+	if err := s.Verify(DiskLocal, path, q); errors.Is(err, ErrUrlExpired) {
+		t.Logf("Verify() PASS. Expected expired, got \"%s\"", err)
+	} else {
+		t.Errorf("Verify() FAILED. Expected expired, got %v", err)
+	}
+}
+
+func TestDiskTemporaryUrl(t *testing.T) {
+	d := NewDiskLocal("./storage/testing/unit", "/", VisibilityPrivate, nil)
+	if _, err := d.TemporaryUrl("a.txt", time.Minute); errors.Is(err, ErrTemporaryUrlNotSupported) {
+		t.Logf("TemporaryUrl() PASS. Expected not supported without signer, got \"%s\"", err)
+	} else {
+		t.Errorf("TemporaryUrl() FAILED. Expected not supported without signer, got %v", err)
+	}
+
+	d = NewDiskLocal("./storage/testing/unit", "/", VisibilityPrivate, setupUrlSigner(t))
+	if u, err := d.TemporaryUrl("a.txt", time.Minute); err == nil && strings.Contains(u, "signature=") {
+		t.Logf("TemporaryUrl() PASS. Got \"%s\"", u)
+	} else {
+		t.Errorf("TemporaryUrl() FAILED. Expected signed url, got \"%s\", %v", u, err)
+	}
+
+	s3, _ := NewDriverS3(S3Config{Region: "us-east-1", Bucket: "bucket", Key: "key", Secret: "secret"}, nil)
+	if u, err := NewDiskS3(s3, "").TemporaryUrl("a.txt", time.Minute); err == nil && strings.Contains(u, "X-Amz-Signature=") {
+		t.Logf("TemporaryUrl() PASS. Got presigned \"%s\"", u)
+	} else {
+		t.Errorf("TemporaryUrl() FAILED. Expected presigned url, got \"%s\", %v", u, err)
+	}
+}
+
+func TestNewUrlSignerRootPath(t *testing.T) {
+	for _, u := range []string{"http://localhost:3000/", "http://localhost:3000", "http://localhost:3000//"} {
+		if _, err := NewUrlSigner("secret", u); err != nil {
+			t.Logf("NewUrlSigner(\"%s\") PASS. Expected error, got \"%s\"\n", u, err)
+		} else {
+			t.Errorf("NewUrlSigner(\"%s\") FAILED. Expected error for a url without path, got nil\n", u)
+		}
+	}
+
+	s, err := NewUrlSigner("secret", "http://localhost:3000/files/")
+	if err == nil && s.RoutePath() == "/files" {
+		t.Logf("RoutePath() PASS. Expected \"/files\", got \"%s\"\n", s.RoutePath())
+	} else {
+		t.Errorf("RoutePath() FAILED. Expected \"/files\", got %v, %v\n", s, err)
+	}
+}
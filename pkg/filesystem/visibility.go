@@ -0,0 +1,73 @@
+package filesystem
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+type Visibility string
+
+const (
+	VisibilityPublic  Visibility = "public"
+	VisibilityPrivate Visibility = "private"
+)
+
+const (
+	aclPublicRead = "public-read"
+	aclPrivate    = "private"
+)
+
+// ParseVisibility parses v, an empty value returns def
+func ParseVisibility(v string, def Visibility) (Visibility, error) {
+	switch Visibility(strings.ToLower(strings.TrimSpace(v))) {
+	case "":
+		return def, nil
+	case VisibilityPublic:
+		return VisibilityPublic, nil
+	case VisibilityPrivate:
+		return VisibilityPrivate, nil
+	default:
+		return "", fmt.Errorf("filesystem visibility \"%s\" is invalid", v)
+	}
+}
+
+// or returns v, or def when v is empty
+func (v Visibility) or(def Visibility) Visibility {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+// acl maps the visibility to a canned S3 ACL
+func (v Visibility) acl() string {
+	if v == VisibilityPublic {
+		return aclPublicRead
+	}
+	return aclPrivate
+}
+
+// fileMode maps the visibility to the mode of local files
+func (v Visibility) fileMode() os.FileMode {
+	if v == VisibilityPublic {
+		return 0644
+	}
+	return 0600
+}
+
+// dirMode maps the visibility to the mode of local directories
+func (v Visibility) dirMode() os.FileMode {
+	if v == VisibilityPublic {
+		return 0755
+	}
+	return 0700
+}
+
+// visibilityOfMode reports files readable by group or others as public
+func visibilityOfMode(mode os.FileMode) Visibility {
+	if mode.Perm()&0044 != 0 {
+		return VisibilityPublic
+	}
+	return VisibilityPrivate
+}
@@ -0,0 +1,170 @@
+package filesystem
+
+import (
+	"github.com/kurneo/go-template/pkg/filesystem/helper"
+	"io/fs"
+	pathPkg "path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WalkFunc is called by Walk for every file and directory below the walked path, exactly
+// one of file and directory is set. Returning fs.SkipDir for a directory skips its
+// contents and returning fs.SkipAll stops the walk without error
+type WalkFunc func(file *File, directory *Directory) error
+
+// listContentsRecursive lists path level by level, for drivers without a native
+// recursive listing
+func listContentsRecursive(d DriverContract, path string) ([]File, []Directory, error) {
+	files, directories, err := d.ListContents(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, directory := range directories {
+		f, dirs, err := listContentsRecursive(d, directory.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, f...)
+		directories = append(directories, dirs...)
+	}
+	return files, directories, nil
+}
+
+// walkLevels lists path one level at a time and calls fn for the entries of a level as
+// soon as it is listed, parents are visited before their contents and siblings in
+// lexical order. A directory skipped with fs.SkipDir is never listed and fs.SkipAll
+// stops before any further listing
+func walkLevels(d DriverContract, path string, fn WalkFunc) error {
+	if err := walkLevel(d, path, fn); err != nil && err != fs.SkipAll {
+		return err
+	}
+	return nil
+}
+
+func walkLevel(d DriverContract, path string, fn WalkFunc) error {
+	files, directories, err := d.ListContents(path)
+	if err != nil {
+		return err
+	}
+
+	type entry struct {
+		name      string
+		file      *File
+		directory *Directory
+	}
+	entries := make([]entry, 0, len(files)+len(directories))
+	for i := range files {
+		entries = append(entries, entry{name: files[i].Name, file: &files[i]})
+	}
+	for i := range directories {
+		entries = append(entries, entry{name: directories[i].Name, directory: &directories[i]})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+
+	for _, e := range entries {
+		err = fn(e.file, e.directory)
+		switch {
+		case err == fs.SkipDir && e.directory != nil:
+			continue
+		case err != nil:
+			return err
+		case e.directory != nil:
+			if err = walkLevel(d, e.directory.Path, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// keyDirectories collects the directories of a recursive object store listing, marker
+// objects as well as the prefixes of keys, each directory once
+type keyDirectories struct {
+	pf   helper.PathPreFixer
+	root string
+	seen map[string]int
+	list []Directory
+}
+
+// addKey registers the directories containing key and, for marker keys ending with a
+// separator, the directory itself
+func (k *keyDirectories) addKey(key string, modTime *time.Time) {
+	rel := strings.TrimPrefix(key, k.root)
+	marker := strings.HasSuffix(rel, "/")
+	rel = strings.TrimRight(rel, "/")
+	if rel == "" {
+		return
+	}
+
+	for i := 0; i < len(rel); i++ {
+		if rel[i] == '/' {
+			k.add(k.root+rel[:i], nil)
+		}
+	}
+	if marker {
+		k.add(k.root+rel, modTime)
+	}
+}
+
+func (k *keyDirectories) add(key string, modTime *time.Time) {
+	if i, ok := k.seen[key]; ok {
+		if modTime != nil {
+			k.list[i].ModTime = modTime
+		}
+		return
+	}
+	k.seen[key] = len(k.list)
+	k.list = append(k.list, Directory{
+		Path:    k.pf.StripPrefix(key),
+		Name:    pathPkg.Base(key),
+		ModTime: modTime,
+	})
+}
+
+func newKeyDirectories(pf helper.PathPreFixer, root string) *keyDirectories {
+	return &keyDirectories{
+		pf:   pf,
+		root: root,
+		seen: make(map[string]int),
+		list: make([]Directory, 0),
+	}
+}
+
+// MatchFiles returns the files matching a glob pattern (see path.Match). Patterns without
+// a separator such as "*.png" match the file name at any depth, other patterns match the
+// whole path
+func MatchFiles(files []File, pattern string) ([]File, error) {
+	pattern = strings.TrimLeft(strings.ReplaceAll(pattern, "\\", "/"), "/")
+	byName := !strings.Contains(pattern, "/")
+
+	matched := make([]File, 0)
+	for _, f := range files {
+		subject := f.Path
+		if byName {
+			subject = f.Name
+		}
+		ok, err := pathPkg.Match(pattern, subject)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, f)
+		}
+	}
+	return matched, nil
+}
+
+// Glob lists path recursively and returns the files matching pattern, see MatchFiles
+func Glob(d DriverContract, path, pattern string) ([]File, error) {
+	if _, err := pathPkg.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	files, _, err := d.ListContentsRecursive(path)
+	if err != nil {
+		return nil, err
+	}
+	return MatchFiles(files, pattern)
+}
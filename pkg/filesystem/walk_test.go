@@ -0,0 +1,183 @@
+package filesystem
+
+import (
+	"github.com/kurneo/go-template/pkg/filesystem/helper"
+	"io/fs"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func setupWalkDriver() DriverContract {
+	d := NewDriverMemory()
+	_ = d.Put("a.txt", []byte("a"))
+	_ = d.Put("a/b.png", []byte("b"))
+	_ = d.Put("a/c/d.png", []byte("d"))
+	_ = d.MakeDir("a/e", 0755)
+	_ = d.Put("z.png", []byte("z"))
+	return d
+}
+
+func TestListContentsRecursive(t *testing.T) {
+	for name, d := range map[string]DriverContract{"memory": setupWalkDriver(), "local": setupLocalDriver()} {
+		if name == "local" {
+			_ = d.Put("test/nested.txt", []byte("nested"))
+		}
+
+		files, directories, err := d.ListContentsRecursive("")
+		if err != nil {
+			t.Errorf("%s ListContentsRecursive(\"\") FAILED. Unexpected error \"%s\"\n", name, err)
+			continue
+		}
+
+		paths := make([]string, 0)
+		for _, f := range files {
+			paths = append(paths, f.Path)
+		}
+		for _, dir := range directories {
+			paths = append(paths, dir.Path+"/")
+		}
+		sort.Strings(paths)
+
+		expect := []string{"a.txt", "a/", "a/b.png", "a/c/", "a/c/d.png", "a/e/", "z.png"}
+		if name == "local" {
+			expect = []string{"test.txt", "test/", "test/nested.txt"}
+		}
+		if reflect.DeepEqual(paths, expect) {
+			t.Logf("%s ListContentsRecursive(\"\") PASS. Expected %v, got %v\n", name, expect, paths)
+		} else {
+			t.Errorf("%s ListContentsRecursive(\"\") FAILED. Expected %v, got %v\n", name, expect, paths)
+		}
+	}
+	teardownLocalDriver()
+}
+
+func TestWalk(t *testing.T) {
+	d := setupWalkDriver()
+
+	visited := make([]string, 0)
+	err := d.Walk("", func(file *File, directory *Directory) error {
+		if directory != nil {
+			visited = append(visited, directory.Path+"/")
+			if directory.Name == "c" {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		visited = append(visited, file.Path)
+		return nil
+	})
+
+	expect := []string{"a/", "a/b.png", "a/c/", "a/e/", "a.txt", "z.png"}
+	if err == nil && reflect.DeepEqual(visited, expect) {
+		t.Logf("Walk(\"\") PASS. Expected %v, got %v\n", expect, visited)
+	} else {
+		t.Errorf("Walk(\"\") FAILED. Expected %v, got %v, %v\n", expect, visited, err)
+	}
+
+	count := 0
+	err = d.Walk("a", func(file *File, directory *Directory) error {
+		count++
+		return fs.SkipAll
+	})
+	if err == nil && count == 1 {
+		t.Logf("Walk(\"a\") PASS. Expected SkipAll to stop after 1 entry, got %d\n", count)
+	} else {
+		t.Errorf("Walk(\"a\") FAILED. Expected SkipAll to stop after 1 entry, got %d, %v\n", count, err)
+	}
+}
+
+// listingDriver records the paths listed through ListContents
+type listingDriver struct {
+	DriverContract
+	listed []string
+}
+
+func (d *listingDriver) ListContents(path string) ([]File, []Directory, error) {
+	d.listed = append(d.listed, path)
+	return d.DriverContract.ListContents(path)
+}
+
+func TestWalkSkipsListing(t *testing.T) {
+	d := &listingDriver{DriverContract: setupWalkDriver()}
+
+	_ = walkLevels(d, "", func(file *File, directory *Directory) error {
+		if directory != nil && directory.Name == "c" {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	expect := []string{"", "a", "a/e"}
+	if reflect.DeepEqual(d.listed, expect) {
+		t.Logf("walkLevels() PASS. Expected skipped directory not listed, got %v\n", d.listed)
+	} else {
+		t.Errorf("walkLevels() FAILED. Expected listed %v, got %v\n", expect, d.listed)
+	}
+
+	d.listed = nil
+	_ = walkLevels(d, "", func(file *File, directory *Directory) error {
+		return fs.SkipAll
+	})
+	if reflect.DeepEqual(d.listed, []string{""}) {
+		t.Logf("walkLevels() PASS. Expected SkipAll to stop listing, got %v\n", d.listed)
+	} else {
+		t.Errorf("walkLevels() FAILED. Expected only the root listed, got %v\n", d.listed)
+	}
+}
+
+func TestGlob(t *testing.T) {
+	d := setupWalkDriver()
+
+	for pattern, expect := range map[string][]string{
+		"*.png":   {"a/b.png", "a/c/d.png", "z.png"},
+		"a/*.png": {"a/b.png"},
+		"*.gif":   {},
+	} {
+		files, err := Glob(d, "", pattern)
+		paths := make([]string, 0)
+		for _, f := range files {
+			paths = append(paths, f.Path)
+		}
+		sort.Strings(paths)
+		if err == nil && reflect.DeepEqual(paths, expect) {
+			t.Logf("Glob(\"%s\") PASS. Expected %v, got %v\n", pattern, expect, paths)
+		} else {
+			t.Errorf("Glob(\"%s\") FAILED. Expected %v, got %v, %v\n", pattern, expect, paths, err)
+		}
+	}
+
+	if _, err := Glob(d, "", "[a-"); err != nil {
+		t.Logf("Glob(\"[a-\") PASS. Expected error, got \"%s\"\n", err)
+	} else {
+		t.Errorf("Glob(\"[a-\") FAILED. Expected error for malformed pattern, got nil\n")
+	}
+}
+
+func TestKeyDirectories(t *testing.T) {
+	pf := helper.NewPreFixer("uploads", "/")
+	root, _ := pf.PrefixDirectoryPath("images")
+	modTime := time.Now()
+
+	directories := newKeyDirectories(pf, root)
+	directories.addKey("uploads/images/2024/01/a.png", nil)
+	directories.addKey("uploads/images/2024/b.png", nil)
+	directories.addKey("uploads/images/empty/", &modTime)
+
+	paths := make([]string, 0)
+	for _, dir := range directories.list {
+		paths = append(paths, dir.Path)
+	}
+	expect := []string{"images/2024", "images/2024/01", "images/empty"}
+	if reflect.DeepEqual(paths, expect) && directories.list[2].ModTime == &modTime {
+		t.Logf("addKey() PASS. Expected %v, got %v\n", expect, paths)
+	} else {
+		t.Errorf("addKey() FAILED. Expected %v with marker mod time, got %v\n", expect, paths)
+	}
+
+	if len(directories.list) > 0 && directories.list[0].Name == "2024" {
+		t.Logf("addKey() PASS. Expected name \"2024\", got \"%s\"\n", directories.list[0].Name)
+	} else {
+		t.Errorf("addKey() FAILED. Expected name \"2024\", got %v\n", directories.list)
+	}
+}
@@ -0,0 +1,130 @@
+package image
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	_ "golang.org/x/image/webp"
+	goImage "image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+// defaultMaxPixels guards against decompression bombs, 50 megapixels is about 200 MB decoded
+const defaultMaxPixels = 50_000_000
+
+var mimes = map[string]string{
+	FormatJpeg: "image/jpeg",
+	FormatPng:  "image/png",
+	FormatGif:  "image/gif",
+	FormatWebp: "image/webp",
+}
+
+var extensions = map[string]string{
+	FormatJpeg: ".jpg",
+	FormatPng:  ".png",
+	FormatGif:  ".gif",
+}
+
+// Decode decodes a jpeg, png, gif or webp image upright, jpeg EXIF orientation is applied
+// since metadata is dropped when the image is encoded again
+func Decode(b []byte, maxPixels int) (goImage.Image, string, error) {
+	if maxPixels <= 0 {
+		maxPixels = defaultMaxPixels
+	}
+
+	cfg, format, err := goImage.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %s", ErrFormatNotSupported, err)
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, "", fmt.Errorf("image of %dx%d pixels exceeds the limit of %d", cfg.Width, cfg.Height, maxPixels)
+	}
+
+	img, format, err := goImage.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, "", err
+	}
+	if format == FormatJpeg {
+		img = orient(img, jpegOrientation(b))
+	}
+	return img, format, nil
+}
+
+// Encode writes img in format, encoding never copies metadata so EXIF data is stripped
+func Encode(w io.Writer, img goImage.Image, format string, quality int) error {
+	switch format {
+	case FormatJpeg:
+		if quality <= 0 {
+			quality = defaultQuality
+		}
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	case FormatPng:
+		return png.Encode(w, img)
+	case FormatGif:
+		return gif.Encode(w, img, nil)
+	}
+	return fmt.Errorf("%w: cannot encode \"%s\"", ErrFormatNotSupported, format)
+}
+
+// jpegOrientation reads the orientation tag of the EXIF segment, 1 (upright) when missing
+func jpegOrientation(b []byte) int {
+	if len(b) < 4 || b[0] != 0xFF || b[1] != 0xD8 {
+		return 1
+	}
+
+	for i := 2; i+4 <= len(b); {
+		if b[i] != 0xFF {
+			return 1
+		}
+		marker := b[i+1]
+		size := int(binary.BigEndian.Uint16(b[i+2:]))
+		// start of scan, the metadata segments are all before it
+		if marker == 0xDA || size < 2 || i+2+size > len(b) {
+			return 1
+		}
+		segment := b[i+4 : i+2+size]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + size
+	}
+	return 1
+}
+
+// exifOrientation reads tag 0x0112 of IFD0 in a TIFF structure
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if v := int(order.Uint16(tiff[entry+8:])); v >= 1 && v <= 8 {
+				return v
+			}
+			return 1
+		}
+	}
+	return 1
+}
@@ -0,0 +1,225 @@
+package image
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/kurneo/go-template/pkg/filesystem"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	processorInstance *Processor
+	processorOnce     sync.Once
+)
+
+const (
+	defaultVariantsDir   = "variants"
+	defaultVariantFormat = FormatPng
+)
+
+var (
+	ErrPresetNotFound  = errors.New("image preset is not configured")
+	ErrUrlNotSupported = errors.New("image variant disk has no public urls")
+)
+
+type Config struct {
+	// SourceDisk images are read from, empty means the default disk
+	SourceDisk string
+	// Disk variants are written to, empty means the default disk
+	Disk string
+	// Dir of the variants, the "thumbnail" variant of "categories/a.jpg" is written to
+	// "<Dir>/thumbnail/categories/a.jpg", defaults to "variants"
+	Dir     string
+	Presets map[string]Preset
+	// MaxPixels rejects larger sources before decoding them, defaults to 50 megapixels
+	MaxPixels int
+	// Visibility of the variants, the disk default is used when empty
+	Visibility filesystem.Visibility
+}
+
+// Variant describes a written variant, Url is set when the disk serves public urls
+type Variant struct {
+	Preset string `json:"preset"`
+	Path   string `json:"path"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Mime   string `json:"mime"`
+	Size   int64  `json:"size"`
+	Url    string `json:"url,omitempty"`
+}
+
+// Processor writes resized, cropped and converted variants of images stored on a disk
+type Processor struct {
+	c Config
+	m *filesystem.Manager
+}
+
+// Process decodes the image at path of the source disk and writes a variant for every
+// named preset, or for every configured preset when none is given
+func (p *Processor) Process(path string, presets ...string) ([]Variant, error) {
+	if len(presets) == 0 {
+		presets = p.Presets()
+	}
+	for _, name := range presets {
+		if _, ok := p.c.Presets[name]; !ok {
+			return nil, fmt.Errorf("%w: \"%s\"", ErrPresetNotFound, name)
+		}
+	}
+
+	src, err := p.m.Disk(p.c.SourceDisk)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := p.m.Disk(p.c.Disk)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := src.Get(path)
+	if err != nil {
+		return nil, err
+	}
+	img, format, err := Decode(b, p.c.MaxPixels)
+	if err != nil {
+		return nil, err
+	}
+
+	variants := make([]Variant, 0, len(presets))
+	for _, name := range presets {
+		preset := p.c.Presets[name]
+		out := variantFormat(preset, format)
+
+		resized := Transform(img, preset)
+		buf := new(bytes.Buffer)
+		if err = Encode(buf, resized, out, preset.quality()); err != nil {
+			return nil, err
+		}
+
+		v := Variant{
+			Preset: name,
+			Path:   p.variantPath(path, name, out),
+			Width:  resized.Bounds().Dx(),
+			Height: resized.Bounds().Dy(),
+			Mime:   mimes[out],
+			Size:   int64(buf.Len()),
+		}
+		err = dst.PutStream(v.Path, buf, filesystem.PutOptions{
+			ContentType: v.Mime,
+			Size:        v.Size,
+			Visibility:  p.c.Visibility,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if d, ok := dst.(interface{ Url(path string) string }); ok {
+			v.Url = d.Url(v.Path)
+		}
+		variants = append(variants, v)
+	}
+	return variants, nil
+}
+
+// VariantPath returns where the variant of the preset for the image at path is written
+func (p *Processor) VariantPath(path, preset string) (string, error) {
+	pr, ok := p.c.Presets[preset]
+	if !ok {
+		return "", fmt.Errorf("%w: \"%s\"", ErrPresetNotFound, preset)
+	}
+	format := normalizeFormat(strings.TrimPrefix(pathExt(path), "."))
+	return p.variantPath(path, preset, variantFormat(pr, format)), nil
+}
+
+// Url returns the url of a variant through the Url method of the variants disk
+func (p *Processor) Url(path, preset string) (string, error) {
+	vp, err := p.VariantPath(path, preset)
+	if err != nil {
+		return "", err
+	}
+	d, err := p.m.Disk(p.c.Disk)
+	if err != nil {
+		return "", err
+	}
+	u, ok := d.(interface{ Url(path string) string })
+	if !ok {
+		return "", ErrUrlNotSupported
+	}
+	return u.Url(vp), nil
+}
+
+// Delete removes every variant of the image at path
+func (p *Processor) Delete(path string) error {
+	d, err := p.m.Disk(p.c.Disk)
+	if err != nil {
+		return err
+	}
+	for _, name := range p.Presets() {
+		vp, _ := p.VariantPath(path, name)
+		if err = d.Delete(vp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Presets returns the names of the configured presets
+func (p *Processor) Presets() []string {
+	names := make([]string, 0, len(p.c.Presets))
+	for name := range p.c.Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (p *Processor) variantPath(src, preset, format string) string {
+	base := strings.TrimSuffix(src, pathExt(src))
+	return path.Join(p.c.Dir, preset, base+extensions[format])
+}
+
+// variantFormat keeps the source format when it can be encoded, webp sources become png
+func variantFormat(p Preset, source string) string {
+	if p.Format != "" {
+		return p.Format
+	}
+	if _, ok := extensions[source]; ok {
+		return source
+	}
+	return defaultVariantFormat
+}
+
+func pathExt(p string) string {
+	return path.Ext(strings.ReplaceAll(p, "\\", "/"))
+}
+
+func New(c Config, m *filesystem.Manager) (*Processor, error) {
+	var err error
+	processorOnce.Do(func() {
+		processorInstance, err = newProcessor(c, m)
+	})
+	return processorInstance, err
+}
+
+func newProcessor(c Config, m *filesystem.Manager) (*Processor, error) {
+	if c.Dir == "" {
+		c.Dir = defaultVariantsDir
+	}
+	if c.Presets == nil {
+		c.Presets = make(map[string]Preset)
+	}
+	for name, preset := range c.Presets {
+		if err := preset.validate(); err != nil {
+			return nil, fmt.Errorf("image preset \"%s\": %w", name, err)
+		}
+	}
+	if _, err := m.Disk(c.SourceDisk); err != nil {
+		return nil, err
+	}
+	if _, err := m.Disk(c.Disk); err != nil {
+		return nil, err
+	}
+	return &Processor{c: c, m: m}, nil
+}
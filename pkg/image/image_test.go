@@ -0,0 +1,159 @@
+package image
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"github.com/kurneo/go-template/pkg/filesystem"
+	"hash/crc32"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func setupProcessor(t *testing.T) (*Processor, filesystem.DriverContract) {
+	m, err := filesystem.New(filesystem.Config{}, nil)
+	if err != nil {
+		t.Fatalf("filesystem.New() FAILED. Unexpected error \"%s\"", err)
+	}
+	src := filesystem.NewDriverMemory()
+	m.Extend("images", src)
+	m.Extend("variants", filesystem.NewDiskPublic(t.TempDir(), "/", "http://localhost:3000/storage", filesystem.VisibilityPublic, nil))
+
+	presets, err := ParsePresets("thumbnail:100x100:cover:jpeg:80 medium:200x0")
+	if err != nil {
+		t.Fatalf("ParsePresets() FAILED. Unexpected error \"%s\"", err)
+	}
+	p, err := newProcessor(Config{SourceDisk: "images", Disk: "variants", Dir: "thumbs", Presets: presets}, m)
+	if err != nil {
+		t.Fatalf("newProcessor() FAILED. Unexpected error \"%s\"", err)
+	}
+	return p, src
+}
+
+// withOrientation inserts an EXIF segment holding the orientation tag after the SOI marker
+func withOrientation(b []byte, orientation uint16) []byte {
+	tiff := new(bytes.Buffer)
+	tiff.WriteString("II")
+	for _, v := range []interface{}{uint16(42), uint32(8), uint16(1), uint16(0x0112), uint16(3), uint32(1), orientation, uint16(0), uint32(0)} {
+		_ = binary.Write(tiff, binary.LittleEndian, v)
+	}
+
+	segment := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(segment)+2))
+
+	out := append([]byte{}, b[:2]...)
+	out = append(out, app1...)
+	out = append(out, segment...)
+	return append(out, b[2:]...)
+}
+
+func TestParsePresets(t *testing.T) {
+	presets, err := ParsePresets("thumbnail:100x100:cover:jpg:80 medium:200x0")
+	expect := Preset{Width: 100, Height: 100, Fit: FitCover, Format: FormatJpeg, Quality: 80}
+	if err == nil && presets["thumbnail"] == expect && presets["medium"] == (Preset{Width: 200}) {
+		t.Logf("ParsePresets() PASS. Got %+v\n", presets)
+	} else {
+		t.Errorf("ParsePresets() FAILED. Expected %+v, got %+v, %v\n", expect, presets, err)
+	}
+
+	for _, s := range []string{"thumbnail", "thumbnail:100", "thumbnail:100x100:zoom", "thumbnail:100x0:cover", "thumbnail:100x100:contain:webp"} {
+		if _, err = ParsePresets(s); err != nil {
+			t.Logf("ParsePresets(\"%s\") PASS. Expected error, got \"%s\"\n", s, err)
+		} else {
+			t.Errorf("ParsePresets(\"%s\") FAILED. Expected error, got nil\n", s)
+		}
+	}
+
+	if _, err = ParsePresets("a:1x1:contain:webp"); errors.Is(err, ErrFormatNotSupported) {
+		t.Logf("ParsePresets() PASS. Expected ErrFormatNotSupported for webp output\n")
+	} else {
+		t.Errorf("ParsePresets() FAILED. Expected ErrFormatNotSupported for webp output, got %v\n", err)
+	}
+}
+
+func TestDecodeOrientation(t *testing.T) {
+	buf := new(bytes.Buffer)
+	_ = jpeg.Encode(buf, setupImage(30, 20), nil)
+
+	img, format, err := Decode(withOrientation(buf.Bytes(), 6), 0)
+	if err == nil && format == FormatJpeg && img.Bounds().Dx() == 20 && img.Bounds().Dy() == 30 {
+		t.Logf("Decode() PASS. Expected upright 20x30 jpeg, got %v\n", img.Bounds())
+	} else {
+		t.Errorf("Decode() FAILED. Expected upright 20x30 jpeg, got %v, \"%s\", %v\n", img, format, err)
+	}
+
+	if _, _, err = Decode(buf.Bytes(), 100); err != nil {
+		t.Logf("Decode() PASS. Expected error above max pixels, got \"%s\"\n", err)
+	} else {
+		t.Errorf("Decode() FAILED. Expected error above max pixels, got nil\n")
+	}
+}
+
+func TestProcess(t *testing.T) {
+	p, src := setupProcessor(t)
+
+	buf := new(bytes.Buffer)
+	_ = jpeg.Encode(buf, setupImage(400, 200), nil)
+	_ = src.Put("categories/a.jpeg", withOrientation(buf.Bytes(), 1))
+
+	variants, err := p.Process("categories/a.jpeg")
+	if err != nil || len(variants) != 2 {
+		t.Fatalf("Process() FAILED. Expected 2 variants, got %v, %v", variants, err)
+	}
+
+	for _, v := range variants {
+		switch v.Preset {
+		case "medium":
+			if v.Path == "thumbs/medium/categories/a.jpg" && v.Width == 200 && v.Height == 100 && v.Url == "http://localhost:3000/storage/thumbs/medium/categories/a.jpg" {
+				t.Logf("Process() medium PASS. Got %+v\n", v)
+			} else {
+				t.Errorf("Process() medium FAILED. Got %+v\n", v)
+			}
+		case "thumbnail":
+			if v.Width == 100 && v.Height == 100 && v.Mime == "image/jpeg" {
+				t.Logf("Process() thumbnail PASS. Got %+v\n", v)
+			} else {
+				t.Errorf("Process() thumbnail FAILED. Got %+v\n", v)
+			}
+		}
+	}
+
+	if u, err := p.Url("categories/a.jpeg", "thumbnail"); err == nil && u == variants[1].Url {
+		t.Logf("Url() PASS. Got \"%s\"\n", u)
+	} else {
+		t.Errorf("Url() FAILED. Expected \"%s\", got \"%s\", %v\n", variants[1].Url, u, err)
+	}
+
+	if _, err = p.Process("categories/a.jpeg", "missing"); errors.Is(err, ErrPresetNotFound) {
+		t.Logf("Process() PASS. Expected ErrPresetNotFound, got \"%s\"\n", err)
+	} else {
+		t.Errorf("Process() FAILED. Expected ErrPresetNotFound, got %v\n", err)
+	}
+}
+
+func TestProcessStripsMetadata(t *testing.T) {
+	p, src := setupProcessor(t)
+
+	buf := new(bytes.Buffer)
+	_ = png.Encode(buf, setupImage(10, 10))
+	b := buf.Bytes()
+	// a tEXt chunk before IEND, the encoder never writes it back
+	chunk := append([]byte{0, 0, 0, 7}, "tEXtgps\x001,2"...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+	b = append(append(append([]byte{}, b[:len(b)-12]...), chunk...), b[len(b)-12:]...)
+	_ = src.Put("a.png", b)
+
+	variants, err := p.Process("a.png", "medium")
+	if err != nil {
+		t.Fatalf("Process() FAILED. Unexpected error \"%s\"", err)
+	}
+	d, _ := p.m.Disk("variants")
+	content, _ := d.Get(variants[0].Path)
+	if !bytes.Contains(content, []byte("gps")) && variants[0].Mime == "image/png" {
+		t.Logf("Process() PASS. Expected metadata to be stripped\n")
+	} else {
+		t.Errorf("Process() FAILED. Expected metadata to be stripped, got %+v\n", variants[0])
+	}
+}
@@ -0,0 +1,132 @@
+package image
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Fit string
+
+const (
+	// FitContain scales the image to fit inside the box keeping its ratio
+	FitContain Fit = "contain"
+	// FitCover scales the image to cover the box keeping its ratio and crops the overflow around the center
+	FitCover Fit = "cover"
+	// FitFill stretches the image to the box
+	FitFill Fit = "fill"
+)
+
+const (
+	FormatJpeg = "jpeg"
+	FormatPng  = "png"
+	FormatGif  = "gif"
+	// FormatWebp can be decoded only, there is no pure Go encoder
+	FormatWebp = "webp"
+)
+
+const defaultQuality = 85
+
+var ErrFormatNotSupported = errors.New("image format is not supported")
+
+// Preset is a named variant, a zero Width or Height follows the ratio of the source and
+// both zero keep the source size, which still converts the format and strips metadata
+type Preset struct {
+	Width  int
+	Height int
+	Fit    Fit
+	// Format of the variant, empty keeps the format of the source when it can be encoded
+	Format string
+	// Quality of jpeg variants from 1 to 100
+	Quality int
+	// Upscale allows variants larger than the source
+	Upscale bool
+}
+
+func (p Preset) validate() error {
+	if p.Width < 0 || p.Height < 0 {
+		return fmt.Errorf("image preset size %dx%d is invalid", p.Width, p.Height)
+	}
+	switch p.Fit {
+	case "", FitContain, FitCover, FitFill:
+	default:
+		return fmt.Errorf("image preset fit \"%s\" is invalid", p.Fit)
+	}
+	if (p.Fit == FitCover || p.Fit == FitFill) && (p.Width == 0 || p.Height == 0) {
+		return fmt.Errorf("image preset fit \"%s\" needs a width and a height", p.Fit)
+	}
+	switch p.Format {
+	case "", FormatJpeg, FormatPng, FormatGif:
+	default:
+		return fmt.Errorf("%w: cannot encode \"%s\"", ErrFormatNotSupported, p.Format)
+	}
+	if p.Quality < 0 || p.Quality > 100 {
+		return fmt.Errorf("image preset quality %d is invalid", p.Quality)
+	}
+	return nil
+}
+
+func (p Preset) fit() Fit {
+	if p.Fit == "" {
+		return FitContain
+	}
+	return p.Fit
+}
+
+func (p Preset) quality() int {
+	if p.Quality == 0 {
+		return defaultQuality
+	}
+	return p.Quality
+}
+
+// ParsePresets parses presets written as "name:WIDTHxHEIGHT[:fit[:format[:quality]]]"
+// separated by whitespace, e.g. "thumbnail:200x200:cover:jpeg:80 medium:800x0"
+func ParsePresets(s string) (map[string]Preset, error) {
+	presets := make(map[string]Preset)
+	for _, v := range strings.Fields(s) {
+		parts := strings.Split(v, ":")
+		if len(parts) < 2 || len(parts) > 5 || parts[0] == "" {
+			return nil, fmt.Errorf("image preset \"%s\" is invalid", v)
+		}
+
+		w, h, found := strings.Cut(parts[1], "x")
+		if !found {
+			return nil, fmt.Errorf("image preset \"%s\" has an invalid size", v)
+		}
+		var p Preset
+		var err error
+		if p.Width, err = strconv.Atoi(w); err != nil {
+			return nil, fmt.Errorf("image preset \"%s\" has an invalid width", v)
+		}
+		if p.Height, err = strconv.Atoi(h); err != nil {
+			return nil, fmt.Errorf("image preset \"%s\" has an invalid height", v)
+		}
+		if len(parts) > 2 {
+			p.Fit = Fit(parts[2])
+		}
+		if len(parts) > 3 {
+			p.Format = normalizeFormat(parts[3])
+		}
+		if len(parts) > 4 {
+			if p.Quality, err = strconv.Atoi(parts[4]); err != nil {
+				return nil, fmt.Errorf("image preset \"%s\" has an invalid quality", v)
+			}
+		}
+
+		if err = p.validate(); err != nil {
+			return nil, err
+		}
+		presets[parts[0]] = p
+	}
+	return presets, nil
+}
+
+func normalizeFormat(f string) string {
+	f = strings.ToLower(strings.TrimPrefix(f, "."))
+	if f == "jpg" {
+		return FormatJpeg
+	}
+	return f
+}
@@ -0,0 +1,110 @@
+package image
+
+import (
+	xDraw "golang.org/x/image/draw"
+	goImage "image"
+	"image/draw"
+)
+
+// Transform resizes img following the preset
+func Transform(img goImage.Image, p Preset) goImage.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w == 0 || h == 0 || (p.Width == 0 && p.Height == 0) {
+		return img
+	}
+
+	switch p.fit() {
+	case FitFill:
+		return Resize(img, p.Width, p.Height)
+	case FitCover:
+		// scale so the image covers the box, then crop the overflow around the center
+		scale := max(float64(p.Width)/float64(w), float64(p.Height)/float64(h))
+		if scale > 1 && !p.Upscale {
+			return Crop(img, centered(w, h, min(w, p.Width), min(h, p.Height)))
+		}
+		sw, sh := max(round(float64(w)*scale), p.Width), max(round(float64(h)*scale), p.Height)
+		return Crop(Resize(img, sw, sh), centered(sw, sh, p.Width, p.Height))
+	default:
+		scale := 0.0
+		switch {
+		case p.Width == 0:
+			scale = float64(p.Height) / float64(h)
+		case p.Height == 0:
+			scale = float64(p.Width) / float64(w)
+		default:
+			scale = min(float64(p.Width)/float64(w), float64(p.Height)/float64(h))
+		}
+		if scale > 1 && !p.Upscale {
+			return img
+		}
+		return Resize(img, max(round(float64(w)*scale), 1), max(round(float64(h)*scale), 1))
+	}
+}
+
+// Resize scales img to width x height with Catmull-Rom resampling
+func Resize(img goImage.Image, width, height int) goImage.Image {
+	dst := goImage.NewNRGBA(goImage.Rect(0, 0, width, height))
+	xDraw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return dst
+}
+
+// Crop returns the part of img inside r, r is relative to the top left corner of img
+func Crop(img goImage.Image, r goImage.Rectangle) goImage.Image {
+	b := img.Bounds()
+	r = r.Add(b.Min).Intersect(b)
+	dst := goImage.NewNRGBA(goImage.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, r.Min, draw.Src)
+	return dst
+}
+
+// orient returns img as it should be displayed for an EXIF orientation (1 to 8)
+func orient(img goImage.Image, orientation int) goImage.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	b := img.Bounds()
+	src := goImage.NewNRGBA(goImage.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := goImage.NewNRGBA(goImage.Rect(0, 0, dw, dh))
+
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[src.PixOffset(sx, sy):src.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst
+}
+
+func centered(w, h, cw, ch int) goImage.Rectangle {
+	x, y := (w-cw)/2, (h-ch)/2
+	return goImage.Rect(x, y, x+cw, y+ch)
+}
+
+func round(f float64) int {
+	return int(f + 0.5)
+}
@@ -0,0 +1,76 @@
+package image
+
+import (
+	goImage "image"
+	"image/color"
+	"testing"
+)
+
+func setupImage(w, h int) goImage.Image {
+	img := goImage.NewNRGBA(goImage.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), A: 255})
+		}
+	}
+	return img
+}
+
+func TestTransform(t *testing.T) {
+	img := setupImage(400, 200)
+
+	for name, tc := range map[string]struct {
+		preset Preset
+		w, h   int
+	}{
+		"contain":          {preset: Preset{Width: 100, Height: 100}, w: 100, h: 50},
+		"contain width":    {preset: Preset{Width: 200}, w: 200, h: 100},
+		"contain height":   {preset: Preset{Height: 50}, w: 100, h: 50},
+		"contain no scale": {preset: Preset{Width: 800, Height: 800}, w: 400, h: 200},
+		"contain upscale":  {preset: Preset{Width: 800, Height: 800, Upscale: true}, w: 800, h: 400},
+		"cover":            {preset: Preset{Width: 100, Height: 100, Fit: FitCover}, w: 100, h: 100},
+		"cover no scale":   {preset: Preset{Width: 300, Height: 300, Fit: FitCover}, w: 300, h: 200},
+		"fill":             {preset: Preset{Width: 50, Height: 70, Fit: FitFill}, w: 50, h: 70},
+		"original":         {preset: Preset{}, w: 400, h: 200},
+	} {
+		b := Transform(img, tc.preset).Bounds()
+		if b.Dx() == tc.w && b.Dy() == tc.h {
+			t.Logf("Transform() %s PASS. Expected %dx%d, got %dx%d\n", name, tc.w, tc.h, b.Dx(), b.Dy())
+		} else {
+			t.Errorf("Transform() %s FAILED. Expected %dx%d, got %dx%d\n", name, tc.w, tc.h, b.Dx(), b.Dy())
+		}
+	}
+}
+
+func TestCrop(t *testing.T) {
+	img := setupImage(10, 10)
+	cropped := Crop(img, goImage.Rect(2, 3, 6, 8))
+
+	r, g, _, _ := cropped.At(0, 0).RGBA()
+	if cropped.Bounds().Dx() == 4 && cropped.Bounds().Dy() == 5 && r>>8 == 2 && g>>8 == 3 {
+		t.Logf("Crop() PASS. Expected 4x5 starting at (2, 3), got %v\n", cropped.Bounds())
+	} else {
+		t.Errorf("Crop() FAILED. Expected 4x5 starting at (2, 3), got %v with %d, %d\n", cropped.Bounds(), r>>8, g>>8)
+	}
+}
+
+func TestOrient(t *testing.T) {
+	img := setupImage(3, 2)
+
+	// orientation 6 is displayed rotated 90 degrees clockwise, the bottom left pixel lands top left
+	rotated := orient(img, 6)
+	r, g, _, _ := rotated.At(0, 0).RGBA()
+	if rotated.Bounds().Dx() == 2 && rotated.Bounds().Dy() == 3 && r>>8 == 0 && g>>8 == 1 {
+		t.Logf("orient(6) PASS. Expected 2x3 with (0, 1) top left, got %v\n", rotated.Bounds())
+	} else {
+		t.Errorf("orient(6) FAILED. Expected 2x3 with (0, 1) top left, got %v with (%d, %d)\n", rotated.Bounds(), r>>8, g>>8)
+	}
+
+	flipped := orient(img, 2)
+	r, _, _, _ = flipped.At(0, 0).RGBA()
+	if r>>8 == 2 {
+		t.Logf("orient(2) PASS. Expected mirrored image\n")
+	} else {
+		t.Errorf("orient(2) FAILED. Expected mirrored image, got %d top left\n", r>>8)
+	}
+}
@@ -3,106 +3,66 @@ package log
 import (
 	"fmt"
 	"github.com/sirupsen/logrus"
-	"log"
+	"regexp"
 	"time"
 )
 
+// dailyDriver writes to one file per day, switching file on the first write of a new day
 type dailyDriver struct {
-	l *logrus.Logger
-	c DailyConfig
+	*rotatingFile
+	c   DailyConfig
+	dir string
 }
 
 type DailyConfig struct {
-	FileName string
-	Level    string
+	FileName  string
+	Level     string
+	Formatter string
+	// MaxAge removes files older than the duration
+	MaxAge time.Duration
+	// MaxFiles keeps at most this number of old files
+	MaxFiles int
+	// MaxSize rotates the current file once it grows past this size in megabytes
+	MaxSize  int
+	Compress bool
 }
 
-func (d dailyDriver) Debug(args ...interface{}) {
-	d.getLog().Debug(args)
-}
-
-func (d dailyDriver) Info(args ...interface{}) {
-	d.getLog().Info(args)
-}
-
-func (d dailyDriver) Warn(args ...interface{}) {
-	d.getLog().Warn(args)
-}
-
-func (d dailyDriver) Error(args ...interface{}) {
-	d.getLog().Error(args)
-}
-
-func (d dailyDriver) Fatal(args ...interface{}) {
-	d.getLog().Fatal("fatal", args)
-}
-
-func (d dailyDriver) Fatalf(format string, args ...interface{}) {
-	d.getLog().Fatalf(format, args)
-}
-
-func (d dailyDriver) getLog() *logrus.Entry {
-	d.prepareLogFile()
-	return d.l.WithField("file", getCalledFile(3))
-}
-
-func (d dailyDriver) prepareLogFile() {
-	if file == nil {
-		f, err := createLogFile(d.getLogFilePath())
-		if err != nil {
-			log.Fatalf("Log error: cannot create new log file %s", err)
-			return
-		}
-		file = f
-	} else {
-		if file.Name() == d.getLogFileName() {
-			return
-		}
-
-		f, err := createLogFile(d.getLogFilePath())
-		if err != nil {
-			log.Fatalf("Log error: cannot create new log file %s", err)
-			return
-		}
-
-		if file != nil {
-			err = file.Close()
-			if err != nil {
-				log.Fatalf("Log error: cannot close old log file %s", err)
-			}
-		}
-		file = f
-	}
-	d.l.SetOutput(file)
-}
-
-func (d dailyDriver) getLogFilePath() string {
+func (d *dailyDriver) getLogFilePath() string {
 	return fmt.Sprintf(
 		"%s/%s",
-		getLogsDir(),
+		d.dir,
 		d.getLogFileName(),
 	)
 }
 
-func (d dailyDriver) getLogFileName() string {
-	fileName := d.c.FileName
-	if fileName == "" {
-		fileName = "app.log"
-	}
+func (d *dailyDriver) getLogFileName() string {
 	return fmt.Sprintf(
 		"%s-%s.log",
-		normalizedFilename(fileName),
+		normalizedFilename(d.c.FileName),
 		time.Now().Format("2006-01-02"),
 	)
 }
 
-func newDailyDriver(l *logrus.Logger, c DailyConfig) (Contract, error) {
-	level := c.Level
-	if level != "" {
-		l.SetLevel(getLogLevel(level))
+func newDailyWriter(dir string, c DailyConfig) *dailyDriver {
+	if c.FileName == "" {
+		c.FileName = defaultLogFileName
+	}
+	d := &dailyDriver{
+		c:   c,
+		dir: getLogsDir(dir),
 	}
-	return &dailyDriver{
-		l: l,
-		c: c,
-	}, nil
+	// only the files named by getLogFilePath and their backups are pruned
+	pattern := filePattern(regexp.QuoteMeta(normalizedFilename(c.FileName)) + `-\d{4}-\d{2}-\d{2}`)
+	d.rotatingFile = newRotatingFile(d.dir, pattern, retention{
+		maxAge:   c.MaxAge,
+		maxFiles: c.MaxFiles,
+		maxSize:  int64(c.MaxSize) * megabyte,
+		compress: c.Compress,
+	}, d.getLogFilePath)
+	return d
+}
+
+func newDailyDriver(l *logrus.Logger, dir string, c DailyConfig) (Contract, error) {
+	configureLogrus(l, newDailyWriter(dir, c), c.Level, c.Formatter)
+	return newLogger(l), nil
 }
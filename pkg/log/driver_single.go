@@ -3,79 +3,57 @@ package log
 import (
 	"fmt"
 	"github.com/sirupsen/logrus"
-	"log"
+	"regexp"
+	"time"
 )
 
+// singleDriver appends every entry to the same file
 type singleDriver struct {
-	l *logrus.Logger
-	c SingeConfig
+	*rotatingFile
+	c   SingeConfig
+	dir string
 }
 
 type SingeConfig struct {
-	FileName string
-	Level    string
-}
-
-func (d singleDriver) Debug(args ...interface{}) {
-	d.getLog().Debug(args)
-}
-
-func (d singleDriver) Info(args ...interface{}) {
-	d.getLog().Info(args)
-}
-
-func (d singleDriver) Warn(args ...interface{}) {
-	d.getLog().Warn(args)
-}
-
-func (d singleDriver) Error(args ...interface{}) {
-	d.getLog().Error(args)
-}
-
-func (d singleDriver) Fatal(args ...interface{}) {
-	d.getLog().Fatal("fatal", args)
-}
-
-func (d singleDriver) Fatalf(format string, args ...interface{}) {
-	d.getLog().Fatalf(format, args)
-}
-func (d singleDriver) getLog() *logrus.Entry {
-	d.prepareLogFile()
-	return d.l.WithField("file", getCalledFile(3))
-}
-
-func (d singleDriver) prepareLogFile() {
-	if file == nil {
-		f, err := createLogFile(d.getLogFilePath())
-		if err != nil {
-			log.Fatalf("Log error: cannot create new log file %s", err)
-			return
-		}
-		file = f
-		d.l.SetOutput(file)
-	}
-}
-
-func (d singleDriver) getLogFilePath() string {
-	fileName := d.c.FileName
-	if fileName == "" {
-		fileName = "app.log"
-	}
-
+	FileName  string
+	Level     string
+	Formatter string
+	// MaxAge removes rotated files older than the duration
+	MaxAge time.Duration
+	// MaxFiles keeps at most this number of rotated files
+	MaxFiles int
+	// MaxSize rotates the file once it grows past this size in megabytes
+	MaxSize  int
+	Compress bool
+}
+
+func (d *singleDriver) getLogFilePath() string {
 	return fmt.Sprintf(
-		"%s/%s",
-		getLogsDir(),
-		normalizedFilename(fileName),
+		"%s/%s.log",
+		d.dir,
+		normalizedFilename(d.c.FileName),
 	)
 }
 
-func newSingleDriver(l *logrus.Logger, c SingeConfig) (Contract, error) {
-	level := c.Level
-	if level != "" {
-		l.SetLevel(getLogLevel(level))
+func newSingleWriter(dir string, c SingeConfig) *singleDriver {
+	if c.FileName == "" {
+		c.FileName = defaultLogFileName
+	}
+	d := &singleDriver{
+		c:   c,
+		dir: getLogsDir(dir),
 	}
-	return &singleDriver{
-		l: l,
-		c: c,
-	}, nil
+	pattern := filePattern(regexp.QuoteMeta(normalizedFilename(c.FileName)))
+	d.rotatingFile = newRotatingFile(d.dir, pattern, retention{
+		maxAge:   c.MaxAge,
+		maxFiles: c.MaxFiles,
+		maxSize:  int64(c.MaxSize) * megabyte,
+		compress: c.Compress,
+	}, d.getLogFilePath)
+	return d
+}
+
+func newSingleDriver(l *logrus.Logger, dir string, c SingeConfig) (Contract, error) {
+	configureLogrus(l, newSingleWriter(dir, c), c.Level, c.Formatter)
+	return newLogger(l), nil
 }
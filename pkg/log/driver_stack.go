@@ -0,0 +1,88 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"strings"
+)
+
+type StackConfig struct {
+	Channels []string
+}
+
+// channelHook writes entries to one channel of a stack with its own level and formatter
+type channelHook struct {
+	w         io.Writer
+	level     logrus.Level
+	formatter logrus.Formatter
+}
+
+func (h *channelHook) Fire(entry *logrus.Entry) error {
+	if entry.Level > levels.channelLevel(h.level) {
+		return nil
+	}
+	b, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	_, err = h.w.Write(b)
+	return err
+}
+
+// Levels returns every level, logrus caches them when the hook is added so
+// filtering happens in Fire where runtime level changes are visible
+func (h *channelHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func resolveChannelHook(name string, c Config) (*channelHook, error) {
+	switch strings.TrimSpace(name) {
+	case ChannelDaily:
+		return &channelHook{
+			w:         newDailyWriter(c.Path, c.Daily),
+			level:     getLogLevel(c.Daily.Level),
+			formatter: getFormatter(c.Daily.Formatter),
+		}, nil
+	case ChannelSingle:
+		return &channelHook{
+			w:         newSingleWriter(c.Path, c.Singe),
+			level:     getLogLevel(c.Singe.Level),
+			formatter: getFormatter(c.Singe.Formatter),
+		}, nil
+	case ChannelStdout:
+		return &channelHook{
+			w:         os.Stdout,
+			level:     getLogLevel(c.StdOut.Level),
+			formatter: getFormatter(c.StdOut.Formatter),
+		}, nil
+	default:
+		return nil, fmt.Errorf("log stack channel \"%s\" is invalid", name)
+	}
+}
+
+// newStackDriver fans every entry out to the configured channels, the logger
+// itself discards output and runs at the most verbose level of its channels
+func newStackDriver(l *logrus.Logger, c Config) (Contract, error) {
+	if len(c.Stack.Channels) == 0 {
+		return nil, errors.New("log stack has no channels")
+	}
+
+	level := logrus.PanicLevel
+	for _, name := range c.Stack.Channels {
+		h, err := resolveChannelHook(name, c)
+		if err != nil {
+			return nil, err
+		}
+		if h.level > level {
+			level = h.level
+		}
+		l.AddHook(h)
+	}
+
+	l.SetOutput(io.Discard)
+	l.SetLevel(level)
+	return newLogger(l), nil
+}
@@ -0,0 +1,45 @@
+package log
+
+import (
+	"bytes"
+	"github.com/sirupsen/logrus"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestStackLevels(t *testing.T) {
+	jsonOut := &bytes.Buffer{}
+	textOut := &bytes.Buffer{}
+
+	l := newLogrus(logrus.InfoLevel)
+	l.SetOutput(io.Discard)
+	l.SetLevel(logrus.DebugLevel)
+	l.AddHook(&channelHook{w: jsonOut, level: logrus.ErrorLevel, formatter: getFormatter(FormatterJSON)})
+	l.AddHook(&channelHook{w: textOut, level: logrus.DebugLevel, formatter: getFormatter(FormatterText)})
+	lg := newLogger(l)
+
+	lg.Debug("debug entry")
+	lg.Error("error entry")
+
+	if !strings.Contains(jsonOut.String(), "debug entry") && strings.Contains(jsonOut.String(), "\"msg\":\"error entry\"") {
+		t.Logf("stack json channel PASS. Got \"%s\"", jsonOut.String())
+	} else {
+		t.Errorf("stack json channel FAILED. Expected only error entry as json, got \"%s\"", jsonOut.String())
+	}
+
+	if strings.Contains(textOut.String(), "msg=\"debug entry\"") && strings.Contains(textOut.String(), "msg=\"error entry\"") {
+		t.Logf("stack text channel PASS. Got \"%s\"", textOut.String())
+	} else {
+		t.Errorf("stack text channel FAILED. Expected both entries as text, got \"%s\"", textOut.String())
+	}
+}
+
+func TestStackInvalidChannel(t *testing.T) {
+	_, err := newStackDriver(newLogrus(logrus.InfoLevel), Config{Stack: StackConfig{Channels: []string{"stdout", "syslog"}}})
+	if err != nil {
+		t.Logf("newStackDriver() PASS. Expected error, got \"%s\"", err)
+	} else {
+		t.Errorf("newStackDriver() FAILED. Expected error for unknown channel, got nil")
+	}
+}
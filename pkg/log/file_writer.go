@@ -0,0 +1,270 @@
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	megabyte           = 1024 * 1024
+	backupTimeFormat   = "20060102T150405.000"
+	compressedFileExt  = ".gz"
+	defaultLogFileName = "app.log"
+	// backupPattern matches the optional rotation timestamp, extension and compression
+	// following the name of a log file, see rotatingFile.rotate
+	backupPattern = `(\.\d{8}T\d{6}\.\d{3})?\.log(\.gz)?`
+)
+
+var filesMu sync.Mutex
+
+// retention controls how rotated files are kept on disk, zero values disable the rule
+type retention struct {
+	maxAge   time.Duration
+	maxFiles int
+	maxSize  int64
+	compress bool
+}
+
+// rotatingFile is a concurrency safe writer that reopens the file whenever the
+// active path changes (e.g. a new day), rotates it once it grows past maxSize and
+// compresses / prunes old files in the background
+type rotatingFile struct {
+	dir        string
+	pattern    *regexp.Regexp
+	activePath func() string
+	r          retention
+
+	mu      sync.Mutex
+	file    *os.File
+	size    int64
+	bg      sync.Mutex
+	pending sync.WaitGroup
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := f.activePath()
+	if f.file == nil || f.file.Name() != path {
+		previous := f.file
+		if err := f.open(path); err != nil {
+			return 0, err
+		}
+		if previous != nil {
+			_ = previous.Close()
+			f.background(previous.Name())
+		}
+	}
+
+	if f.r.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.r.maxSize {
+		if err := f.rotate(path); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close closes the active file and waits for pending compression and pruning
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.mu.Unlock()
+
+	f.pending.Wait()
+	return err
+}
+
+func (f *rotatingFile) open(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("log error: cannot create log directory %w", err)
+	}
+
+	file, err := createLogFile(path)
+	if err != nil {
+		return fmt.Errorf("log error: cannot create new log file %w", err)
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = stat.Size()
+	return nil
+}
+
+func (f *rotatingFile) rotate(path string) error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	backup := fmt.Sprintf(
+		"%s.%s%s",
+		strings.TrimSuffix(path, filepath.Ext(path)),
+		time.Now().Format(backupTimeFormat),
+		filepath.Ext(path),
+	)
+	if err := os.Rename(path, backup); err != nil {
+		return fmt.Errorf("log error: cannot rotate log file %w", err)
+	}
+
+	if err := f.open(path); err != nil {
+		return err
+	}
+	f.background(backup)
+	return nil
+}
+
+func (f *rotatingFile) background(path string) {
+	f.pending.Add(1)
+	go func() {
+		defer f.pending.Done()
+		f.finish(path)
+	}()
+}
+
+// finish compresses a file that is no longer written to and applies retention
+func (f *rotatingFile) finish(path string) {
+	f.bg.Lock()
+	defer f.bg.Unlock()
+
+	if f.r.compress {
+		if err := compressFile(path); err != nil {
+			log.Println("Log error: cannot compress log file", err)
+		}
+	}
+	f.prune()
+}
+
+func (f *rotatingFile) prune() {
+	if f.r.maxAge <= 0 && f.r.maxFiles <= 0 {
+		return
+	}
+
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		log.Println("Log error: cannot read log directory", err)
+		return
+	}
+
+	type oldFile struct {
+		path    string
+		modTime time.Time
+	}
+
+	active := openFiles()
+	files := make([]oldFile, 0)
+	for _, e := range entries {
+		path := filepath.Join(f.dir, e.Name())
+		if e.IsDir() || active[path] || !f.owns(e.Name()) {
+			continue
+		}
+		info, errInfo := e.Info()
+		if errInfo != nil {
+			continue
+		}
+		files = append(files, oldFile{path: path, modTime: info.ModTime()})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.After(files[j].modTime)
+	})
+
+	for i, file := range files {
+		expired := f.r.maxAge > 0 && time.Since(file.modTime) > f.r.maxAge
+		exceeded := f.r.maxFiles > 0 && i >= f.r.maxFiles
+		if expired || exceeded {
+			if errRemove := os.Remove(file.path); errRemove != nil {
+				log.Println("Log error: cannot remove old log file", errRemove)
+			}
+		}
+	}
+}
+
+// owns reports whether a file name belongs to this writer (current, dated or rotated files)
+func (f *rotatingFile) owns(name string) bool {
+	return f.pattern.MatchString(name)
+}
+
+// openFiles returns the active file of every writer, another writer sharing the
+// directory must never lose the file it writes to
+func openFiles() map[string]bool {
+	filesMu.Lock()
+	writers := append([]*rotatingFile(nil), files...)
+	filesMu.Unlock()
+
+	active := make(map[string]bool, len(writers))
+	for _, w := range writers {
+		w.mu.Lock()
+		if w.file != nil {
+			active[w.file.Name()] = true
+		}
+		w.mu.Unlock()
+	}
+	return active
+}
+
+// filePattern matches the files of a writer whose names start with prefix, a regular expression
+func filePattern(prefix string) *regexp.Regexp {
+	return regexp.MustCompile("^" + prefix + backupPattern + "$")
+}
+
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+compressedFileExt, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+func newRotatingFile(dir string, pattern *regexp.Regexp, r retention, activePath func() string) *rotatingFile {
+	f := &rotatingFile{
+		dir:        dir,
+		pattern:    pattern,
+		activePath: activePath,
+		r:          r,
+	}
+	filesMu.Lock()
+	files = append(files, f)
+	filesMu.Unlock()
+	return f
+}
@@ -0,0 +1,109 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func countFiles(t *testing.T, dir, suffix string) int {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := 0
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), suffix) {
+			c++
+		}
+	}
+	return c
+}
+
+func TestRotateBySize(t *testing.T) {
+	dir := t.TempDir()
+	w := newSingleWriter(dir, SingeConfig{FileName: "app.log", MaxSize: 1, Compress: true, MaxFiles: 2})
+	w.r.maxSize = 10
+
+	for i := 0; i < 4; i++ {
+		if _, err := w.Write([]byte("0123456789")); err != nil {
+			t.Fatalf("Write() FAILED. Expected error nil, got error \"%s\"", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// wait for background compression and pruning
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "app.log")); err == nil {
+		t.Logf("rotate PASS. Active file exists")
+	} else {
+		t.Errorf("rotate FAILED. Expected active file app.log, got error \"%s\"", err)
+	}
+
+	compressed := countFiles(t, dir, ".log.gz")
+	if compressed == 2 {
+		t.Logf("rotate PASS. Expected 2 compressed backups, got %d", compressed)
+	} else {
+		t.Errorf("rotate FAILED. Expected 2 compressed backups, got %d", compressed)
+	}
+}
+
+func TestOwns(t *testing.T) {
+	// both writers use the default name in the same directory
+	dir := t.TempDir()
+	daily := newDailyWriter(dir, DailyConfig{})
+	single := newSingleWriter(dir, SingeConfig{})
+	cases := map[string][2]bool{
+		"app.log":                                   {false, true},
+		"app.20240101T101010.000.log":               {false, true},
+		"app.20240101T101010.000.log.gz":            {false, true},
+		"app-2024-01-01.log":                        {true, false},
+		"app-2024-01-01.log.gz":                     {true, false},
+		"app-2024-01-01.20240101T101010.000.log.gz": {true, false},
+		"application.log":                           {false, false},
+		"app-2024-01-01.txt":                        {false, false},
+		"app-backup.log":                            {false, false},
+		"other-2024-01-01.log":                      {false, false},
+	}
+	for name, expect := range cases {
+		actual := [2]bool{daily.owns(name), single.owns(name)}
+		if actual == expect {
+			t.Logf("owns(\"%s\") PASS. Expected daily, single %v, got %v", name, expect, actual)
+		} else {
+			t.Errorf("owns(\"%s\") FAILED. Expected daily, single %v, got %v", name, expect, actual)
+		}
+	}
+}
+
+func TestPruneSharedDirectory(t *testing.T) {
+	dir := t.TempDir()
+	daily := newDailyWriter(dir, DailyConfig{MaxFiles: 1})
+	single := newSingleWriter(dir, SingeConfig{MaxFiles: 1})
+	for _, name := range []string{"app-2020-01-01.log", "app-2020-01-02.log", "app.20200101T101010.000.log"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("old"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, _ = daily.Write([]byte("daily"))
+	_, _ = single.Write([]byte("single"))
+
+	daily.prune()
+	single.prune()
+	_ = daily.Close()
+	_ = single.Close()
+
+	_, errSingle := os.Stat(filepath.Join(dir, "app.log"))
+	_, errBackup := os.Stat(filepath.Join(dir, "app.20200101T101010.000.log"))
+	// the active dated file and one backup of the daily writer
+	dated := countFiles(t, dir, ".log") - 2
+	if errSingle == nil && errBackup == nil && dated == 2 {
+		t.Logf("prune PASS. Expected each writer to keep the files of the other")
+	} else {
+		t.Errorf("prune FAILED. Expected app.log and its backup kept with 2 dated files, got %v, %v, %d", errSingle, errBackup, dated)
+	}
+}
@@ -3,6 +3,7 @@ package log
 import (
 	"fmt"
 	"github.com/sirupsen/logrus"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -16,6 +17,25 @@ func newLogrus(level logrus.Level) *logrus.Logger {
 	return logger
 }
 
+func configureLogrus(l *logrus.Logger, w io.Writer, level, format string) {
+	if level != "" {
+		l.SetLevel(getLogLevel(level))
+	}
+	l.SetFormatter(getFormatter(format))
+	l.SetOutput(w)
+}
+
+func getFormatter(f string) logrus.Formatter {
+	switch strings.ToLower(f) {
+	case FormatterText:
+		return &logrus.TextFormatter{
+			FullTimestamp: true,
+		}
+	default:
+		return formatter
+	}
+}
+
 func createLogFile(path string) (*os.File, error) {
 	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0755)
 }
@@ -39,8 +59,12 @@ func getLogLevel(l string) logrus.Level {
 	return level
 }
 
-func getLogsDir() string {
-	return "storage/logs"
+func getLogsDir(dir string) string {
+	dir = strings.TrimRight(dir, "\\/")
+	if dir == "" {
+		return "storage/logs"
+	}
+	return dir
 }
 
 func normalizedFilename(file string) string {
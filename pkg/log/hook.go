@@ -0,0 +1,388 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/kurneo/go-template/pkg/support/requestid"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	hookDefaultQueueSize     = 1024
+	hookDefaultBatchSize     = 10
+	hookDefaultFlushInterval = 5 * time.Second
+	hookDefaultRetryBackoff  = time.Second
+	hookDefaultTimeout       = 10 * time.Second
+	hookDedupMaxKeys         = 1000
+	// hookExitTimeout bounds the delivery of queued entries before a fatal entry exits
+	hookExitTimeout = 5 * time.Second
+)
+
+// HookOptions controls delivery of entries to remote services, shared by every hook
+type HookOptions struct {
+	// BatchSize is the max number of entries sent in one request
+	BatchSize int
+	// FlushInterval is the max time an entry waits in the queue before it is sent
+	FlushInterval time.Duration
+	// RateLimit is the max number of requests per minute, 0 means unlimited
+	RateLimit int
+	// MaxRetries is the number of extra attempts when a request fails
+	MaxRetries int
+	// RetryBackoff is the initial delay between attempts, doubled on each retry
+	RetryBackoff time.Duration
+	// DedupWindow suppresses entries with the same level and message inside the window
+	DedupWindow time.Duration
+	// QueueSize is the number of entries buffered before new ones are dropped
+	QueueSize int
+}
+
+// hookEntry is a copy of a logrus entry detached from the logger so it can be sent asynchronously
+type hookEntry struct {
+	Time     time.Time
+	Level    logrus.Level
+	Message  string
+	Fields   map[string]interface{}
+	Text     string
+	Repeated int
+}
+
+// sender delivers a batch of entries to one remote service
+type sender interface {
+	Send(ctx context.Context, entries []*hookEntry) error
+}
+
+type dedupState struct {
+	since      time.Time
+	suppressed int
+}
+
+// asyncHook queues entries and delivers them in batches through a sender
+type asyncHook struct {
+	sender    sender
+	level     logrus.Level
+	formatter logrus.Formatter
+	o         HookOptions
+	limiter   *rate.Limiter
+
+	queue   chan *hookEntry
+	flush   chan chan struct{}
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+
+	mu      sync.Mutex
+	dedup   map[string]*dedupState
+	dropped int
+}
+
+func (h *asyncHook) Fire(entry *logrus.Entry) error {
+	repeated, ok := h.shouldSend(entry)
+	if !ok {
+		return nil
+	}
+
+	fields := make(map[string]interface{}, len(entry.Data))
+	for k, v := range entry.Data {
+		if err, isErr := v.(error); isErr {
+			v = err.Error()
+		}
+		fields[k] = v
+	}
+	if _, found := fields["request_id"]; !found && entry.Context != nil {
+		if id := requestid.FromContext(entry.Context); id != "" {
+			fields["request_id"] = id
+		}
+	}
+
+	text := entry.Message
+	if buf, err := h.formatter.Format(entry); err == nil {
+		text = string(buf)
+	}
+
+	e := &hookEntry{
+		Time:     entry.Time,
+		Level:    entry.Level,
+		Message:  entry.Message,
+		Fields:   fields,
+		Text:     text,
+		Repeated: repeated,
+	}
+
+	select {
+	case h.queue <- e:
+	default:
+		h.mu.Lock()
+		h.dropped++
+		h.mu.Unlock()
+	}
+	return nil
+}
+
+func (h *asyncHook) Levels() []logrus.Level {
+	var out []logrus.Level
+	for _, level := range logrus.AllLevels {
+		if level <= h.level {
+			out = append(out, level)
+		}
+	}
+	return out
+}
+
+// shouldSend applies deduplication, it returns the number of suppressed
+// duplicates since the last delivered entry with the same level and message
+func (h *asyncHook) shouldSend(entry *logrus.Entry) (int, bool) {
+	if h.o.DedupWindow <= 0 {
+		return 0, true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := entry.Level.String() + "|" + entry.Message
+	now := time.Now()
+	st, found := h.dedup[key]
+	if found && now.Sub(st.since) < h.o.DedupWindow {
+		st.suppressed++
+		return 0, false
+	}
+
+	repeated := 0
+	if found {
+		repeated = st.suppressed
+	}
+
+	if len(h.dedup) >= hookDedupMaxKeys {
+		for k, v := range h.dedup {
+			if now.Sub(v.since) >= h.o.DedupWindow {
+				delete(h.dedup, k)
+			}
+		}
+	}
+	h.dedup[key] = &dedupState{since: now}
+	return repeated, true
+}
+
+func (h *asyncHook) run() {
+	defer close(h.stopped)
+
+	ticker := time.NewTicker(h.o.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*hookEntry, 0, h.o.BatchSize)
+	send := func() {
+		if len(batch) > 0 {
+			h.deliver(batch)
+			batch = make([]*hookEntry, 0, h.o.BatchSize)
+		}
+	}
+	drain := func() {
+		for {
+			select {
+			case e := <-h.queue:
+				batch = append(batch, e)
+				if len(batch) >= h.o.BatchSize {
+					send()
+				}
+			default:
+				send()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case e := <-h.queue:
+			batch = append(batch, e)
+			if len(batch) >= h.o.BatchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case done := <-h.flush:
+			drain()
+			close(done)
+		case <-h.stop:
+			drain()
+			return
+		}
+	}
+}
+
+func (h *asyncHook) deliver(batch []*hookEntry) {
+	backoff := h.o.RetryBackoff
+	var err error
+	for attempt := 0; attempt <= h.o.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		if err = h.limiter.Wait(context.Background()); err != nil {
+			break
+		}
+
+		ctx, cancel := context.WithTimeout(batchContext(batch), hookDefaultTimeout)
+		err = h.sender.Send(ctx, batch)
+		cancel()
+		if err == nil {
+			h.reportDropped()
+			return
+		}
+
+		var perm permanentError
+		if errors.As(err, &perm) {
+			break
+		}
+	}
+	log.Println("Log hook error: cannot deliver entries", stripUrl(err))
+}
+
+// reportDropped logs the number of entries dropped on a full queue since the last report
+func (h *asyncHook) reportDropped() {
+	h.mu.Lock()
+	dropped := h.dropped
+	h.dropped = 0
+	h.mu.Unlock()
+	if dropped > 0 {
+		log.Printf("Log hook error: %d entries dropped, the queue was full", dropped)
+	}
+}
+
+// stripUrl removes the request url from err, it may hold credentials such as the telegram bot token
+func stripUrl(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s request: %w", urlErr.Op, urlErr.Err)
+	}
+	return err
+}
+
+// flushHooks delivers the queued entries before the process exits on a fatal entry
+func flushHooks() {
+	ctx, cancel := context.WithTimeout(context.Background(), hookExitTimeout)
+	defer cancel()
+	for _, h := range hooks {
+		_ = h.Close(ctx)
+	}
+}
+
+// batchContext carries the request id shared by every entry of batch, the http client
+// of the hooks forwards it to the called service
+func batchContext(batch []*hookEntry) context.Context {
+	ctx := context.Background()
+	id, _ := batch[0].Fields["request_id"].(string)
+	for _, e := range batch[1:] {
+		if other, _ := e.Fields["request_id"].(string); other != id {
+			return ctx
+		}
+	}
+	if id == "" {
+		return ctx
+	}
+	return requestid.NewContext(ctx, id)
+}
+
+// Flush blocks until every queued entry has been handed to the sender or ctx is done
+func (h *asyncHook) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	select {
+	case h.flush <- done:
+	case <-h.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close delivers the queued entries and stops the background worker
+func (h *asyncHook) Close(ctx context.Context) error {
+	h.once.Do(func() {
+		close(h.stop)
+	})
+	select {
+	case <-h.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// permanentError marks a failure that must not be retried (e.g. a 4xx response)
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// checkResponse converts a non 2xx response into an error, client errors except 429 are permanent
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	err := errors.New("unexpected response status " + resp.Status)
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return permanentError{err: err}
+	}
+	return err
+}
+
+func newAsyncHook(s sender, level string, o HookOptions) *asyncHook {
+	if o.BatchSize <= 0 {
+		o.BatchSize = hookDefaultBatchSize
+	}
+	if o.FlushInterval <= 0 {
+		o.FlushInterval = hookDefaultFlushInterval
+	}
+	if o.RetryBackoff <= 0 {
+		o.RetryBackoff = hookDefaultRetryBackoff
+	}
+	if o.QueueSize <= 0 {
+		o.QueueSize = hookDefaultQueueSize
+	}
+
+	limit := rate.Inf
+	if o.RateLimit > 0 {
+		limit = rate.Every(time.Minute / time.Duration(o.RateLimit))
+	}
+
+	h := &asyncHook{
+		sender:    s,
+		level:     getLogLevel(level),
+		formatter: formatter,
+		o:         o,
+		limiter:   rate.NewLimiter(limit, 1),
+		queue:     make(chan *hookEntry, o.QueueSize),
+		flush:     make(chan chan struct{}),
+		stop:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+		dedup:     map[string]*dedupState{},
+	}
+	go h.run()
+	return h
+}
+
+func newHookHttpClient() *http.Client {
+	client := requestid.NewHttpClient()
+	client.Timeout = hookDefaultTimeout
+	return client
+}
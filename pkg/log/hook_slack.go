@@ -0,0 +1,76 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// slackSender posts batches to a slack incoming webhook
+type slackSender struct {
+	client    *http.Client
+	url       string
+	channel   string
+	username  string
+	iconEmoji string
+	mentions  []string
+}
+
+func (s *slackSender) Send(ctx context.Context, entries []*hookEntry) error {
+	payload := map[string]interface{}{
+		"text": s.text(entries),
+	}
+	if s.channel != "" {
+		payload["channel"] = s.channel
+	}
+	if s.username != "" {
+		payload["username"] = s.username
+	}
+	if s.iconEmoji != "" {
+		payload["icon_emoji"] = s.iconEmoji
+	}
+	return postJSON(ctx, s.client, s.url, nil, payload)
+}
+
+func (s *slackSender) text(entries []*hookEntry) string {
+	lines := make([]string, 0, len(entries)+1)
+	if len(s.mentions) > 0 {
+		lines = append(lines, strings.Join(s.mentions, " "))
+	}
+	for _, e := range entries {
+		line := fmt.Sprintf("*[%s]* %s", strings.ToUpper(e.Level.String()), e.Message)
+		if e.Repeated > 0 {
+			line += fmt.Sprintf(" _(repeated %d times)_", e.Repeated)
+		}
+		if len(e.Fields) > 0 {
+			if b, err := json.MarshalIndent(e.Fields, "", "  "); err == nil {
+				line += "\n```" + string(b) + "```"
+			}
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+type SlackHookConfig struct {
+	Enable     bool
+	WebhookUrl string
+	Channel    string
+	Username   string
+	IconEmoji  string
+	Level      string
+	Mentions   string
+}
+
+func newSlackHook(c SlackHookConfig, o HookOptions) *asyncHook {
+	return newAsyncHook(&slackSender{
+		client:    newHookHttpClient(),
+		url:       c.WebhookUrl,
+		channel:   c.Channel,
+		username:  c.Username,
+		iconEmoji: c.IconEmoji,
+		mentions:  splitMentions(c.Mentions),
+	}, c.Level, o)
+}
@@ -1,93 +1,84 @@
 package log
 
 import (
+	"context"
 	"fmt"
-	"github.com/sirupsen/logrus"
-	"log"
 	"net/http"
 	"net/url"
 	"strings"
-	"sync"
+	"unicode/utf8"
 )
 
-const telegramEndpoint = "https://api.telegram.org/bot%s/sendMessage?chat_id=%d&text=%s"
+const (
+	telegramEndpoint   = "https://api.telegram.org"
+	telegramMaxMessage = 4000
+)
 
-type telegramClient struct {
+type telegramSender struct {
+	client   *http.Client
+	endpoint string
 	botToken string
 	chatID   string
-	endpoint string
-	queue    chan string
-	cancel   chan struct{}
-}
-
-func (c *telegramClient) SendMsg(text string) {
-	c.queue <- text
-}
-
-func (c *telegramClient) Cancel() {
-	c.cancel <- struct{}{}
+	mentions []string
 }
 
-func (c *telegramClient) background() {
-	for {
-		select {
-		case txt := <-c.queue:
-			query := fmt.Sprintf(c.endpoint, url.QueryEscape(c.botToken), c.chatID, url.QueryEscape(txt))
-			resp, err := http.Get(query)
-			if err != nil {
-				log.Println("Error sending message:", err)
-				continue
-			}
-			if resp.StatusCode != http.StatusOK {
-				log.Println("Response status code:", resp.Status)
-				continue
-			}
-		case <-c.cancel:
-			return
+func (s *telegramSender) Send(ctx context.Context, entries []*hookEntry) error {
+	for _, msg := range s.messages(entries) {
+		form := url.Values{}
+		form.Set("chat_id", s.chatID)
+		form.Set("text", msg)
+
+		req, err := http.NewRequestWithContext(
+			ctx,
+			http.MethodPost,
+			fmt.Sprintf("%s/bot%s/sendMessage", s.endpoint, s.botToken),
+			strings.NewReader(form.Encode()),
+		)
+		if err != nil {
+			return permanentError{err: err}
 		}
-	}
-}
-
-// Hook
-type telegramHook struct {
-	client    *telegramClient
-	level     logrus.Level
-	mention   []string
-	formatter logrus.Formatter
-	mutex     sync.Mutex
-}
-
-func (h *telegramHook) Fire(entry *logrus.Entry) error {
-	h.mutex.Lock()
-	defer h.mutex.Unlock()
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	if len(h.mention) > 0 {
-		time, level, msg := entry.Time, entry.Level, entry.Message
-		entry = entry.WithField("mention", strings.Join(h.mention, ", "))
-		entry.Time, entry.Level, entry.Message = time, level, msg
-	}
-
-	buf, err := h.formatter.Format(entry)
-	if err != nil {
-		return err
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return err
+		}
+		_ = resp.Body.Close()
+		if err = checkResponse(resp); err != nil {
+			return err
+		}
 	}
-
-	h.client.SendMsg(string(buf))
 	return nil
 }
 
-func (h *telegramHook) Levels() []logrus.Level {
-	h.mutex.Lock()
-	defer h.mutex.Unlock()
+// messages joins the batch into as few messages as the telegram size limit allows
+func (s *telegramSender) messages(entries []*hookEntry) []string {
+	header := ""
+	if len(s.mentions) > 0 {
+		header = strings.Join(s.mentions, ", ") + "\n"
+	}
 
-	var out []logrus.Level
-	for _, level := range logrus.AllLevels {
-		if level <= h.level {
-			out = append(out, level)
+	messages := make([]string, 0)
+	current := header
+	for _, e := range entries {
+		text := strings.TrimSpace(e.Text)
+		if e.Repeated > 0 {
+			text = fmt.Sprintf("%s\n(repeated %d times)", text, e.Repeated)
+		}
+		text = truncateUtf8(text, telegramMaxMessage)
+		if current != header && len(current)+len(text)+2 > telegramMaxMessage {
+			messages = append(messages, current)
+			current = header
 		}
+		if current != header {
+			current += "\n\n"
+		}
+		current += text
 	}
-
-	return out
+	if current != header {
+		messages = append(messages, current)
+	}
+	return messages
 }
 
 type TeleHookConfig struct {
@@ -96,25 +87,41 @@ type TeleHookConfig struct {
 	ChatID   string
 	Level    string
 	Mentions string
+	// Endpoint overrides the telegram api url, used by tests
+	Endpoint string
 }
 
-func addTelegramHook(l *logrus.Logger, c TeleHookConfig) {
-	tc := &telegramClient{
+func newTelegramHook(c TeleHookConfig, o HookOptions) *asyncHook {
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = telegramEndpoint
+	}
+	return newAsyncHook(&telegramSender{
+		client:   newHookHttpClient(),
+		endpoint: strings.TrimRight(endpoint, "/"),
 		botToken: c.BotToken,
 		chatID:   c.ChatID,
-		endpoint: telegramEndpoint,
-		queue:    make(chan string, 128),
-		cancel:   make(chan struct{}),
-	}
-
-	go tc.background()
+		mentions: splitMentions(c.Mentions),
+	}, c.Level, o)
+}
 
-	h := &telegramHook{
-		client:    tc,
-		level:     getLogLevel(c.Level),
-		mention:   strings.Split(c.Mentions, ","),
-		formatter: formatter,
+func splitMentions(mentions string) []string {
+	result := make([]string, 0)
+	for _, m := range strings.Split(mentions, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			result = append(result, m)
+		}
 	}
+	return result
+}
 
-	l.AddHook(h)
+// truncateUtf8 cuts s to at most n bytes without splitting a multi-byte character
+func truncateUtf8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
 }
@@ -0,0 +1,268 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/kurneo/go-template/pkg/support/requestid"
+	"github.com/sirupsen/logrus"
+	"io"
+	stdlog "log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	mu       sync.Mutex
+	failures int
+	requests []*http.Request
+	bodies   []string
+}
+
+func (r *recorder) handler(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, _ := io.ReadAll(req.Body)
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, string(b))
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func setupHookLogger(h *asyncHook) *logger {
+	l := newLogrus(logrus.DebugLevel)
+	l.SetOutput(io.Discard)
+	l.AddHook(h)
+	return newLogger(l)
+}
+
+func TestWebhookBatchAndDedup(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(http.HandlerFunc(rec.handler))
+	defer srv.Close()
+
+	h := newWebhookHook(WebhookHookConfig{Url: srv.URL, Level: "error", Headers: map[string]string{"Authorization": "Bearer t"}}, HookOptions{
+		BatchSize:     10,
+		FlushInterval: time.Hour,
+		DedupWindow:   time.Hour,
+	})
+	lg := setupHookLogger(h)
+
+	lg.Error("database is down")
+	lg.Error("database is down")
+	lg.Error("database is down")
+	lg.WithField("category_id", 5).Error("cannot update category")
+	lg.Info("ignored by level")
+
+	if err := h.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rec.requests) != 1 {
+		t.Fatalf("webhook FAILED. Expected 1 request, got %d", len(rec.requests))
+	}
+	if rec.requests[0].Header.Get("Authorization") == "Bearer t" {
+		t.Logf("webhook PASS. Custom header sent")
+	} else {
+		t.Errorf("webhook FAILED. Expected Authorization header, got \"%s\"", rec.requests[0].Header.Get("Authorization"))
+	}
+
+	payload := struct {
+		Entries []webhookEntry `json:"entries"`
+	}{}
+	if err := json.Unmarshal([]byte(rec.bodies[0]), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(payload.Entries) == 2 && payload.Entries[1].Fields["category_id"] == float64(5) {
+		t.Logf("webhook PASS. Expected 2 deduplicated entries, got %d", len(payload.Entries))
+	} else {
+		t.Errorf("webhook FAILED. Expected 2 deduplicated entries, got %s", rec.bodies[0])
+	}
+}
+
+func TestWebhookRequestID(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(http.HandlerFunc(rec.handler))
+	defer srv.Close()
+
+	h := newWebhookHook(WebhookHookConfig{Url: srv.URL, Level: "error"}, HookOptions{FlushInterval: time.Hour})
+	lg := setupHookLogger(h)
+	lg.WithContext(requestid.NewContext(context.Background(), "abc-123")).Error("boom")
+	_ = h.Close(context.Background())
+
+	if len(rec.requests) == 1 && rec.requests[0].Header.Get(requestid.Header) == "abc-123" {
+		t.Logf("webhook PASS. Expected request id \"abc-123\" forwarded")
+	} else {
+		t.Errorf("webhook FAILED. Expected request id \"abc-123\" forwarded, got %d requests", len(rec.requests))
+	}
+}
+
+func TestWebhookRetry(t *testing.T) {
+	rec := &recorder{failures: 2}
+	srv := httptest.NewServer(http.HandlerFunc(rec.handler))
+	defer srv.Close()
+
+	h := newWebhookHook(WebhookHookConfig{Url: srv.URL, Level: "error"}, HookOptions{
+		FlushInterval: time.Hour,
+		MaxRetries:    3,
+		RetryBackoff:  time.Millisecond,
+	})
+	lg := setupHookLogger(h)
+	lg.Error("boom")
+
+	if err := h.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	_ = h.Close(context.Background())
+
+	if len(rec.requests) == 3 {
+		t.Logf("retry PASS. Expected 3 attempts, got %d", len(rec.requests))
+	} else {
+		t.Errorf("retry FAILED. Expected 3 attempts, got %d", len(rec.requests))
+	}
+}
+
+func TestSlackHook(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(http.HandlerFunc(rec.handler))
+	defer srv.Close()
+
+	h := newSlackHook(SlackHookConfig{WebhookUrl: srv.URL, Channel: "#alerts", Level: "warn", Mentions: "<!here>"}, HookOptions{
+		FlushInterval: time.Hour,
+	})
+	lg := setupHookLogger(h)
+	lg.Warn("disk almost full")
+	_ = h.Close(context.Background())
+
+	if len(rec.bodies) != 1 {
+		t.Fatalf("slack FAILED. Expected 1 request, got %d", len(rec.bodies))
+	}
+	payload := map[string]string{}
+	_ = json.Unmarshal([]byte(rec.bodies[0]), &payload)
+	if payload["channel"] == "#alerts" && strings.Contains(payload["text"], "<!here>") && strings.Contains(payload["text"], "*[WARNING]* disk almost full") {
+		t.Logf("slack PASS. Got %v", payload)
+	} else {
+		t.Errorf("slack FAILED. Unexpected payload %s", rec.bodies[0])
+	}
+}
+
+func TestTelegramHook(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(http.HandlerFunc(rec.handler))
+	defer srv.Close()
+
+	h := newTelegramHook(TeleHookConfig{Endpoint: srv.URL, BotToken: "token", ChatID: "-100", Level: "error"}, HookOptions{
+		FlushInterval: time.Hour,
+	})
+	lg := setupHookLogger(h)
+	lg.Error("payment failed")
+	_ = h.Close(context.Background())
+
+	if len(rec.requests) != 1 {
+		t.Fatalf("telegram FAILED. Expected 1 request, got %d", len(rec.requests))
+	}
+	if rec.requests[0].URL.Path == "/bottoken/sendMessage" && strings.Contains(rec.bodies[0], "chat_id=-100") {
+		t.Logf("telegram PASS. Got %s %s", rec.requests[0].URL.Path, rec.bodies[0])
+	} else {
+		t.Errorf("telegram FAILED. Unexpected request %s %s", rec.requests[0].URL.Path, rec.bodies[0])
+	}
+}
+
+// blockingSender holds every Send until release is closed
+type blockingSender struct {
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (s *blockingSender) Send(ctx context.Context, entries []*hookEntry) error {
+	s.once.Do(func() { close(s.started) })
+	<-s.release
+	return nil
+}
+
+func TestHookDroppedReported(t *testing.T) {
+	buf := new(bytes.Buffer)
+	stdlog.SetOutput(buf)
+	defer stdlog.SetOutput(os.Stderr)
+
+	s := &blockingSender{started: make(chan struct{}), release: make(chan struct{})}
+	h := newAsyncHook(s, "error", HookOptions{BatchSize: 1, QueueSize: 1, FlushInterval: time.Hour})
+	lg := setupHookLogger(h)
+
+	lg.Error("first")
+	<-s.started
+	lg.Error("second")
+	lg.Error("third")
+	lg.Error("fourth")
+	close(s.release)
+	_ = h.Close(context.Background())
+
+	if strings.Contains(buf.String(), "2 entries dropped") {
+		t.Logf("dropped PASS. Got \"%s\"", strings.TrimSpace(buf.String()))
+	} else {
+		t.Errorf("dropped FAILED. Expected \"2 entries dropped\" reported, got \"%s\"", buf.String())
+	}
+}
+
+func TestStripUrl(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Close()
+
+	_, err := http.Post(srv.URL+"/botsecret-token/sendMessage", "text/plain", nil)
+	if err == nil {
+		t.Fatalf("Post() FAILED. Expected error on a closed server")
+	}
+	if stripped := stripUrl(permanentError{err: err}); !strings.Contains(stripped.Error(), "secret-token") {
+		t.Logf("stripUrl() PASS. Got \"%s\"", stripped)
+	} else {
+		t.Errorf("stripUrl() FAILED. Expected no token, got \"%s\"", stripped)
+	}
+}
+
+func TestTruncateUtf8(t *testing.T) {
+	cases := map[string]string{
+		"héllo": "h",
+		"hello": "he",
+		"日本":    "",
+		"a":     "a",
+	}
+	for in, expect := range cases {
+		if got := truncateUtf8(in, 2); got == expect {
+			t.Logf("truncateUtf8(\"%s\") PASS. Expected \"%s\"", in, expect)
+		} else {
+			t.Errorf("truncateUtf8(\"%s\") FAILED. Expected \"%s\", got \"%s\"", in, expect, got)
+		}
+	}
+}
+
+func TestFlushHooksOnExit(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(http.HandlerFunc(rec.handler))
+	defer srv.Close()
+
+	h := newWebhookHook(WebhookHookConfig{Url: srv.URL, Level: "error"}, HookOptions{FlushInterval: time.Hour})
+	hooks = []*asyncHook{h}
+	defer func() { hooks = nil }()
+
+	// logrus runs the exit handlers after firing the hooks of a fatal entry
+	lg := setupHookLogger(h)
+	lg.l.ExitFunc = func(int) {}
+	logrus.RegisterExitHandler(flushHooks)
+	lg.Fatal("shutting down")
+
+	if len(rec.requests) == 1 {
+		t.Logf("exit handler PASS. Expected fatal entry delivered before exit")
+	} else {
+		t.Errorf("exit handler FAILED. Expected 1 request before exit, got %d", len(rec.requests))
+	}
+}
@@ -0,0 +1,80 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+type webhookEntry struct {
+	Time     time.Time              `json:"time"`
+	Level    string                 `json:"level"`
+	Message  string                 `json:"message"`
+	Fields   map[string]interface{} `json:"fields"`
+	Repeated int                    `json:"repeated,omitempty"`
+}
+
+// webhookSender posts batches as json to any http endpoint
+type webhookSender struct {
+	client  *http.Client
+	url     string
+	headers map[string]string
+}
+
+func (s *webhookSender) Send(ctx context.Context, entries []*hookEntry) error {
+	payload := struct {
+		Entries []webhookEntry `json:"entries"`
+	}{
+		Entries: make([]webhookEntry, 0, len(entries)),
+	}
+	for _, e := range entries {
+		payload.Entries = append(payload.Entries, webhookEntry{
+			Time:     e.Time,
+			Level:    e.Level.String(),
+			Message:  e.Message,
+			Fields:   e.Fields,
+			Repeated: e.Repeated,
+		})
+	}
+	return postJSON(ctx, s.client, s.url, s.headers, payload)
+}
+
+type WebhookHookConfig struct {
+	Enable  bool
+	Url     string
+	Level   string
+	Headers map[string]string
+}
+
+func newWebhookHook(c WebhookHookConfig, o HookOptions) *asyncHook {
+	return newAsyncHook(&webhookSender{
+		client:  newHookHttpClient(),
+		url:     c.Url,
+		headers: c.Headers,
+	}, c.Level, o)
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, payload interface{}) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return permanentError{err: err}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return permanentError{err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	return checkResponse(resp)
+}
@@ -0,0 +1,221 @@
+package log
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"sync"
+	"time"
+)
+
+// verbosity lists the supported levels from the least to the most verbose
+var verbosity = []logrus.Level{
+	logrus.ErrorLevel,
+	logrus.WarnLevel,
+	logrus.InfoLevel,
+	logrus.DebugLevel,
+}
+
+// levelController changes the level of the running logger, an override
+// shifts the logger and every stack channel by the same number of steps until
+// it is reverted
+type levelController struct {
+	mu          sync.RWMutex
+	root        *logrus.Logger
+	base        logrus.Level
+	revertAfter time.Duration
+	admins      []int64
+	override    *logrus.Level
+	revertAt    *time.Time
+	timer       *time.Timer
+	// generation is bumped by every change, a revert timer only applies when no
+	// change happened since it was scheduled
+	generation uint64
+}
+
+var levels = &levelController{}
+
+// LevelState describes the current runtime level
+type LevelState struct {
+	Level     string     `json:"level"`
+	BaseLevel string     `json:"base_level"`
+	RevertAt  *time.Time `json:"revert_at"`
+}
+
+func (c *levelController) init(root *logrus.Logger, revertAfter time.Duration, admins []int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.root = root
+	c.base = root.GetLevel()
+	c.revertAfter = revertAfter
+	c.admins = admins
+}
+
+// channelLevel returns the level a stack channel must apply, its own level moved
+// by as many steps as the override moves the base level (e.g. an info -> debug
+// override turns an error channel into a warn channel)
+func (c *levelController) channelLevel(own logrus.Level) logrus.Level {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.override == nil {
+		return own
+	}
+	return stepLevel(own, verbosityIndex(*c.override)-verbosityIndex(c.base))
+}
+
+func (c *levelController) set(level logrus.Level, revertAfter time.Duration) (LevelState, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.root == nil {
+		return LevelState{}, errors.New("logger is not initialized")
+	}
+
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.revertAt = nil
+	c.generation++
+
+	if level == c.base {
+		c.override = nil
+	} else {
+		c.override = &level
+		if revertAfter > 0 {
+			at := time.Now().Add(revertAfter)
+			c.revertAt = &at
+			generation := c.generation
+			c.timer = time.AfterFunc(revertAfter, func() {
+				c.revert(generation)
+			})
+		}
+	}
+	c.root.SetLevel(level)
+	return c.stateLocked(), nil
+}
+
+// revert restores the base level unless the level changed after generation, the
+// timer may fire while a newer change waits for the lock
+func (c *levelController) revert(generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation != generation {
+		return
+	}
+	c.generation++
+	c.timer = nil
+	c.revertAt = nil
+	c.override = nil
+	c.root.SetLevel(c.base)
+}
+
+func (c *levelController) current() logrus.Level {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.override != nil {
+		return *c.override
+	}
+	return c.base
+}
+
+func (c *levelController) stateLocked() LevelState {
+	level := c.base
+	if c.override != nil {
+		level = *c.override
+	}
+	return LevelState{
+		Level:     level.String(),
+		BaseLevel: c.base.String(),
+		RevertAt:  c.revertAt,
+	}
+}
+
+// RevertAfter returns the configured delay after which runtime level changes are reverted
+func RevertAfter() time.Duration {
+	levels.mu.RLock()
+	defer levels.mu.RUnlock()
+	return levels.revertAfter
+}
+
+// IsLevelAdmin reports whether the user is allowed to change the level at runtime
+func IsLevelAdmin(id int64) bool {
+	levels.mu.RLock()
+	defer levels.mu.RUnlock()
+	for _, admin := range levels.admins {
+		if admin == id {
+			return true
+		}
+	}
+	return false
+}
+
+// GetLevel returns the current runtime level
+func GetLevel() LevelState {
+	levels.mu.RLock()
+	defer levels.mu.RUnlock()
+	return levels.stateLocked()
+}
+
+// SetLevel changes the level of the running logger, when revertAfter is
+// positive the configured level is restored once it elapses
+func SetLevel(level string, revertAfter time.Duration) (LevelState, error) {
+	l, err := parseLevel(level)
+	if err != nil {
+		return LevelState{}, err
+	}
+	return levels.set(l, revertAfter)
+}
+
+// ResetLevel restores the configured level
+func ResetLevel() (LevelState, error) {
+	levels.mu.RLock()
+	base := levels.base
+	levels.mu.RUnlock()
+	return levels.set(base, 0)
+}
+
+// RaiseLevel makes the logger one step more verbose (e.g. info -> debug)
+func RaiseLevel(revertAfter time.Duration) (LevelState, error) {
+	return levels.set(stepLevel(levels.current(), 1), revertAfter)
+}
+
+// LowerLevel makes the logger one step less verbose (e.g. info -> warn)
+func LowerLevel(revertAfter time.Duration) (LevelState, error) {
+	return levels.set(stepLevel(levels.current(), -1), revertAfter)
+}
+
+// verbosityIndex returns the position of level in verbosity, zero for unsupported levels
+func verbosityIndex(level logrus.Level) int {
+	for i, l := range verbosity {
+		if l == level {
+			return i
+		}
+	}
+	return 0
+}
+
+func stepLevel(level logrus.Level, step int) logrus.Level {
+	for i, l := range verbosity {
+		if l == level {
+			i += step
+			if i < 0 {
+				i = 0
+			}
+			if i >= len(verbosity) {
+				i = len(verbosity) - 1
+			}
+			return verbosity[i]
+		}
+	}
+	return level
+}
+
+func parseLevel(level string) (logrus.Level, error) {
+	switch strings.ToLower(level) {
+	case "error", "warn", "info", "debug":
+		return getLogLevel(level), nil
+	default:
+		return logrus.InfoLevel, errors.New("log level is invalid")
+	}
+}
@@ -0,0 +1,136 @@
+package log
+
+import (
+	"bytes"
+	"github.com/sirupsen/logrus"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setupLevels(t *testing.T, base logrus.Level) (*bytes.Buffer, Contract) {
+	t.Cleanup(teardownLevels)
+	out := &bytes.Buffer{}
+	l := newLogrus(base)
+	l.SetOutput(io.Discard)
+	l.AddHook(&channelHook{w: out, level: base, formatter: getFormatter(FormatterJSON)})
+	levels = &levelController{}
+	levels.init(l, 0, []int64{1})
+	return out, newLogger(l)
+}
+
+func TestSetLevel(t *testing.T) {
+	out, lg := setupLevels(t, logrus.InfoLevel)
+
+	lg.Debug("before change")
+	if _, err := SetLevel("debug", 0); err != nil {
+		t.Fatalf("SetLevel() FAILED. Unexpected error \"%s\"", err)
+	}
+	lg.Debug("after change")
+
+	if !strings.Contains(out.String(), "before change") && strings.Contains(out.String(), "after change") {
+		t.Logf("SetLevel() PASS. Got \"%s\"", out.String())
+	} else {
+		t.Errorf("SetLevel() FAILED. Expected only the entry after the change, got \"%s\"", out.String())
+	}
+
+	if _, err := SetLevel("verbose", 0); err != nil {
+		t.Logf("SetLevel() PASS. Expected error, got \"%s\"", err)
+	} else {
+		t.Errorf("SetLevel() FAILED. Expected error for unknown level, got nil")
+	}
+}
+
+func TestSetLevelRevert(t *testing.T) {
+	_, _ = setupLevels(t, logrus.WarnLevel)
+
+	state, err := SetLevel("debug", 50*time.Millisecond)
+	if err != nil || state.Level != "debug" || state.RevertAt == nil {
+		t.Fatalf("SetLevel() FAILED. Expected debug with revert time, got %+v, %v", state, err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if state = GetLevel(); state.Level == "warning" && state.RevertAt == nil {
+		t.Logf("SetLevel() revert PASS. Got %+v", state)
+	} else {
+		t.Errorf("SetLevel() revert FAILED. Expected warning level, got %+v", state)
+	}
+}
+
+func TestRaiseLowerLevel(t *testing.T) {
+	_, _ = setupLevels(t, logrus.InfoLevel)
+
+	state, _ := RaiseLevel(0)
+	state, _ = RaiseLevel(0)
+	if state.Level == "debug" {
+		t.Logf("RaiseLevel() PASS. Got \"%s\"", state.Level)
+	} else {
+		t.Errorf("RaiseLevel() FAILED. Expected \"debug\", got \"%s\"", state.Level)
+	}
+
+	for i := 0; i < 5; i++ {
+		state, _ = LowerLevel(0)
+	}
+	if state.Level == "error" {
+		t.Logf("LowerLevel() PASS. Got \"%s\"", state.Level)
+	} else {
+		t.Errorf("LowerLevel() FAILED. Expected \"error\", got \"%s\"", state.Level)
+	}
+}
+
+func TestChannelLevelRelative(t *testing.T) {
+	_, _ = setupLevels(t, logrus.InfoLevel)
+	_, _ = SetLevel("debug", 0)
+
+	cases := map[logrus.Level]logrus.Level{
+		logrus.ErrorLevel: logrus.WarnLevel,
+		logrus.InfoLevel:  logrus.DebugLevel,
+		logrus.DebugLevel: logrus.DebugLevel,
+	}
+	for own, expect := range cases {
+		if got := levels.channelLevel(own); got == expect {
+			t.Logf("channelLevel(%s) PASS. Expected %s", own, expect)
+		} else {
+			t.Errorf("channelLevel(%s) FAILED. Expected %s, got %s", own, expect, got)
+		}
+	}
+
+	_, _ = ResetLevel()
+	if got := levels.channelLevel(logrus.ErrorLevel); got == logrus.ErrorLevel {
+		t.Logf("channelLevel() PASS. Expected own level after reset")
+	} else {
+		t.Errorf("channelLevel() FAILED. Expected error after reset, got %s", got)
+	}
+}
+
+func TestSetLevelStaleRevert(t *testing.T) {
+	_, _ = setupLevels(t, logrus.InfoLevel)
+
+	_, _ = SetLevel("debug", time.Hour)
+	levels.mu.RLock()
+	stale := levels.generation
+	levels.mu.RUnlock()
+	_, _ = SetLevel("warn", time.Hour)
+
+	// a timer of the first change firing late must keep the newer override
+	levels.revert(stale)
+	if state := GetLevel(); state.Level == "warning" && state.RevertAt != nil {
+		t.Logf("revert() PASS. Expected newer override kept, got %+v", state)
+	} else {
+		t.Errorf("revert() FAILED. Expected newer override kept, got %+v", state)
+	}
+}
+
+func TestIsLevelAdmin(t *testing.T) {
+	_, _ = setupLevels(t, logrus.InfoLevel)
+	if IsLevelAdmin(1) && !IsLevelAdmin(2) {
+		t.Logf("IsLevelAdmin() PASS. Expected only user 1")
+	} else {
+		t.Errorf("IsLevelAdmin() FAILED. Expected only user 1, got %v, %v", IsLevelAdmin(1), IsLevelAdmin(2))
+	}
+}
+
+func teardownLevels() {
+	levels = &levelController{}
+}
@@ -1,56 +1,136 @@
 package log
 
 import (
+	"context"
 	"errors"
 	"github.com/sirupsen/logrus"
-	"os"
 	"sync"
+	"time"
 )
 
 var (
 	instance  Contract
 	once      sync.Once
-	file      *os.File
 	formatter = &logrus.JSONFormatter{}
+	hooks     []*asyncHook
+	files     []*rotatingFile
 )
 
+const (
+	ChannelDaily  = "daily"
+	ChannelSingle = "single"
+	ChannelStdout = "stdout"
+	ChannelStack  = "stack"
+)
+
+const (
+	FormatterJSON = "json"
+	FormatterText = "text"
+)
+
+// Fields is a set of structured key/value pairs attached to log entries
+type Fields map[string]interface{}
+
 type Contract interface {
 	Debug(args ...interface{})
 	Info(args ...interface{})
 	Warn(args ...interface{})
 	Error(args ...interface{})
 	Fatal(args ...interface{})
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+	// WithField returns a child logger that adds key to every entry
+	WithField(key string, value interface{}) Contract
+	// WithFields returns a child logger that adds all fields to every entry
+	WithFields(fields Fields) Contract
+	// WithError returns a child logger that adds err under the "error" key
+	WithError(err error) Contract
+	// WithContext returns a child logger bound to ctx, hooks may read request scoped values from it
+	WithContext(ctx context.Context) Contract
 }
 
 type Config struct {
 	Channel        string
+	Path           string
 	Daily          DailyConfig
 	Singe          SingeConfig
 	StdOut         StdOutConfig
+	Stack          StackConfig
+	HookOptions    HookOptions
 	TeleHookConfig TeleHookConfig
+	WebhookHook    WebhookHookConfig
+	SlackHook      SlackHookConfig
+	Redact         RedactConfig
+	// LevelRevertAfter restores the configured level after a runtime change, zero keeps the change
+	LevelRevertAfter time.Duration
+	// LevelAdmins are the user ids allowed to change the level at runtime, empty allows nobody
+	LevelAdmins []int64
 }
 
 func New(c Config) (Contract, error) {
 	var err error
 	once.Do(func() {
 		l := newLogrus(logrus.InfoLevel)
+		if !c.Redact.Disable {
+			var r *redactHook
+			if r, err = newRedactHook(c.Redact); err != nil {
+				return
+			}
+			l.AddHook(r)
+		}
 		switch c.Channel {
-		case "daily":
-			instance, err = newDailyDriver(l, c.Daily)
+		case ChannelDaily:
+			instance, err = newDailyDriver(l, c.Path, c.Daily)
 			break
-		case "single":
-			instance, err = newSingleDriver(l, c.Singe)
+		case ChannelSingle:
+			instance, err = newSingleDriver(l, c.Path, c.Singe)
 			break
-		case "stdout":
+		case ChannelStdout:
 			instance = newStdoutDriver(l, c.StdOut)
 			break
+		case ChannelStack:
+			instance, err = newStackDriver(l, c)
+			break
 		default:
 			err = errors.New("log channel is invalid")
 		}
 
 		if c.TeleHookConfig.Enable {
-			addTelegramHook(l, c.TeleHookConfig)
+			hooks = append(hooks, newTelegramHook(c.TeleHookConfig, c.HookOptions))
+		}
+		if c.WebhookHook.Enable {
+			hooks = append(hooks, newWebhookHook(c.WebhookHook, c.HookOptions))
 		}
+		if c.SlackHook.Enable {
+			hooks = append(hooks, newSlackHook(c.SlackHook, c.HookOptions))
+		}
+		for _, h := range hooks {
+			l.AddHook(h)
+		}
+		if len(hooks) > 0 {
+			logrus.RegisterExitHandler(flushHooks)
+		}
+		levels.init(l, c.LevelRevertAfter, c.LevelAdmins)
 	})
 	return instance, err
 }
+
+// Shutdown delivers entries still queued in hooks and closes log files, it should
+// be called once the application stops serving requests
+func Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, h := range hooks {
+		if err := h.Close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, f := range files {
+		if err := f.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
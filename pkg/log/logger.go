@@ -0,0 +1,100 @@
+package log
+
+import (
+	"context"
+	"github.com/kurneo/go-template/pkg/support/requestid"
+	"github.com/sirupsen/logrus"
+)
+
+// logger is the Contract implementation shared by every driver, drivers only
+// differ in the output the underlying logrus instance writes to
+type logger struct {
+	l      *logrus.Logger
+	fields logrus.Fields
+	ctx    context.Context
+}
+
+func (lg *logger) Debug(args ...interface{}) {
+	lg.getLog().Debug(args...)
+}
+
+func (lg *logger) Info(args ...interface{}) {
+	lg.getLog().Info(args...)
+}
+
+func (lg *logger) Warn(args ...interface{}) {
+	lg.getLog().Warn(args...)
+}
+
+func (lg *logger) Error(args ...interface{}) {
+	lg.getLog().Error(args...)
+}
+
+func (lg *logger) Fatal(args ...interface{}) {
+	lg.getLog().Fatal(args...)
+}
+
+func (lg *logger) Debugf(format string, args ...interface{}) {
+	lg.getLog().Debugf(format, args...)
+}
+
+func (lg *logger) Infof(format string, args ...interface{}) {
+	lg.getLog().Infof(format, args...)
+}
+
+func (lg *logger) Warnf(format string, args ...interface{}) {
+	lg.getLog().Warnf(format, args...)
+}
+
+func (lg *logger) Errorf(format string, args ...interface{}) {
+	lg.getLog().Errorf(format, args...)
+}
+
+func (lg *logger) Fatalf(format string, args ...interface{}) {
+	lg.getLog().Fatalf(format, args...)
+}
+
+func (lg *logger) WithField(key string, value interface{}) Contract {
+	return lg.WithFields(Fields{key: value})
+}
+
+func (lg *logger) WithFields(fields Fields) Contract {
+	merged := make(logrus.Fields, len(lg.fields)+len(fields))
+	for k, v := range lg.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	child := *lg
+	child.fields = merged
+	return &child
+}
+
+func (lg *logger) WithError(err error) Contract {
+	return lg.WithField(logrus.ErrorKey, err)
+}
+
+func (lg *logger) WithContext(ctx context.Context) Contract {
+	child := *lg
+	child.ctx = ctx
+	return &child
+}
+
+func (lg *logger) getLog() *logrus.Entry {
+	entry := lg.l.WithFields(lg.fields).WithField("file", getCalledFile(3))
+	if lg.ctx != nil {
+		entry = entry.WithContext(lg.ctx)
+		if id := requestid.FromContext(lg.ctx); id != "" {
+			entry = entry.WithField("request_id", id)
+		}
+	}
+	return entry
+}
+
+func newLogger(l *logrus.Logger) *logger {
+	return &logger{
+		l:      l,
+		fields: logrus.Fields{},
+	}
+}
@@ -0,0 +1,67 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"testing"
+)
+
+func setupLogger() (*logger, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	l := newLogrus(logrus.DebugLevel)
+	l.SetOutput(buf)
+	return newLogger(l), buf
+}
+
+func decodeEntry(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	entry := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("cannot decode log entry \"%s\": %s", buf.String(), err)
+	}
+	return entry
+}
+
+func TestMessageFormatting(t *testing.T) {
+	lg, buf := setupLogger()
+	lg.Info("user", " logged in")
+	entry := decodeEntry(t, buf)
+	expect := "user logged in"
+	if entry["msg"] == expect {
+		t.Logf("Info() PASS. Expected msg \"%s\", got \"%v\"", expect, entry["msg"])
+	} else {
+		t.Errorf("Info() FAILED. Expected msg \"%s\", got \"%v\"", expect, entry["msg"])
+	}
+
+	buf.Reset()
+	lg.Warnf("category %d not found", 10)
+	entry = decodeEntry(t, buf)
+	expect = "category 10 not found"
+	if entry["msg"] == expect {
+		t.Logf("Warnf() PASS. Expected msg \"%s\", got \"%v\"", expect, entry["msg"])
+	} else {
+		t.Errorf("Warnf() FAILED. Expected msg \"%s\", got \"%v\"", expect, entry["msg"])
+	}
+}
+
+func TestWithFields(t *testing.T) {
+	lg, buf := setupLogger()
+	child := lg.WithField("user_id", 1).WithFields(Fields{"category_id": 2}).WithError(errors.New("boom"))
+	child.Error("update failed")
+	entry := decodeEntry(t, buf)
+	if entry["user_id"] == float64(1) && entry["category_id"] == float64(2) && entry["error"] == "boom" {
+		t.Logf("WithFields() PASS. Got %v", entry)
+	} else {
+		t.Errorf("WithFields() FAILED. Expected user_id, category_id and error fields, got %v", entry)
+	}
+
+	buf.Reset()
+	lg.Info("parent")
+	entry = decodeEntry(t, buf)
+	if _, ok := entry["user_id"]; !ok {
+		t.Logf("WithField() PASS. Parent logger is not modified")
+	} else {
+		t.Errorf("WithField() FAILED. Parent logger got child field, %v", entry)
+	}
+}
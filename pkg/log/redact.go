@@ -0,0 +1,187 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+const redactedValue = "[REDACTED]"
+
+var (
+	defaultRedactKeys     = []string{"password", "access_token", "authorization"}
+	defaultRedactPatterns = []string{`(?i)bearer\s+[a-z0-9\-._~+/]+=*`}
+)
+
+// RedactConfig describes which values are masked before entries reach drivers and hooks.
+// Keys match field names case-insensitively by substring (e.g. "password" matches
+// "new_password"), Patterns are regular expressions applied to messages and string values
+type RedactConfig struct {
+	Disable  bool
+	Keys     []string
+	Patterns []string
+}
+
+// redactHook masks sensitive data in place, it must be the first hook added to the
+// logger so every other hook and the formatter only see the masked entry
+type redactHook struct {
+	keys     []string
+	messages []*regexp.Regexp
+	patterns []*regexp.Regexp
+}
+
+func newRedactHook(c RedactConfig) (*redactHook, error) {
+	keys := c.Keys
+	if len(keys) == 0 {
+		keys = defaultRedactKeys
+	}
+	patterns := c.Patterns
+	if len(patterns) == 0 {
+		patterns = defaultRedactPatterns
+	}
+
+	h := &redactHook{}
+	for _, k := range keys {
+		k = strings.ToLower(strings.TrimSpace(k))
+		if k == "" {
+			continue
+		}
+		h.keys = append(h.keys, k)
+		// key=value, key: value and "key":"value" inside free text
+		h.messages = append(h.messages, regexp.MustCompile(
+			`(?i)("?[\w-]*`+regexp.QuoteMeta(k)+`[\w-]*"?\s*[:=]\s*"?)([^"\s,&}]+)`,
+		))
+	}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("log redact pattern %q is invalid: %w", p, err)
+		}
+		h.patterns = append(h.patterns, re)
+	}
+	return h, nil
+}
+
+func (h *redactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *redactHook) Fire(entry *logrus.Entry) error {
+	entry.Message = h.redactString(entry.Message)
+	if len(entry.Data) > 0 {
+		data := make(logrus.Fields, len(entry.Data))
+		for k, v := range entry.Data {
+			data[k] = h.redactValue(k, v)
+		}
+		entry.Data = data
+	}
+	return nil
+}
+
+func (h *redactHook) sensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range h.keys {
+		if strings.Contains(key, k) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *redactHook) redactValue(key string, v interface{}) interface{} {
+	if h.sensitive(key) {
+		return redactedValue
+	}
+	switch val := v.(type) {
+	case string:
+		return h.redactString(val)
+	case error:
+		if s := h.redactString(val.Error()); s != val.Error() {
+			return s
+		}
+		return val
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = h.redactValue(k, item)
+		}
+		return out
+	case map[string]string:
+		out := make(map[string]string, len(val))
+		for k, item := range val {
+			if h.sensitive(k) {
+				out[k] = redactedValue
+			} else {
+				out[k] = h.redactString(item)
+			}
+		}
+		return out
+	case []string:
+		out := make([]string, len(val))
+		for i, item := range val {
+			out[i] = h.redactString(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = h.redactValue(key, item)
+		}
+		return out
+	default:
+		return h.redactComposite(key, v)
+	}
+}
+
+// redactComposite masks structs, pointers, maps and slices of other types through their
+// JSON form (e.g. LoginFormData{Password}), fields are matched by their JSON names
+func (h *redactHook) redactComposite(key string, v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return v
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Array:
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return v
+		}
+	default:
+		return v
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return redactedValue
+	}
+	var generic interface{}
+	if err = json.Unmarshal(b, &generic); err != nil {
+		return redactedValue
+	}
+	switch generic.(type) {
+	case map[string]interface{}, []interface{}:
+		return h.redactValue(key, generic)
+	default:
+		// values marshalled to a scalar such as time.Time are kept as they are
+		return v
+	}
+}
+
+func (h *redactHook) redactString(s string) string {
+	if s == "" {
+		return s
+	}
+	for _, re := range h.patterns {
+		s = re.ReplaceAllString(s, redactedValue)
+	}
+	for _, re := range h.messages {
+		s = re.ReplaceAllString(s, "${1}"+redactedValue)
+	}
+	return s
+}
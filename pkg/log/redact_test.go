@@ -0,0 +1,93 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setupRedact(t *testing.T, c RedactConfig) (*bytes.Buffer, Contract) {
+	out := &bytes.Buffer{}
+	r, err := newRedactHook(c)
+	if err != nil {
+		t.Fatalf("newRedactHook() FAILED. Unexpected error \"%s\"", err)
+	}
+	l := newLogrus(logrus.DebugLevel)
+	l.SetOutput(io.Discard)
+	l.AddHook(r)
+	l.AddHook(&channelHook{w: out, level: logrus.DebugLevel, formatter: getFormatter(FormatterJSON)})
+	return out, newLogger(l)
+}
+
+func TestRedactFields(t *testing.T) {
+	out, lg := setupRedact(t, RedactConfig{})
+
+	lg.WithFields(Fields{
+		"password":      "s3cret",
+		"Authorization": "Bearer abc.def",
+		"body":          map[string]interface{}{"new_password": "n3w", "email": "user@example.com"},
+	}).Info("login attempt")
+
+	got := out.String()
+	if !strings.Contains(got, "s3cret") && !strings.Contains(got, "abc.def") && !strings.Contains(got, "n3w") &&
+		strings.Contains(got, "user@example.com") && strings.Contains(got, redactedValue) {
+		t.Logf("redact fields PASS. Got \"%s\"", got)
+	} else {
+		t.Errorf("redact fields FAILED. Expected sensitive values to be masked, got \"%s\"", got)
+	}
+}
+
+func TestRedactMessage(t *testing.T) {
+	out, lg := setupRedact(t, RedactConfig{})
+
+	lg.WithError(errors.New("request failed: authorization=Bearer xyz123")).
+		Errorf("payload {\"email\":\"user@example.com\",\"password\":\"hunter2\"} access_token=tok-1")
+
+	got := out.String()
+	if !strings.Contains(got, "hunter2") && !strings.Contains(got, "tok-1") && !strings.Contains(got, "xyz123") &&
+		strings.Contains(got, "user@example.com") {
+		t.Logf("redact message PASS. Got \"%s\"", got)
+	} else {
+		t.Errorf("redact message FAILED. Expected sensitive values to be masked, got \"%s\"", got)
+	}
+}
+
+func TestRedactStruct(t *testing.T) {
+	out, lg := setupRedact(t, RedactConfig{})
+
+	type credentials struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	type request struct {
+		Body   *credentials  `json:"body"`
+		Tokens []credentials `json:"tokens"`
+		At     time.Time     `json:"at"`
+	}
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	lg.WithFields(Fields{
+		"form":    credentials{Email: "user@example.com", Password: "s3cret"},
+		"request": &request{Body: &credentials{Password: "n3w"}, Tokens: []credentials{{Password: "t0k"}}, At: at},
+		"at":      at,
+	}).Info("login attempt")
+
+	got := out.String()
+	if !strings.Contains(got, "s3cret") && !strings.Contains(got, "n3w") && !strings.Contains(got, "t0k") &&
+		strings.Contains(got, "user@example.com") && strings.Contains(got, "2024-01-02T03:04:05Z") {
+		t.Logf("redact struct PASS. Got \"%s\"", got)
+	} else {
+		t.Errorf("redact struct FAILED. Expected sensitive struct fields to be masked, got \"%s\"", got)
+	}
+}
+
+func TestRedactInvalidPattern(t *testing.T) {
+	if _, err := newRedactHook(RedactConfig{Patterns: []string{"(unclosed"}}); err != nil {
+		t.Logf("newRedactHook() PASS. Expected error, got \"%s\"", err)
+	} else {
+		t.Errorf("newRedactHook() FAILED. Expected error for invalid pattern, got nil")
+	}
+}
@@ -0,0 +1,40 @@
+//go:build !windows
+
+package log
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// WatchSignals raises the level on SIGUSR1 and lowers it on SIGUSR2 until ctx is done
+func WatchSignals(ctx context.Context) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-ch:
+				var state LevelState
+				var err error
+				if sig == syscall.SIGUSR1 {
+					state, err = RaiseLevel(RevertAfter())
+				} else {
+					state, err = LowerLevel(RevertAfter())
+				}
+				if err != nil {
+					log.Println("Log error: cannot change level", err)
+					continue
+				}
+				log.Printf("Log level changed to %s by %s", state.Level, sig)
+			}
+		}
+	}()
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package log
+
+import (
+	"context"
+)
+
+// WatchSignals is a no-op on windows, SIGUSR1/SIGUSR2 do not exist there
+func WatchSignals(ctx context.Context) {
+}
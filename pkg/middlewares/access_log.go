@@ -0,0 +1,107 @@
+package middlewares
+
+import (
+	jwtPkg "github.com/kurneo/go-template/pkg/jwt"
+	"github.com/kurneo/go-template/pkg/log"
+	"github.com/labstack/echo/v4"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+type AccessLogConfig struct {
+	// SampleRate is the fraction (0, 1] of successful requests that are logged, failed
+	// and slow requests are always logged. Values outside the range log every request
+	SampleRate float64
+	// SkipPaths are request paths or route templates that are never logged, a trailing
+	// "*" matches by prefix (e.g. "/health*")
+	SkipPaths []string
+	// SlowThreshold logs requests taking longer as warnings, zero disables the check
+	SlowThreshold time.Duration
+}
+
+func AccessLogMiddleware(l log.Contract, c AccessLogConfig) echo.MiddlewareFunc {
+	if c.SampleRate <= 0 || c.SampleRate > 1 {
+		c.SampleRate = 1
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(context echo.Context) error {
+			if skipAccessLog(context, c.SkipPaths) {
+				return next(context)
+			}
+
+			start := time.Now()
+			err := next(context)
+			if err != nil {
+				// let echo write the error response so the logged status is the real one
+				context.Error(err)
+			}
+			latency := time.Since(start)
+
+			req := context.Request()
+			res := context.Response()
+			slow := c.SlowThreshold > 0 && latency > c.SlowThreshold
+
+			if res.Status < 400 && !slow && c.SampleRate < 1 && rand.Float64() >= c.SampleRate {
+				return err
+			}
+
+			fields := log.Fields{
+				"method":     req.Method,
+				"route":      context.Path(),
+				"path":       req.URL.Path,
+				"status":     res.Status,
+				"latency_ms": float64(latency.Microseconds()) / 1000,
+				"bytes_in":   req.ContentLength,
+				"bytes_out":  res.Size,
+				"ip":         context.RealIP(),
+				"user_agent": req.UserAgent(),
+			}
+			if id, ok := accessLogUserID(context); ok {
+				fields["user_id"] = id
+			}
+			if slow {
+				fields["slow"] = true
+			}
+
+			lg := l.WithContext(req.Context()).WithFields(fields)
+			if err != nil {
+				lg = lg.WithError(err)
+			}
+
+			switch {
+			case res.Status >= 500:
+				lg.Error("http request")
+			case res.Status >= 400 || slow:
+				lg.Warn("http request")
+			default:
+				lg.Info("http request")
+			}
+			return err
+		}
+	}
+}
+
+func skipAccessLog(context echo.Context, skips []string) bool {
+	path := context.Request().URL.Path
+	route := context.Path()
+	for _, s := range skips {
+		if prefix, ok := strings.CutSuffix(s, "*"); ok {
+			if strings.HasPrefix(path, prefix) || strings.HasPrefix(route, prefix) {
+				return true
+			}
+			continue
+		}
+		if path == s || route == s {
+			return true
+		}
+	}
+	return false
+}
+
+func accessLogUserID(context echo.Context) (int64, bool) {
+	if auth, ok := context.Get("auth").(*jwtPkg.AccessToken[int64]); ok && auth != nil {
+		return auth.Sub, true
+	}
+	return 0, false
+}
@@ -0,0 +1,156 @@
+package middlewares
+
+import (
+	"context"
+	"github.com/kurneo/go-template/pkg/log"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type accessLogEntry struct {
+	level  string
+	fields log.Fields
+}
+
+// accessLogRecorder is a log.Contract keeping every entry with its level and fields
+type accessLogRecorder struct {
+	mu      *sync.Mutex
+	entries *[]accessLogEntry
+	fields  log.Fields
+}
+
+func (r accessLogRecorder) record(level string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*r.entries = append(*r.entries, accessLogEntry{level: level, fields: r.fields})
+}
+
+func (r accessLogRecorder) Debug(args ...interface{}) { r.record("debug") }
+func (r accessLogRecorder) Info(args ...interface{})  { r.record("info") }
+func (r accessLogRecorder) Warn(args ...interface{})  { r.record("warn") }
+func (r accessLogRecorder) Error(args ...interface{}) { r.record("error") }
+func (r accessLogRecorder) Fatal(args ...interface{}) { r.record("fatal") }
+func (r accessLogRecorder) Debugf(format string, args ...interface{}) {
+	r.record("debug")
+}
+func (r accessLogRecorder) Infof(format string, args ...interface{}) {
+	r.record("info")
+}
+func (r accessLogRecorder) Warnf(format string, args ...interface{}) {
+	r.record("warn")
+}
+func (r accessLogRecorder) Errorf(format string, args ...interface{}) {
+	r.record("error")
+}
+func (r accessLogRecorder) Fatalf(format string, args ...interface{}) {
+	r.record("fatal")
+}
+
+func (r accessLogRecorder) WithField(key string, value interface{}) log.Contract {
+	return r.WithFields(log.Fields{key: value})
+}
+
+func (r accessLogRecorder) WithFields(fields log.Fields) log.Contract {
+	merged := log.Fields{}
+	for k, v := range r.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	r.fields = merged
+	return r
+}
+
+func (r accessLogRecorder) WithError(err error) log.Contract {
+	return r.WithField("error", err)
+}
+
+func (r accessLogRecorder) WithContext(ctx context.Context) log.Contract {
+	return r
+}
+
+func setupAccessLog(c AccessLogConfig) (*echo.Echo, *[]accessLogEntry) {
+	entries := &[]accessLogEntry{}
+	lg := accessLogRecorder{mu: &sync.Mutex{}, entries: entries}
+
+	e := echo.New()
+	e.Use(AccessLogMiddleware(lg, c))
+	e.GET("/ok", func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+	e.GET("/health", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/users/:id", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/fail", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusInternalServerError, "boom")
+	})
+	e.GET("/slow", func(c echo.Context) error {
+		time.Sleep(20 * time.Millisecond)
+		return c.NoContent(http.StatusOK)
+	})
+	return e, entries
+}
+
+func serve(e *echo.Echo, path string) {
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+}
+
+func TestAccessLogSkipPaths(t *testing.T) {
+	e, entries := setupAccessLog(AccessLogConfig{SkipPaths: []string{"/health", "/users/*"}})
+
+	serve(e, "/health")
+	serve(e, "/users/5")
+	serve(e, "/ok")
+
+	if len(*entries) == 1 && (*entries)[0].fields["path"] == "/ok" && (*entries)[0].level == "info" {
+		t.Logf("AccessLogMiddleware() skip PASS. Expected only /ok logged, got %+v\n", *entries)
+	} else {
+		t.Errorf("AccessLogMiddleware() skip FAILED. Expected only /ok logged, got %+v\n", *entries)
+	}
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	e, entries := setupAccessLog(AccessLogConfig{SampleRate: 0.000001})
+
+	for i := 0; i < 50; i++ {
+		serve(e, "/ok")
+	}
+	serve(e, "/fail")
+
+	// failed requests bypass sampling, successful ones are almost never picked
+	if len(*entries) == 1 && (*entries)[0].fields["status"] == http.StatusInternalServerError && (*entries)[0].level == "error" {
+		t.Logf("AccessLogMiddleware() sampling PASS. Expected only the failed request, got %+v\n", *entries)
+	} else {
+		t.Errorf("AccessLogMiddleware() sampling FAILED. Expected only the failed request, got %+v\n", *entries)
+	}
+
+	e, entries = setupAccessLog(AccessLogConfig{SampleRate: 2})
+	for i := 0; i < 5; i++ {
+		serve(e, "/ok")
+	}
+	if len(*entries) == 5 {
+		t.Logf("AccessLogMiddleware() sampling PASS. Expected an invalid rate to log everything\n")
+	} else {
+		t.Errorf("AccessLogMiddleware() sampling FAILED. Expected 5 entries, got %d\n", len(*entries))
+	}
+}
+
+func TestAccessLogSlow(t *testing.T) {
+	e, entries := setupAccessLog(AccessLogConfig{SampleRate: 0.000001, SlowThreshold: 10 * time.Millisecond})
+
+	serve(e, "/slow")
+
+	if len(*entries) == 1 && (*entries)[0].level == "warn" && (*entries)[0].fields["slow"] == true {
+		t.Logf("AccessLogMiddleware() slow PASS. Expected a slow warning, got %+v\n", *entries)
+	} else {
+		t.Errorf("AccessLogMiddleware() slow FAILED. Expected a slow warning, got %+v\n", *entries)
+	}
+}
@@ -10,16 +10,19 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
-func JwtMiddleware(t *jwtPkg.TokenManager[int64]) echo.MiddlewareFunc {
+// JwtMiddleware protects every route except the login route and the given excepts,
+// excepts are compared with the registered route, e.g. "/files/:disk/*", never with a
+// prefix of the request url
+func JwtMiddleware(t *jwtPkg.TokenManager[int64], excepts ...string) echo.MiddlewareFunc {
 	secret, _ := t.GetSecret()
-	excepts := []string{
+	excepts = append([]string{
 		"/api/admin/v1/auth/login",
-	}
+	}, excepts...)
 	return echoJwt.WithConfig(echoJwt.Config{
 		SigningKey: secret,
 		Skipper: func(c echo.Context) bool {
 			if slices.Some[string](excepts, func(item string) bool {
-				return c.Request().URL.Path == item
+				return c.Path() == item
 			}) {
 				return true
 			}
@@ -0,0 +1,22 @@
+package middlewares
+
+import (
+	"github.com/kurneo/go-template/pkg/support/requestid"
+	"github.com/labstack/echo/v4"
+)
+
+func RequestIDMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(context echo.Context) error {
+			req := context.Request()
+			id := req.Header.Get(requestid.Header)
+			if !requestid.IsValid(id) {
+				id = requestid.Generate()
+			}
+			context.SetRequest(req.WithContext(requestid.NewContext(req.Context(), id)))
+			context.Response().Header().Set(requestid.Header, id)
+			context.Set("request_id", id)
+			return next(context)
+		}
+	}
+}
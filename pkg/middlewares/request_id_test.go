@@ -0,0 +1,42 @@
+package middlewares
+
+import (
+	"github.com/kurneo/go-template/pkg/support/requestid"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	e := echo.New()
+	e.Use(RequestIDMiddleware())
+	e.GET("/", func(c echo.Context) error {
+		return c.String(http.StatusOK, requestid.FromContext(c.Request().Context()))
+	})
+
+	cases := []struct {
+		incoming string
+		reused   bool
+	}{
+		{"abc-123", true},
+		{"", false},
+		{"bad id\r\n", false},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if c.incoming != "" {
+			req.Header.Set(requestid.Header, c.incoming)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		id := rec.Header().Get(requestid.Header)
+		ok := requestid.IsValid(id) && rec.Body.String() == id && (id == c.incoming) == c.reused
+		if ok {
+			t.Logf("RequestIDMiddleware(\"%s\") PASS. Got \"%s\"\n", c.incoming, id)
+		} else {
+			t.Errorf("RequestIDMiddleware(\"%s\") FAILED. Expected reused %v, got header \"%s\", context \"%s\"\n", c.incoming, c.reused, id, rec.Body.String())
+		}
+	}
+}
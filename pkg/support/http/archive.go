@@ -0,0 +1,86 @@
+package http
+
+import (
+	"errors"
+	"github.com/kurneo/go-template/pkg/filesystem"
+	"github.com/labstack/echo/v4"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// ResponseArchive streams the directory tree at dir of disk as an attachment named
+// name plus the extension of format, e.g. "assets.zip". The archive is written while it
+// is built, so a failure after the first bytes can only abort the response
+func ResponseArchive(context echo.Context, m *filesystem.Manager, disk, dir, name string, format filesystem.ArchiveFormat) error {
+	d, err := m.Disk(disk)
+	if err != nil {
+		return err
+	}
+	if ok, err := d.DirExists(dir); err != nil || !ok {
+		return ResponseNotFound(context)
+	}
+
+	res := context.Response()
+	res.Header().Set(echo.HeaderContentType, format.Mime())
+	res.Header().Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{
+		"filename": name + format.Extension(),
+	}))
+	res.WriteHeader(http.StatusOK)
+	return filesystem.WriteArchive(res, d, dir, format)
+}
+
+// ExtractUpload extracts the zip or tar.gz archive uploaded as field into dir of disk and
+// returns the extracted paths. The archive is read from the multipart body while it is
+// extracted, so the upload is bounded by limits instead of being buffered first. Archives
+// breaking the limits fail with an UploadError ruled "max_size", entries leaving dir with
+// filesystem.ErrPathOutsideRoot
+func ExtractUpload(context echo.Context, m *filesystem.Manager, field, disk, dir string, limits filesystem.ExtractLimits) ([]string, error) {
+	part, err := archivePart(context.Request(), field)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.ToLower(part.FileName())
+	var format filesystem.ArchiveFormat
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		format = filesystem.ArchiveZip
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		format = filesystem.ArchiveTarGz
+	default:
+		return nil, &UploadError{Field: field, Rule: "mimes", Err: ErrUploadMimeNotAllowed}
+	}
+
+	paths, err := m.Extract(part, format, disk, dir, limits)
+	if errors.Is(err, filesystem.ErrArchiveTooLarge) {
+		return nil, &UploadError{Field: field, Rule: "max_size", Err: err}
+	}
+	return paths, err
+}
+
+// archivePart skips the multipart body up to the file of field, at most
+// defaultUploadMaxParts parts are read
+func archivePart(req *http.Request, field string) (*multipart.Part, error) {
+	reader, err := req.MultipartReader()
+	if err != nil {
+		return nil, &UploadError{Field: field, Rule: "required", Err: ErrUploadRequired}
+	}
+	for parts := 0; ; parts++ {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, &UploadError{Field: field, Rule: "required", Err: ErrUploadRequired}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if parts >= defaultUploadMaxParts {
+			return nil, &UploadError{Field: part.FormName(), Rule: "max_parts", Err: ErrUploadTooManyParts}
+		}
+		if part.FormName() == field && part.FileName() != "" {
+			return part, nil
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package http
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"github.com/kurneo/go-template/pkg/filesystem"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResponseArchive(t *testing.T) {
+	m, d := setupUploads(t)
+	_ = d.Put("assets/logo.svg", []byte("<svg></svg>"))
+	e := echo.New()
+
+	rec := httptest.NewRecorder()
+	context := e.NewContext(httptest.NewRequest(http.MethodGet, "/assets", nil), rec)
+	err := ResponseArchive(context, m, "uploads", "assets", "assets", filesystem.ArchiveZip)
+
+	body := rec.Body.Bytes()
+	r, zipErr := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	disposition := rec.Header().Get(echo.HeaderContentDisposition)
+	if err == nil && zipErr == nil && len(r.File) == 1 && disposition == "attachment; filename=assets.zip" {
+		t.Logf("ResponseArchive() PASS. Expected assets.zip attachment, got \"%s\"\n", disposition)
+	} else {
+		t.Errorf("ResponseArchive() FAILED. Expected assets.zip attachment, got \"%s\", %v, %v\n", disposition, err, zipErr)
+	}
+
+	rec = httptest.NewRecorder()
+	context = e.NewContext(httptest.NewRequest(http.MethodGet, "/missing", nil), rec)
+	if err = ResponseArchive(context, m, "uploads", "missing", "missing", filesystem.ArchiveZip); err == nil && rec.Code == http.StatusNotFound {
+		t.Logf("ResponseArchive() PASS. Expected 404 for a missing directory\n")
+	} else {
+		t.Errorf("ResponseArchive() FAILED. Expected 404 for a missing directory, got %d, %v\n", rec.Code, err)
+	}
+}
+
+func TestExtractUpload(t *testing.T) {
+	m, d := setupUploads(t)
+	e := echo.New()
+
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	f, _ := w.Create("img/a.txt")
+	_, _ = f.Write([]byte(strings.Repeat("a", 100)))
+	_ = w.Close()
+
+	context := e.NewContext(newUploadRequest(t, nil, map[string][]byte{"assets.zip": buf.Bytes()}), httptest.NewRecorder())
+	paths, err := ExtractUpload(context, m, "file", "uploads", "imports", filesystem.ExtractLimits{})
+	content, _ := d.Get("imports/img/a.txt")
+	if err == nil && len(paths) == 1 && len(content) == 100 {
+		t.Logf("ExtractUpload() PASS. Got %v\n", paths)
+	} else {
+		t.Errorf("ExtractUpload() FAILED. Expected [imports/img/a.txt], got %v, %v\n", paths, err)
+	}
+
+	context = e.NewContext(newUploadRequest(t, nil, map[string][]byte{"assets.zip": buf.Bytes()}), httptest.NewRecorder())
+	_, err = ExtractUpload(context, m, "file", "uploads", "imports", filesystem.ExtractLimits{MaxFileSize: 10})
+	var uploadErr *UploadError
+	if errors.As(err, &uploadErr) && uploadErr.Rule == "max_size" {
+		t.Logf("ExtractUpload() PASS. Expected max_size, got \"%s\"\n", err)
+	} else {
+		t.Errorf("ExtractUpload() FAILED. Expected max_size, got %v\n", err)
+	}
+
+	context = e.NewContext(newUploadRequest(t, nil, map[string][]byte{"assets.rar": buf.Bytes()}), httptest.NewRecorder())
+	if _, err = ExtractUpload(context, m, "file", "uploads", "imports", filesystem.ExtractLimits{}); errors.Is(err, ErrUploadMimeNotAllowed) {
+		t.Logf("ExtractUpload() PASS. Expected ErrUploadMimeNotAllowed, got \"%s\"\n", err)
+	} else {
+		t.Errorf("ExtractUpload() FAILED. Expected ErrUploadMimeNotAllowed, got %v\n", err)
+	}
+
+	context = e.NewContext(newUploadRequest(t, nil, map[string][]byte{"assets.zip": buf.Bytes()}), httptest.NewRecorder())
+	_, err = ExtractUpload(context, m, "file", "uploads", "imports", filesystem.ExtractLimits{MaxTotalSize: 50})
+	if errors.As(err, &uploadErr) && uploadErr.Rule == "max_size" {
+		t.Logf("ExtractUpload() PASS. Expected max_size for an archive above the total size, got \"%s\"\n", err)
+	} else {
+		t.Errorf("ExtractUpload() FAILED. Expected max_size for an archive above the total size, got %v\n", err)
+	}
+
+	context = e.NewContext(newUploadRequest(t, map[string]string{"name": "assets"}, nil), httptest.NewRecorder())
+	if _, err = ExtractUpload(context, m, "file", "uploads", "imports", filesystem.ExtractLimits{}); errors.Is(err, ErrUploadRequired) {
+		t.Logf("ExtractUpload() PASS. Expected ErrUploadRequired, got \"%s\"\n", err)
+	} else {
+		t.Errorf("ExtractUpload() FAILED. Expected ErrUploadRequired, got %v\n", err)
+	}
+}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"github.com/labstack/echo/v4"
+	"io"
 	"net/http"
 	"os"
 	"reflect"
@@ -63,6 +64,10 @@ func ResponseSteam(context echo.Context, contentType string, file *os.File) erro
 	return context.Stream(http.StatusOK, contentType, file)
 }
 
+func ResponseReader(context echo.Context, contentType string, r io.Reader) error {
+	return context.Stream(http.StatusOK, contentType, r)
+}
+
 func ResponseNotFound(context echo.Context, vars ...interface{}) error {
 	message := "not found"
 	if len(vars) > 0 && vars[0] != nil && reflect.ValueOf(vars[0]).Kind() == reflect.String {
@@ -78,3 +83,14 @@ func ResponseNotFound(context echo.Context, vars ...interface{}) error {
 func ResponseNoContent(context echo.Context) error {
 	return context.NoContent(http.StatusNoContent)
 }
+
+func ResponseForbidden(context echo.Context, vars ...interface{}) error {
+	message := "forbidden"
+	if len(vars) > 0 && vars[0] != nil && reflect.ValueOf(vars[0]).Kind() == reflect.String {
+		message = vars[0].(string)
+	}
+	return context.JSON(
+		http.StatusForbidden,
+		map[string]interface{}{"message": message},
+	)
+}
@@ -0,0 +1,388 @@
+package http
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/h2non/filetype"
+	"github.com/kurneo/go-template/pkg/filesystem"
+	"github.com/labstack/echo/v4"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	uploadsContextKey = "uploads"
+	// maxUploadFieldSize bounds the non file fields read while streaming a multipart body
+	maxUploadFieldSize = 1 << 20
+	// defaultUploadMaxParts bounds the number of parts of a multipart body
+	defaultUploadMaxParts = 100
+)
+
+// sniffedExtensions override the mime table for generic types http.DetectContentType
+// reports, any client extension would match them (e.g. ".exe" for binary data)
+var sniffedExtensions = map[string]string{
+	"text/plain":               ".txt",
+	"application/octet-stream": "",
+}
+
+// activeUploadMimes run scripts when a browser opens them from the app origin, they are
+// only accepted when listed in UploadConfig.AllowedMimes as is
+var activeUploadMimes = map[string]bool{
+	"text/html":             true,
+	"text/xml":              true,
+	"application/xml":       true,
+	"application/xhtml+xml": true,
+	"image/svg+xml":         true,
+}
+
+var (
+	ErrUploadRequired       = errors.New("file is required")
+	ErrUploadTooLarge       = errors.New("file is too large")
+	ErrUploadMimeNotAllowed = errors.New("file type is not allowed")
+	ErrUploadFieldTooLarge  = errors.New("field is too large")
+	ErrUploadTooManyParts   = errors.New("request has too many parts")
+)
+
+// UploadError is returned for uploads failing validation, Rule is reported to clients
+// the same way validator rules are, e.g. {"avatar": ["max_size"]}
+type UploadError struct {
+	Field string
+	Rule  string
+	Err   error
+}
+
+func (e *UploadError) Error() string {
+	return fmt.Sprintf("upload \"%s\": %s", e.Field, e.Err)
+}
+
+func (e *UploadError) Unwrap() error {
+	return e.Err
+}
+
+type UploadConfig struct {
+	// Disk the files are written to, empty means the default disk
+	Disk string
+	// Dir of the disk the files are written to
+	Dir string
+	// Fields accepted as files, files of other fields are skipped. Empty accepts every field
+	Fields []string
+	// Required rejects requests without any file
+	Required bool
+	// MaxSize of a single file in bytes, zero means no limit
+	MaxSize int64
+	// AllowedMimes are matched against the type sniffed from the content, not the one
+	// sent by the client, e.g. "image/png" or "image/*". Empty accepts every type but
+	// html, xml and svg, which must be listed by their exact type
+	AllowedMimes []string
+	// Visibility of the written files, the disk default is used when empty
+	Visibility filesystem.Visibility
+	// MaxParts of the multipart body, files and other fields together, defaults to 100
+	MaxParts int
+}
+
+// UploadedFile describes a stored upload, Disk is empty for the default disk and Url is
+// only set for disks serving public urls
+type UploadedFile struct {
+	Field        string `json:"field"`
+	OriginalName string `json:"original_name"`
+	Disk         string `json:"disk,omitempty"`
+	Path         string `json:"path"`
+	Size         int64  `json:"size"`
+	Mime         string `json:"mime"`
+	Extension    string `json:"extension"`
+	Checksum     string `json:"checksum"`
+	Url          string `json:"url,omitempty"`
+}
+
+// StoreUploads streams every file of a multipart request to the configured disk without
+// buffering it in memory or temporary files. Non file fields stay readable through
+// context.FormValue and ParseFormData. Files already stored are deleted when any file fails
+func StoreUploads(context echo.Context, m *filesystem.Manager, c UploadConfig) ([]UploadedFile, error) {
+	disk, err := m.Disk(c.Disk)
+	if err != nil {
+		return nil, err
+	}
+
+	req := context.Request()
+	reader, err := req.MultipartReader()
+	if err != nil {
+		if c.Required {
+			return nil, &UploadError{Field: uploadField(c), Rule: "required", Err: ErrUploadRequired}
+		}
+		return make([]UploadedFile, 0), nil
+	}
+
+	values := make(url.Values)
+	uploads := make([]UploadedFile, 0)
+	fail := func(err error) ([]UploadedFile, error) {
+		for _, u := range uploads {
+			_ = disk.Delete(u.Path)
+		}
+		return nil, err
+	}
+
+	maxParts := c.MaxParts
+	if maxParts <= 0 {
+		maxParts = defaultUploadMaxParts
+	}
+	for parts := 0; ; parts++ {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fail(err)
+		}
+		if parts >= maxParts {
+			return fail(&UploadError{Field: part.FormName(), Rule: "max_parts", Err: ErrUploadTooManyParts})
+		}
+
+		if part.FileName() == "" {
+			b, err := io.ReadAll(io.LimitReader(part, maxUploadFieldSize+1))
+			if err != nil {
+				return fail(err)
+			}
+			if len(b) > maxUploadFieldSize {
+				return fail(&UploadError{Field: part.FormName(), Rule: "max_size", Err: ErrUploadFieldTooLarge})
+			}
+			values.Add(part.FormName(), string(b))
+			continue
+		}
+
+		if !acceptsUploadField(c, part.FormName()) {
+			continue
+		}
+
+		u, err := storeUpload(disk, part, c)
+		if err != nil {
+			return fail(err)
+		}
+		u.Disk = c.Disk
+		uploads = append(uploads, *u)
+	}
+
+	if c.Required && len(uploads) == 0 {
+		return fail(&UploadError{Field: uploadField(c), Rule: "required", Err: ErrUploadRequired})
+	}
+
+	setFormValues(req, values)
+	return uploads, nil
+}
+
+// UploadMiddleware stores the uploads of the request with StoreUploads before calling the
+// handler, which reads them with GetUploads. Invalid uploads are answered with 422
+func UploadMiddleware(m *filesystem.Manager, c UploadConfig) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(context echo.Context) error {
+			uploads, err := StoreUploads(context, m, c)
+			if err != nil {
+				var uErr *UploadError
+				if errors.As(err, &uErr) {
+					return ResponseUnprocessableEntity(context, map[string][]string{uErr.Field: {uErr.Rule}})
+				}
+				return err
+			}
+			context.Set(uploadsContextKey, uploads)
+			return next(context)
+		}
+	}
+}
+
+// GetUploads returns the files stored by UploadMiddleware
+func GetUploads(context echo.Context) []UploadedFile {
+	if uploads, ok := context.Get(uploadsContextKey).([]UploadedFile); ok {
+		return uploads
+	}
+	return make([]UploadedFile, 0)
+}
+
+func storeUpload(disk filesystem.DriverContract, part *multipart.Part, c UploadConfig) (*UploadedFile, error) {
+	field := part.FormName()
+
+	br := bufio.NewReaderSize(part, 512)
+	head, _ := br.Peek(512)
+	contentType, ext := sniffUpload(head, part.FileName())
+	if !uploadMimeAllowed(contentType, c.AllowedMimes) {
+		return nil, &UploadError{Field: field, Rule: "mimes", Err: ErrUploadMimeNotAllowed}
+	}
+
+	name, err := uploadName(ext)
+	if err != nil {
+		return nil, err
+	}
+	p := path.Join(c.Dir, name)
+
+	h := sha256.New()
+	lr := &sizeLimitReader{r: br, max: c.MaxSize}
+	err = disk.PutStream(p, io.TeeReader(lr, h), filesystem.PutOptions{
+		ContentType: contentType,
+		Visibility:  c.Visibility,
+	})
+	if err != nil {
+		// drivers discard failed writes, deleting covers stores keeping partial objects
+		_ = disk.Delete(p)
+		// object store clients wrap reader errors, the counter tells what happened
+		if lr.exceeded() {
+			return nil, &UploadError{Field: field, Rule: "max_size", Err: ErrUploadTooLarge}
+		}
+		return nil, err
+	}
+
+	u := &UploadedFile{
+		Field:        field,
+		OriginalName: part.FileName(),
+		Path:         p,
+		Size:         lr.n,
+		Mime:         contentType,
+		Extension:    ext,
+		Checksum:     hex.EncodeToString(h.Sum(nil)),
+	}
+	if d, ok := disk.(interface{ Url(path string) string }); ok {
+		u.Url = d.Url(p)
+	}
+	return u, nil
+}
+
+// sniffUpload detects the type from the first bytes, the extension always follows the
+// detected type so a text file named "x.html" is never stored as html
+func sniffUpload(head []byte, filename string) (string, string) {
+	if kind, err := filetype.Match(head); err == nil && kind != filetype.Unknown {
+		return kind.MIME.Value, "." + kind.Extension
+	}
+
+	contentType := http.DetectContentType(head)
+	if t, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = t
+	}
+	return contentType, sniffedExtension(contentType, filepath.Ext(filename))
+}
+
+// sniffedExtension returns the extension of the client name when it belongs to contentType,
+// otherwise the first one known for contentType, empty when there is none
+func sniffedExtension(contentType, clientExt string) string {
+	if ext, ok := sniffedExtensions[contentType]; ok {
+		return ext
+	}
+	clientExt = safeExtension(clientExt)
+	exts, _ := mime.ExtensionsByType(contentType)
+	for _, ext := range exts {
+		if ext == clientExt {
+			return ext
+		}
+	}
+	if len(exts) > 0 {
+		return safeExtension(exts[0])
+	}
+	return ""
+}
+
+func uploadMimeAllowed(contentType string, allowed []string) bool {
+	if activeUploadMimes[contentType] {
+		for _, a := range allowed {
+			if a == contentType {
+				return true
+			}
+		}
+		return false
+	}
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if a == contentType {
+			return true
+		}
+		if strings.HasSuffix(a, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(a, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// safeExtension keeps short alphanumeric extensions only, so client names never reach the disk
+func safeExtension(ext string) string {
+	ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+	if ext == "" || len(ext) > 10 {
+		return ""
+	}
+	for _, r := range ext {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return ""
+		}
+	}
+	return "." + ext
+}
+
+// uploadName returns a random name, uploads never overwrite each other
+func uploadName(ext string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b) + ext, nil
+}
+
+func acceptsUploadField(c UploadConfig, field string) bool {
+	if len(c.Fields) == 0 {
+		return true
+	}
+	for _, f := range c.Fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func uploadField(c UploadConfig) string {
+	if len(c.Fields) > 0 {
+		return c.Fields[0]
+	}
+	return "file"
+}
+
+// setFormValues exposes the fields read from the multipart body the way
+// ParseMultipartForm would, the body itself can no longer be parsed
+func setFormValues(req *http.Request, values url.Values) {
+	form := make(url.Values)
+	for k, v := range req.URL.Query() {
+		form[k] = append(form[k], v...)
+	}
+	for k, v := range values {
+		form[k] = append(form[k], v...)
+	}
+	req.PostForm = values
+	req.Form = form
+	req.MultipartForm = &multipart.Form{Value: values, File: make(map[string][]*multipart.FileHeader)}
+}
+
+// sizeLimitReader counts the bytes read and fails once more than max are read, so
+// oversized uploads are aborted by the disk instead of being stored
+type sizeLimitReader struct {
+	r   io.Reader
+	max int64
+	n   int64
+}
+
+func (l *sizeLimitReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	if l.exceeded() {
+		return n, ErrUploadTooLarge
+	}
+	return n, err
+}
+
+func (l *sizeLimitReader) exceeded() bool {
+	return l.max > 0 && l.n > l.max
+}
@@ -0,0 +1,201 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/kurneo/go-template/pkg/filesystem"
+	"github.com/labstack/echo/v4"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var pngContent = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 64)...)
+
+func setupUploads(t *testing.T) (*filesystem.Manager, filesystem.DriverContract) {
+	m, err := filesystem.New(filesystem.Config{}, nil)
+	if err != nil {
+		t.Fatalf("filesystem.New() FAILED. Unexpected error \"%s\"", err)
+	}
+	d := filesystem.NewDriverMemory()
+	m.Extend("uploads", d)
+	return m, d
+}
+
+func newUploadRequest(t *testing.T, fields map[string]string, files map[string][]byte) *http.Request {
+	body := new(bytes.Buffer)
+	w := multipart.NewWriter(body)
+	for k, v := range fields {
+		_ = w.WriteField(k, v)
+	}
+	for name, content := range files {
+		f, err := w.CreateFormFile("file", name)
+		if err != nil {
+			t.Fatalf("CreateFormFile() FAILED. Unexpected error \"%s\"", err)
+		}
+		_, _ = f.Write(content)
+	}
+	_ = w.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set(echo.HeaderContentType, w.FormDataContentType())
+	return req
+}
+
+func TestStoreUploads(t *testing.T) {
+	m, d := setupUploads(t)
+	e := echo.New()
+	c := UploadConfig{Disk: "uploads", Dir: "avatars", AllowedMimes: []string{"image/*"}}
+
+	req := newUploadRequest(t, map[string]string{"title": "me"}, map[string][]byte{"../../me.PNG": pngContent})
+	context := e.NewContext(req, httptest.NewRecorder())
+
+	uploads, err := StoreUploads(context, m, c)
+	if err != nil || len(uploads) != 1 {
+		t.Fatalf("StoreUploads() FAILED. Expected 1 upload, got %v, %v", uploads, err)
+	}
+
+	u := uploads[0]
+	sum := sha256.Sum256(pngContent)
+	if u.Mime == "image/png" && u.Extension == ".png" && u.Size == int64(len(pngContent)) && u.Checksum == hex.EncodeToString(sum[:]) {
+		t.Logf("StoreUploads() PASS. Got %+v", u)
+	} else {
+		t.Errorf("StoreUploads() FAILED. Expected png metadata, got %+v", u)
+	}
+
+	if strings.HasPrefix(u.Path, "avatars/") && !strings.Contains(u.Path, "me") {
+		t.Logf("StoreUploads() PASS. Expected a generated name, got \"%s\"", u.Path)
+	} else {
+		t.Errorf("StoreUploads() FAILED. Expected a generated name below avatars/, got \"%s\"", u.Path)
+	}
+
+	if content, err := d.Get(u.Path); err == nil && bytes.Equal(content, pngContent) {
+		t.Logf("StoreUploads() PASS. Expected content to be stored")
+	} else {
+		t.Errorf("StoreUploads() FAILED. Expected content to be stored, got %v", err)
+	}
+
+	if v := context.FormValue("title"); v == "me" {
+		t.Logf("FormValue(\"title\") PASS. Expected \"me\", got \"%s\"", v)
+	} else {
+		t.Errorf("FormValue(\"title\") FAILED. Expected \"me\", got \"%s\"", v)
+	}
+}
+
+func TestUploadMiddleware(t *testing.T) {
+	m, d := setupUploads(t)
+	e := echo.New()
+	c := UploadConfig{Disk: "uploads", Dir: "docs", Required: true, MaxSize: 32, AllowedMimes: []string{"image/png", "text/plain"}}
+
+	handler := UploadMiddleware(m, c)(func(context echo.Context) error {
+		return ResponseOk(context, GetUploads(context))
+	})
+
+	for name, tc := range map[string]struct {
+		files  map[string][]byte
+		status int
+		rule   string
+	}{
+		"text":      {files: map[string][]byte{"a.txt": []byte("hello")}, status: http.StatusOK},
+		"too large": {files: map[string][]byte{"a.png": pngContent}, status: http.StatusUnprocessableEntity, rule: "max_size"},
+		"mime":      {files: map[string][]byte{"a.gif": []byte("GIF89a\x01\x00\x01\x00")}, status: http.StatusUnprocessableEntity, rule: "mimes"},
+		"required":  {files: map[string][]byte{}, status: http.StatusUnprocessableEntity, rule: "required"},
+	} {
+		rec := httptest.NewRecorder()
+		err := handler(e.NewContext(newUploadRequest(t, nil, tc.files), rec))
+		if err == nil && rec.Code == tc.status && strings.Contains(rec.Body.String(), tc.rule) {
+			t.Logf("UploadMiddleware() %s PASS. Expected %d, got %d %s", name, tc.status, rec.Code, rec.Body.String())
+		} else {
+			t.Errorf("UploadMiddleware() %s FAILED. Expected %d with \"%s\", got %d %s, %v", name, tc.status, tc.rule, rec.Code, rec.Body.String(), err)
+		}
+	}
+
+	files, _, _ := d.ListContentsRecursive("docs")
+	if len(files) == 1 && *files[0].Extension == ".txt" {
+		t.Logf("UploadMiddleware() PASS. Expected only the accepted file to be stored")
+	} else {
+		t.Errorf("UploadMiddleware() FAILED. Expected only the accepted file to be stored, got %v", files)
+	}
+}
+
+func TestSniffUpload(t *testing.T) {
+	for name, tc := range map[string]struct {
+		head        []byte
+		contentType string
+		ext         string
+	}{
+		"x.html": {head: []byte("<html><body>page</body></html>"), contentType: "text/html", ext: ".html"},
+		"y.html": {head: []byte("plain words"), contentType: "text/plain", ext: ".txt"},
+		"z.txt":  {head: []byte("plain words"), contentType: "text/plain", ext: ".txt"},
+		"a.png":  {head: []byte("plain words"), contentType: "text/plain", ext: ".txt"},
+		"b.exe":  {head: []byte{0x00, 0x01, 0x02}, contentType: "application/octet-stream", ext: ""},
+	} {
+		contentType, ext := sniffUpload(tc.head, name)
+		if contentType == tc.contentType && ext == tc.ext {
+			t.Logf("sniffUpload(\"%s\") PASS. Expected \"%s\" \"%s\"", name, tc.contentType, tc.ext)
+		} else {
+			t.Errorf("sniffUpload(\"%s\") FAILED. Expected \"%s\" \"%s\", got \"%s\" \"%s\"", name, tc.contentType, tc.ext, contentType, ext)
+		}
+	}
+}
+
+func TestUploadMimeAllowed(t *testing.T) {
+	for _, tc := range []struct {
+		contentType string
+		allowed     []string
+		expect      bool
+	}{
+		{contentType: "image/png", allowed: nil, expect: true},
+		{contentType: "text/html", allowed: nil, expect: false},
+		{contentType: "image/svg+xml", allowed: nil, expect: false},
+		{contentType: "image/svg+xml", allowed: []string{"image/*"}, expect: false},
+		{contentType: "image/svg+xml", allowed: []string{"image/svg+xml"}, expect: true},
+		{contentType: "image/png", allowed: []string{"image/*"}, expect: true},
+	} {
+		if actual := uploadMimeAllowed(tc.contentType, tc.allowed); actual == tc.expect {
+			t.Logf("uploadMimeAllowed(\"%s\", %v) PASS. Expected %t", tc.contentType, tc.allowed, tc.expect)
+		} else {
+			t.Errorf("uploadMimeAllowed(\"%s\", %v) FAILED. Expected %t, got %t", tc.contentType, tc.allowed, tc.expect, actual)
+		}
+	}
+}
+
+func TestStoreUploadsHtml(t *testing.T) {
+	m, d := setupUploads(t)
+	e := echo.New()
+
+	req := newUploadRequest(t, nil, map[string][]byte{"page.html": []byte("<html><script>alert(1)</script></html>")})
+	_, err := StoreUploads(e.NewContext(req, httptest.NewRecorder()), m, UploadConfig{Disk: "uploads"})
+	files, _, _ := d.ListContentsRecursive("")
+	if errors.Is(err, ErrUploadMimeNotAllowed) && len(files) == 0 {
+		t.Logf("StoreUploads() PASS. Expected html rejected without AllowedMimes, got \"%s\"", err)
+	} else {
+		t.Errorf("StoreUploads() FAILED. Expected ErrUploadMimeNotAllowed and no files, got %v with %d files", err, len(files))
+	}
+}
+
+func TestStoreUploadsLimits(t *testing.T) {
+	m, _ := setupUploads(t)
+	e := echo.New()
+
+	fields := map[string]string{"bio": strings.Repeat("a", maxUploadFieldSize+1)}
+	_, err := StoreUploads(e.NewContext(newUploadRequest(t, fields, nil), httptest.NewRecorder()), m, UploadConfig{Disk: "uploads"})
+	var uErr *UploadError
+	if errors.As(err, &uErr) && uErr.Field == "bio" && uErr.Rule == "max_size" {
+		t.Logf("StoreUploads() PASS. Expected max_size for a large field, got \"%s\"", err)
+	} else {
+		t.Errorf("StoreUploads() FAILED. Expected max_size for a large field, got %v", err)
+	}
+
+	fields = map[string]string{"a": "1", "b": "2", "c": "3"}
+	_, err = StoreUploads(e.NewContext(newUploadRequest(t, fields, nil), httptest.NewRecorder()), m, UploadConfig{Disk: "uploads", MaxParts: 2})
+	if errors.As(err, &uErr) && uErr.Rule == "max_parts" {
+		t.Logf("StoreUploads() PASS. Expected max_parts, got \"%s\"", err)
+	} else {
+		t.Errorf("StoreUploads() FAILED. Expected max_parts, got %v", err)
+	}
+}
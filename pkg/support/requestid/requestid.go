@@ -0,0 +1,76 @@
+package requestid
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"net/http"
+)
+
+// Header carries the correlation id between services
+const Header = "X-Request-ID"
+
+const maxLength = 128
+
+type ctxKey struct{}
+
+// NewContext returns a copy of ctx carrying the request id
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext returns the request id stored in ctx, or an empty string
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// Generate creates a new random request id
+func Generate() string {
+	return uuid.New().String()
+}
+
+// IsValid reports whether an incoming id is safe to reuse, ids are echoed
+// back in headers and written to logs so only a restricted charset is allowed
+func IsValid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Transport forwards the request id of the outgoing request context to the called service
+type Transport struct {
+	Base http.RoundTripper
+}
+
+func (t Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	id := FromContext(r.Context())
+	if id == "" || r.Header.Get(Header) != "" {
+		return base.RoundTrip(r)
+	}
+	clone := r.Clone(r.Context())
+	clone.Header.Set(Header, id)
+	return base.RoundTrip(clone)
+}
+
+// NewHttpClient returns a http client propagating request ids on outbound calls
+func NewHttpClient() *http.Client {
+	return &http.Client{
+		Transport: Transport{},
+	}
+}
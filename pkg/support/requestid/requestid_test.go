@@ -0,0 +1,60 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIsValid(t *testing.T) {
+	cases := map[string]bool{
+		Generate():               true,
+		"trace_1.2:3":            true,
+		"":                       false,
+		"a b":                    false,
+		"line\nbreak":            false,
+		"<script>":               false,
+		strings.Repeat("a", 128): true,
+		strings.Repeat("a", 129): false,
+	}
+	for id, expect := range cases {
+		if IsValid(id) == expect {
+			t.Logf("IsValid(\"%s\") PASS. Expected %v\n", id, expect)
+		} else {
+			t.Errorf("IsValid(\"%s\") FAILED. Expected %v, got %v\n", id, expect, !expect)
+		}
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get(Header))
+	}))
+	defer srv.Close()
+	client := NewHttpClient()
+
+	send := func(ctx context.Context, header string) {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+		if header != "" {
+			req.Header.Set(Header, header)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Do() FAILED. Unexpected error \"%s\"", err)
+		}
+		_ = resp.Body.Close()
+	}
+	send(NewContext(context.Background(), "abc"), "")
+	send(NewContext(context.Background(), "abc"), "explicit")
+	send(context.Background(), "")
+
+	expect := []string{"abc", "explicit", ""}
+	if strings.Join(got, ",") == strings.Join(expect, ",") {
+		t.Logf("Transport PASS. Expected %v, got %v\n", expect, got)
+	} else {
+		t.Errorf("Transport FAILED. Expected %v, got %v\n", expect, got)
+	}
+}
@@ -1,16 +1,21 @@
 package pkg
 
 import (
+	"encoding/json"
 	"github.com/google/wire"
 	"github.com/kurneo/go-template/pkg/cache"
 	"github.com/kurneo/go-template/pkg/database"
+	"github.com/kurneo/go-template/pkg/dedup"
+	"github.com/kurneo/go-template/pkg/filesystem"
 	"github.com/kurneo/go-template/pkg/hashing"
+	imagePkg "github.com/kurneo/go-template/pkg/image"
 	"github.com/kurneo/go-template/pkg/jwt"
 	logPkg "github.com/kurneo/go-template/pkg/log"
 	"github.com/kurneo/go-template/pkg/middlewares"
 	"github.com/labstack/echo/v4"
 	"github.com/spf13/viper"
 	"log"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -23,21 +28,33 @@ var WireSet = wire.NewSet(
 	ResolveTokenManager,
 	ResolveJWTMiddlewareFunc,
 	ResolveHashingInstance,
+	ResolveFilesystemManager,
 	ResolveEcho,
 )
 
 // ResolveCacheInstance resolve dependencies and create cache instance
-func ResolveCacheInstance() cache.Contact {
+func ResolveCacheInstance(db database.Contract) cache.Contact {
 	cfg := cache.Config{
-		Driver: viper.GetString("CACHE_DRIVER"),
-		Redis: struct {
-			Host string
-			Port int
-			DB   int
-		}{
-			Host: viper.GetString("CACHE_REDIS_HOST"),
-			Port: viper.GetInt("CACHE_REDIS_PORT"),
-			DB:   viper.GetInt("CACHE_REDIS_DB"),
+		Driver:  viper.GetString("CACHE_DRIVER"),
+		Prefix:  viper.GetString("CACHE_PREFIX"),
+		Metrics: viper.GetBool("CACHE_METRICS_ENABLE"),
+		Redis: cache.RedisConfig{
+			Mode:             viper.GetString("CACHE_REDIS_MODE"),
+			Host:             viper.GetString("CACHE_REDIS_HOST"),
+			Port:             viper.GetInt("CACHE_REDIS_PORT"),
+			Addrs:            splitNonEmpty(viper.GetString("CACHE_REDIS_ADDRS")),
+			MasterName:       viper.GetString("CACHE_REDIS_MASTER_NAME"),
+			Username:         viper.GetString("CACHE_REDIS_USERNAME"),
+			Password:         viper.GetString("CACHE_REDIS_PASSWORD"),
+			SentinelPassword: viper.GetString("CACHE_REDIS_SENTINEL_PASSWORD"),
+			DB:               viper.GetInt("CACHE_REDIS_DB"),
+			TLS:              viper.GetBool("CACHE_REDIS_TLS"),
+			TLSSkipVerify:    viper.GetBool("CACHE_REDIS_TLS_SKIP_VERIFY"),
+			PoolSize:         viper.GetInt("CACHE_REDIS_POOL_SIZE"),
+			MinIdleConns:     viper.GetInt("CACHE_REDIS_MIN_IDLE_CONNS"),
+			DialTimeout:      viper.GetDuration("CACHE_REDIS_DIAL_TIMEOUT"),
+			ReadTimeout:      viper.GetDuration("CACHE_REDIS_READ_TIMEOUT"),
+			WriteTimeout:     viper.GetDuration("CACHE_REDIS_WRITE_TIMEOUT"),
 		},
 		InMemory: struct {
 			DefaultExpiration      time.Duration
@@ -46,8 +63,31 @@ func ResolveCacheInstance() cache.Contact {
 			DefaultExpiration:      viper.GetDuration("CACHE_IN_MEMORY_DEFAULT_EXPIRATION"),
 			DefaultCleanUpInterval: viper.GetDuration("CACHE_IN_MEMORY_CLEANUP_INTERVAL"),
 		},
+		Tiered: struct {
+			LocalExpiration      time.Duration
+			LocalCleanUpInterval time.Duration
+			InvalidationChannel  string
+		}{
+			LocalExpiration:      viper.GetDuration("CACHE_TIERED_LOCAL_EXPIRATION"),
+			LocalCleanUpInterval: viper.GetDuration("CACHE_TIERED_LOCAL_CLEANUP_INTERVAL"),
+			InvalidationChannel:  viper.GetString("CACHE_TIERED_INVALIDATION_CHANNEL"),
+		},
+		File: struct {
+			Path                   string
+			DefaultCleanUpInterval time.Duration
+		}{
+			Path:                   viper.GetString("CACHE_FILE_PATH"),
+			DefaultCleanUpInterval: viper.GetDuration("CACHE_FILE_CLEANUP_INTERVAL"),
+		},
+		Database: struct {
+			Table                  string
+			DefaultCleanUpInterval time.Duration
+		}{
+			Table:                  viper.GetString("CACHE_DATABASE_TABLE"),
+			DefaultCleanUpInterval: viper.GetDuration("CACHE_DATABASE_CLEANUP_INTERVAL"),
+		},
 	}
-	c, err := cache.New(cfg)
+	c, err := cache.New(cfg, db)
 	if err != nil {
 		log.Fatalf("init cache error: %s", err)
 	}
@@ -55,7 +95,7 @@ func ResolveCacheInstance() cache.Contact {
 }
 
 // ResolveDatabaseInstance resolve global database instance
-func ResolveDatabaseInstance() database.Contract {
+func ResolveDatabaseInstance(l logPkg.Contract) database.Contract {
 	c := database.Config{
 		Driver: viper.GetString("DB_DRIVER"),
 		PgSql: struct {
@@ -87,7 +127,7 @@ func ResolveDatabaseInstance() database.Contract {
 		},
 	}
 
-	d, err := database.New(c)
+	d, err := database.New(c, l)
 	if err != nil {
 		log.Fatalf("init database error: %s", err)
 	}
@@ -98,36 +138,70 @@ func ResolveDatabaseInstance() database.Contract {
 func ResolveLogInstance() logPkg.Contract {
 	c := logPkg.Config{
 		Channel: viper.GetString("LOG_DEFAULT_CHANNEL"),
-		Daily: struct {
-			FileName string
-			Level    string
-		}{
-			FileName: viper.GetString("LOG_DAILY_FILE_NAME"),
-			Level:    viper.GetString("LOG_DAILY_LOG_LEVEL"),
+		Path:    viper.GetString("LOG_PATH"),
+		Daily: logPkg.DailyConfig{
+			FileName:  viper.GetString("LOG_DAILY_FILE_NAME"),
+			Level:     viper.GetString("LOG_DAILY_LOG_LEVEL"),
+			Formatter: viper.GetString("LOG_DAILY_FORMATTER"),
+			MaxAge:    viper.GetDuration("LOG_DAILY_MAX_AGE"),
+			MaxFiles:  viper.GetInt("LOG_DAILY_MAX_FILES"),
+			MaxSize:   viper.GetInt("LOG_DAILY_MAX_SIZE"),
+			Compress:  viper.GetBool("LOG_DAILY_COMPRESS"),
 		},
-		Singe: struct {
-			FileName string
-			Level    string
-		}{
-			FileName: viper.GetString("LOG_SINGLE_FILE_NAME"),
-			Level:    viper.GetString("LOG_SINGLE_LOG_LEVEL"),
-		},
-		StdOut: struct{ Level string }{
-			Level: viper.GetString("LOG_STDOUT_LOG_LEVEL"),
-		},
-		TeleHookConfig: struct {
-			Enable   bool
-			BotToken string
-			ChatID   string
-			Level    string
-			Mentions string
-		}{
+		Singe: logPkg.SingeConfig{
+			FileName:  viper.GetString("LOG_SINGLE_FILE_NAME"),
+			Level:     viper.GetString("LOG_SINGLE_LOG_LEVEL"),
+			Formatter: viper.GetString("LOG_SINGLE_FORMATTER"),
+			MaxAge:    viper.GetDuration("LOG_SINGLE_MAX_AGE"),
+			MaxFiles:  viper.GetInt("LOG_SINGLE_MAX_FILES"),
+			MaxSize:   viper.GetInt("LOG_SINGLE_MAX_SIZE"),
+			Compress:  viper.GetBool("LOG_SINGLE_COMPRESS"),
+		},
+		StdOut: logPkg.StdOutConfig{
+			Level:     viper.GetString("LOG_STDOUT_LOG_LEVEL"),
+			Formatter: viper.GetString("LOG_STDOUT_FORMATTER"),
+		},
+		Stack: logPkg.StackConfig{
+			Channels: splitNonEmpty(viper.GetString("LOG_STACK_CHANNELS")),
+		},
+		HookOptions: logPkg.HookOptions{
+			BatchSize:     viper.GetInt("LOG_HOOK_BATCH_SIZE"),
+			FlushInterval: viper.GetDuration("LOG_HOOK_FLUSH_INTERVAL"),
+			RateLimit:     viper.GetInt("LOG_HOOK_RATE_LIMIT"),
+			MaxRetries:    viper.GetInt("LOG_HOOK_MAX_RETRIES"),
+			RetryBackoff:  viper.GetDuration("LOG_HOOK_RETRY_BACKOFF"),
+			DedupWindow:   viper.GetDuration("LOG_HOOK_DEDUP_WINDOW"),
+			QueueSize:     viper.GetInt("LOG_HOOK_QUEUE_SIZE"),
+		},
+		TeleHookConfig: logPkg.TeleHookConfig{
 			Enable:   viper.GetBool("LOG_HOOK_TELE_ENABLE"),
 			BotToken: viper.GetString("LOG_HOOK_TELE_BOT_TOKEN"),
 			ChatID:   viper.GetString("LOG_HOOK_TELE_CHAT_ID"),
 			Level:    viper.GetString("LOG_HOOK_TELE_LEVEL"),
 			Mentions: viper.GetString("LOG_HOOK_TELE_MENTIONS"),
 		},
+		WebhookHook: logPkg.WebhookHookConfig{
+			Enable:  viper.GetBool("LOG_HOOK_WEBHOOK_ENABLE"),
+			Url:     viper.GetString("LOG_HOOK_WEBHOOK_URL"),
+			Level:   viper.GetString("LOG_HOOK_WEBHOOK_LEVEL"),
+			Headers: splitKeyValues(viper.GetString("LOG_HOOK_WEBHOOK_HEADERS")),
+		},
+		SlackHook: logPkg.SlackHookConfig{
+			Enable:     viper.GetBool("LOG_HOOK_SLACK_ENABLE"),
+			WebhookUrl: viper.GetString("LOG_HOOK_SLACK_WEBHOOK_URL"),
+			Channel:    viper.GetString("LOG_HOOK_SLACK_CHANNEL"),
+			Username:   viper.GetString("LOG_HOOK_SLACK_USERNAME"),
+			IconEmoji:  viper.GetString("LOG_HOOK_SLACK_ICON_EMOJI"),
+			Level:      viper.GetString("LOG_HOOK_SLACK_LEVEL"),
+			Mentions:   viper.GetString("LOG_HOOK_SLACK_MENTIONS"),
+		},
+		Redact: logPkg.RedactConfig{
+			Disable:  viper.GetBool("LOG_REDACT_DISABLE"),
+			Keys:     splitNonEmpty(viper.GetString("LOG_REDACT_KEYS")),
+			Patterns: splitPatterns(viper.GetString("LOG_REDACT_PATTERNS")),
+		},
+		LevelRevertAfter: viper.GetDuration("LOG_LEVEL_REVERT_AFTER"),
+		LevelAdmins:      splitIDs(viper.GetString("LOG_LEVEL_ADMIN_IDS")),
 	}
 	l, err := logPkg.New(c)
 	if err != nil {
@@ -146,8 +220,13 @@ func ResolveTokenManager(c cache.Contact) *jwt.TokenManager[int64] {
 }
 
 // ResolveJWTMiddlewareFunc resolve global echo jwt middleware
-func ResolveJWTMiddlewareFunc(t *jwt.TokenManager[int64]) echo.MiddlewareFunc {
-	return middlewares.JwtMiddleware(t)
+func ResolveJWTMiddlewareFunc(t *jwt.TokenManager[int64], fs *filesystem.Manager) echo.MiddlewareFunc {
+	var excepts []string
+	if s := fs.Signer(); s != nil {
+		// signed urls carry their own authorization, only their route is skipped
+		excepts = append(excepts, s.Route())
+	}
+	return middlewares.JwtMiddleware(t, excepts...)
 }
 
 // ResolveHashingInstance  resolve global hashing instance
@@ -177,8 +256,104 @@ func ResolveHashingInstance() hashing.Contact {
 	return s
 }
 
+// ResolveFilesystemManager resolve global filesystem manager holding the configured disks
+func ResolveFilesystemManager(l logPkg.Contract) *filesystem.Manager {
+	c := filesystem.Config{
+		Default: viper.GetString("FILESYSTEM_DISK"),
+		S3: filesystem.S3Config{
+			Region:            viper.GetString("FILESYSTEM_S3_REGION"),
+			Bucket:            viper.GetString("FILESYSTEM_S3_BUCKET"),
+			Key:               viper.GetString("FILESYSTEM_S3_KEY"),
+			Secret:            viper.GetString("FILESYSTEM_S3_SECRET"),
+			Endpoint:          viper.GetString("FILESYSTEM_S3_ENDPOINT"),
+			UsePathStyle:      viper.GetBool("FILESYSTEM_S3_USE_PATH_STYLE"),
+			Prefix:            viper.GetString("FILESYSTEM_S3_PREFIX"),
+			Url:               viper.GetString("FILESYSTEM_S3_URL"),
+			PartSize:          viper.GetInt64("FILESYSTEM_S3_PART_SIZE"),
+			UploadConcurrency: viper.GetInt("FILESYSTEM_S3_UPLOAD_CONCURRENCY"),
+			Visibility:        filesystem.Visibility(viper.GetString("FILESYSTEM_S3_VISIBILITY")),
+		},
+		Minio: filesystem.MinioConfig{
+			Endpoint:   viper.GetString("FILESYSTEM_MINIO_ENDPOINT"),
+			AccessKey:  viper.GetString("FILESYSTEM_MINIO_ACCESS_KEY"),
+			SecretKey:  viper.GetString("FILESYSTEM_MINIO_SECRET_KEY"),
+			UseSSL:     viper.GetBool("FILESYSTEM_MINIO_USE_SSL"),
+			Region:     viper.GetString("FILESYSTEM_MINIO_REGION"),
+			Bucket:     viper.GetString("FILESYSTEM_MINIO_BUCKET"),
+			Prefix:     viper.GetString("FILESYSTEM_MINIO_PREFIX"),
+			Url:        viper.GetString("FILESYSTEM_MINIO_URL"),
+			PartSize:   viper.GetUint64("FILESYSTEM_MINIO_PART_SIZE"),
+			Visibility: filesystem.Visibility(viper.GetString("FILESYSTEM_MINIO_VISIBILITY")),
+		},
+	}
+	c.Local.Root = viper.GetString("FILESYSTEM_LOCAL_ROOT")
+	c.Local.Visibility = filesystem.Visibility(viper.GetString("FILESYSTEM_LOCAL_VISIBILITY"))
+	c.Public.Root = viper.GetString("FILESYSTEM_PUBLIC_ROOT")
+	c.Public.Url = viper.GetString("FILESYSTEM_PUBLIC_URL")
+	c.Public.Visibility = filesystem.Visibility(viper.GetString("FILESYSTEM_PUBLIC_VISIBILITY"))
+	c.UrlSigning.Key = viper.GetString("FILESYSTEM_URL_SIGNING_KEY")
+	c.UrlSigning.Url = viper.GetString("FILESYSTEM_URL_SIGNING_URL")
+
+	keys, err := filesystem.ParseEncryptionKeys(viper.GetString("FILESYSTEM_ENCRYPTION_KEYS"))
+	if err != nil {
+		log.Fatalf("init filesystem error: %s", err)
+	}
+	c.Encryption = filesystem.EncryptionConfig{
+		Disk:      viper.GetString("FILESYSTEM_ENCRYPTION_DISK"),
+		KeyId:     viper.GetString("FILESYSTEM_ENCRYPTION_KEY_ID"),
+		Keys:      keys,
+		ChunkSize: viper.GetInt("FILESYSTEM_ENCRYPTION_CHUNK_SIZE"),
+	}
+
+	m, err := filesystem.New(c, l)
+	if err != nil {
+		log.Fatalf("init filesystem error: %s", err)
+	}
+	return m
+}
+
+// ResolveImageProcessor resolve global image processor writing preset variants to a disk,
+// it is not part of WireSet, add it to the set of the module using it
+func ResolveImageProcessor(fs *filesystem.Manager) *imagePkg.Processor {
+	presets, err := imagePkg.ParsePresets(viper.GetString("IMAGE_PRESETS"))
+	if err != nil {
+		log.Fatalf("init image error: %s", err)
+	}
+
+	p, err := imagePkg.New(imagePkg.Config{
+		SourceDisk: viper.GetString("IMAGE_SOURCE_DISK"),
+		Disk:       viper.GetString("IMAGE_DISK"),
+		Dir:        viper.GetString("IMAGE_DIR"),
+		Presets:    presets,
+		MaxPixels:  viper.GetInt("IMAGE_MAX_PIXELS"),
+		Visibility: filesystem.Visibility(viper.GetString("IMAGE_VISIBILITY")),
+	}, fs)
+	if err != nil {
+		log.Fatalf("init image error: %s", err)
+	}
+	return p
+}
+
+// ResolveDedupStore resolve global content addressed store deduplicating files on a disk,
+// it is not part of WireSet, add it to the set of the module using it
+func ResolveDedupStore(fs *filesystem.Manager, db database.Contract) *dedup.Store {
+	s, err := dedup.New(dedup.Config{
+		Disk:            viper.GetString("DEDUP_DISK"),
+		Dir:             viper.GetString("DEDUP_DIR"),
+		FilesTable:      viper.GetString("DEDUP_FILES_TABLE"),
+		BlobsTable:      viper.GetString("DEDUP_BLOBS_TABLE"),
+		GracePeriod:     viper.GetDuration("DEDUP_GRACE_PERIOD"),
+		CleanupInterval: viper.GetDuration("DEDUP_CLEANUP_INTERVAL"),
+		Visibility:      filesystem.Visibility(viper.GetString("DEDUP_VISIBILITY")),
+	}, fs, db)
+	if err != nil {
+		log.Fatalf("init dedup error: %s", err)
+	}
+	return s
+}
+
 // ResolveEcho resolve global echo instance
-func ResolveEcho(jwtMiddleware echo.MiddlewareFunc) *echo.Echo {
+func ResolveEcho(lg logPkg.Contract, jwtMiddleware echo.MiddlewareFunc) *echo.Echo {
 	echoApp := echo.New()
 	// configure global middleware here
 
@@ -196,7 +371,15 @@ func ResolveEcho(jwtMiddleware echo.MiddlewareFunc) *echo.Echo {
 		d = time.Minute
 	}
 
+	a := middlewares.AccessLogConfig{
+		SampleRate:    viper.GetFloat64("HTTP_ACCESS_LOG_SAMPLE_RATE"),
+		SkipPaths:     splitNonEmpty(viper.GetString("HTTP_ACCESS_LOG_SKIP_PATHS")),
+		SlowThreshold: viper.GetDuration("HTTP_ACCESS_LOG_SLOW_THRESHOLD"),
+	}
+
 	echoApp.Use(
+		middlewares.RequestIDMiddleware(),
+		middlewares.AccessLogMiddleware(lg, a),
 		middlewares.CorsMiddleware(strings.Split(c, ",")),
 		middlewares.RateLimiterMiddleware(r, d),
 		middlewares.GzipMiddleware(l),
@@ -206,3 +389,56 @@ func ResolveEcho(jwtMiddleware echo.MiddlewareFunc) *echo.Echo {
 	echoApp.HideBanner = true
 	return echoApp
 }
+
+func splitNonEmpty(s string) []string {
+	result := make([]string, 0)
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// splitPatterns parses regular expressions, several ones are given as a JSON array
+// since a regular expression may contain any separator
+func splitPatterns(s string) []string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	if !strings.HasPrefix(s, "[") {
+		return []string{s}
+	}
+	var result []string
+	if err := json.Unmarshal([]byte(s), &result); err != nil {
+		log.Fatalf("invalid patterns \"%s\": %s", s, err)
+	}
+	return result
+}
+
+// splitIDs parses comma separated ids, invalid ones are skipped
+func splitIDs(s string) []int64 {
+	result := make([]int64, 0)
+	for _, v := range splitNonEmpty(s) {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			log.Printf("invalid id \"%s\" skipped", v)
+			continue
+		}
+		result = append(result, id)
+	}
+	return result
+}
+
+// splitKeyValues parses "key:value,key:value" pairs
+func splitKeyValues(s string) map[string]string {
+	result := make(map[string]string)
+	for _, v := range splitNonEmpty(s) {
+		key, value, found := strings.Cut(v, ":")
+		if found {
+			result[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return result
+}